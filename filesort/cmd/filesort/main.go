@@ -4,6 +4,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/pekomon/go-sandbox/filesort/internal/sorter"
 )
@@ -13,9 +16,18 @@ func main() {
 }
 
 func run(args []string) int {
+	if len(args) > 0 && args[0] == "rules" {
+		return runRules(args[1:])
+	}
+	if len(args) > 0 && args[0] == "undo" {
+		return runUndo(args[1:])
+	}
+
 	var dryRun bool
+	var pf planFlags
 	fs := flag.NewFlagSet("filesort", flag.ContinueOnError)
 	fs.BoolVar(&dryRun, "dry-run", false, "plan only; do not modify the filesystem")
+	pf.register(fs)
 	// silence default usage on parse error
 	fs.SetOutput(new(nopWriter))
 	if err := fs.Parse(args); err != nil {
@@ -24,12 +36,18 @@ func run(args []string) int {
 	}
 	rest := fs.Args()
 	if len(rest) != 1 {
-		fmt.Fprintln(os.Stderr, "usage: filesort [--dry-run] <rootDir>")
+		fmt.Fprintln(os.Stderr, "usage: filesort [--dry-run] [--recursive] [--max-depth n] [--include pattern] [--exclude pattern] [--rules file] [--detect] [--on-conflict policy] <rootDir>")
+		fmt.Fprintln(os.Stderr, "       filesort rules check [--rules file] [rootDir]")
+		fmt.Fprintln(os.Stderr, "       filesort undo [--force] [journal|rootDir]")
 		return 2
 	}
 	root := rest[0]
 
-	plan, err := sorter.BuildPlan(root, dryRun)
+	opts, code := pf.options()
+	if code != 0 {
+		return code
+	}
+	plan, err := sorter.BuildPlanWith(root, opts)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
@@ -38,19 +56,141 @@ func run(args []string) int {
 	if dryRun {
 		// Print a small summary; helpful for future assertions and user feedback.
 		fmt.Fprintf(os.Stdout, "dry-run: %d moves planned\n", len(plan.Moves))
-		for src, dst := range plan.Moves {
-			fmt.Fprintf(os.Stdout, "%s -> %s\n", src, dst)
+		for _, src := range sources(plan) {
+			var notes []string
+			if mime, ok := plan.Types[src]; ok {
+				notes = append(notes, mime)
+			}
+			switch c := plan.Conflicts[src]; c.Resolution {
+			case sorter.Renamed:
+				notes = append(notes, fmt.Sprintf("renamed, %s is taken", c.Dst))
+			case sorter.Overwritten:
+				notes = append(notes, "overwrites the existing file")
+			}
+			if len(notes) > 0 {
+				fmt.Fprintf(os.Stdout, "%s -> %s (%s)\n", src, plan.Moves[src], strings.Join(notes, ", "))
+				continue
+			}
+			fmt.Fprintf(os.Stdout, "%s -> %s\n", src, plan.Moves[src])
+		}
+		for _, src := range unmoved(plan) {
+			c := plan.Conflicts[src]
+			if c.Resolution == sorter.Duplicate {
+				fmt.Fprintf(os.Stdout, "%s: duplicate of %s, will be removed\n", src, c.Dst)
+				continue
+			}
+			fmt.Fprintf(os.Stdout, "%s: skipped, %s is taken\n", src, c.Dst)
 		}
+		writeMismatches(plan)
 		return 0
 	}
 
-	if err := sorter.Apply(plan); err != nil {
+	writeMismatches(plan)
+
+	journal, err := sorter.ApplyJournal(plan)
+	if journal != "" {
+		fmt.Fprintf(os.Stdout, "journal: %s (filesort undo reverts the moves)\n", journal)
+	}
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
 	return 0
 }
 
+// sources are the files a plan moves, sorted.
+func sources(plan sorter.Plan) []string {
+	srcs := make([]string, 0, len(plan.Moves))
+	for src := range plan.Moves {
+		srcs = append(srcs, src)
+	}
+	sort.Strings(srcs)
+	return srcs
+}
+
+// unmoved are the conflicting files a plan skips or removes, sorted.
+func unmoved(plan sorter.Plan) []string {
+	var srcs []string
+	for src := range plan.Conflicts {
+		if _, ok := plan.Moves[src]; !ok {
+			srcs = append(srcs, src)
+		}
+	}
+	sort.Strings(srcs)
+	return srcs
+}
+
+// writeMismatches reports the files whose extension disagrees with their
+// content.
+func writeMismatches(plan sorter.Plan) {
+	for _, m := range plan.Mismatches {
+		ext := filepath.Ext(m.Src)
+		if ext == "" {
+			ext = "no extension"
+		}
+		fmt.Fprintf(os.Stdout, "mismatch: %s is %s but has %s (expected %s)\n", m.Src, m.MIME, ext, strings.Join(m.Want, ", "))
+	}
+}
+
+// planFlags are the flags that select and classify the files to sort.
+type planFlags struct {
+	opts       sorter.Options
+	rules      string
+	onConflict string
+}
+
+func (pf *planFlags) register(fs *flag.FlagSet) {
+	pf.registerSelect(fs)
+	fs.BoolVar(&pf.opts.Detect, "detect", false, "classify by the type detected from file content and report mismatching extensions")
+	fs.StringVar(&pf.onConflict, "on-conflict", string(sorter.PolicyRename), "when the destination is taken: skip, rename, overwrite or hash-dedupe")
+}
+
+// registerSelect registers only the flags that select the files and the
+// rules, for commands that do not move anything.
+func (pf *planFlags) registerSelect(fs *flag.FlagSet) {
+	fs.BoolVar(&pf.opts.Recursive, "recursive", false, "also sort files in subdirectories")
+	fs.IntVar(&pf.opts.MaxDepth, "max-depth", 0, "with --recursive, descend at most this many levels (0 = no limit)")
+	fs.Var((*stringList)(&pf.opts.Include), "include", "only sort files matching this gitignore-style pattern (repeatable)")
+	fs.Var((*stringList)(&pf.opts.Exclude), "exclude", "skip files and directories matching this gitignore-style pattern (repeatable)")
+	fs.StringVar(&pf.rules, "rules", "", "classification rules file (default: "+sorter.RulesFile+" in the root, if present)")
+}
+
+// options checks the flags and loads --rules. A non-zero code is the exit
+// code to stop with.
+func (pf *planFlags) options() (sorter.Options, int) {
+	if pf.opts.MaxDepth < 0 {
+		fmt.Fprintln(os.Stderr, "max depth must not be negative")
+		return pf.opts, 2
+	}
+	if pf.onConflict != "" {
+		policy, err := sorter.ParsePolicy(pf.onConflict)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return pf.opts, 2
+		}
+		pf.opts.OnConflict = policy
+	}
+	if pf.rules != "" {
+		rules, err := sorter.LoadRules(pf.rules)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return pf.opts, 1
+		}
+		pf.opts.Rules = rules
+	}
+	return pf.opts, 0
+}
+
 type nopWriter struct{}
 
 func (*nopWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/pekomon/go-sandbox/filesort/internal/sorter"
+)
+
+// runRules handles `rules check [flags] [rootDir]`: it validates the rules
+// file and, given a root, shows the class and rule every file would get.
+func runRules(args []string) int {
+	usage := "usage: filesort rules check [--rules file] [--recursive] [--max-depth n] [--include pattern] [--exclude pattern] [rootDir]"
+	if len(args) == 0 || args[0] != "check" {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	var pf planFlags
+	fs := flag.NewFlagSet("rules check", flag.ContinueOnError)
+	pf.registerSelect(fs)
+	fs.SetOutput(new(nopWriter))
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid flags")
+		return 2
+	}
+	rest := fs.Args()
+	if len(rest) > 1 || (len(rest) == 0 && pf.rules == "") {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	if pf.rules == "" {
+		pf.rules = filepath.Join(rest[0], sorter.RulesFile)
+		if _, err := os.Stat(pf.rules); errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "no rules file: pass --rules or create %s\n", pf.rules)
+			return 1
+		}
+	}
+	opts, code := pf.options()
+	if code != 0 {
+		return code
+	}
+	rules := opts.Rules
+	fmt.Fprintf(os.Stdout, "%s: %d rules OK, default class %s\n", rules.Path, len(rules.Rules), rules.Default)
+	if len(rest) == 0 {
+		return 0
+	}
+
+	plan, err := sorter.BuildPlanWith(rest[0], opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	// Every walked file is listed, including those a conflict would keep
+	// where they are.
+	for _, src := range slices.Sorted(maps.Keys(plan.Matched)) {
+		rel, _ := filepath.Rel(plan.Root, src)
+		class, rule := rules.Default, "default"
+		if name := plan.Matched[src]; name != "" {
+			class, rule = ruleClass(rules, name), "rule "+name
+		}
+		if mime, ok := plan.Types[src]; ok {
+			rule += ", " + mime
+		}
+		fmt.Fprintf(os.Stdout, "%s -> %s (%s)\n", rel, class, rule)
+	}
+	return 0
+}
+
+// ruleClass is the class of the rule plan.Matched names.
+func ruleClass(rules *sorter.Rules, name string) string {
+	for i := range rules.Rules {
+		if r := &rules.Rules[i]; r.Describe() == name {
+			return string(r.Class)
+		}
+	}
+	return ""
+}
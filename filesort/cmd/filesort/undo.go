@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pekomon/go-sandbox/filesort/internal/sorter"
+)
+
+// runUndo handles `undo [--force] [journal|rootDir]`: it reverses the moves
+// of a journal, by default the latest one in the current directory.
+func runUndo(args []string) int {
+	var force bool
+	fs := flag.NewFlagSet("undo", flag.ContinueOnError)
+	fs.BoolVar(&force, "force", false, "also move back files that changed since they were sorted")
+	fs.SetOutput(new(nopWriter))
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid flags")
+		return 2
+	}
+	rest := fs.Args()
+	if len(rest) > 1 {
+		fmt.Fprintln(os.Stderr, "usage: filesort undo [--force] [journal|rootDir]")
+		return 2
+	}
+	journal := "."
+	if len(rest) == 1 {
+		journal = rest[0]
+	}
+	if info, err := os.Stat(journal); err == nil && info.IsDir() {
+		if journal, err = sorter.LatestJournal(journal); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	res, err := sorter.Undo(journal, force)
+	for _, f := range res.Changed {
+		fmt.Fprintf(os.Stdout, "changed: %s was modified since it was sorted; left in place (--force moves it back)\n", f)
+	}
+	for _, f := range res.Missing {
+		fmt.Fprintf(os.Stdout, "missing: %s was moved or deleted since it was sorted\n", f)
+	}
+	for _, f := range res.Taken {
+		fmt.Fprintf(os.Stdout, "taken: %s exists again; left the sorted file in place\n", f)
+	}
+	for _, f := range res.Lost {
+		fmt.Fprintf(os.Stdout, "lost: %s had overwritten a file, which cannot be restored\n", f)
+	}
+	for _, dir := range res.Removed {
+		fmt.Fprintf(os.Stdout, "removed empty directory %s\n", dir)
+	}
+	fmt.Fprintf(os.Stdout, "undo: %d files restored\n", res.Restored)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if res.Remaining != "" {
+		fmt.Fprintf(os.Stderr, "not everything was undone; the rest is kept in %s\n", res.Remaining)
+		return 1
+	}
+	return 0
+}
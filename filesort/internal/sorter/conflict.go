@@ -0,0 +1,201 @@
+package sorter
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Policy decides what happens to a file whose destination is already taken,
+// by an existing file or by another file of the same plan.
+type Policy string
+
+const (
+	// PolicyRename moves the file to the first free "name (n).ext".
+	PolicyRename Policy = "rename"
+	// PolicySkip leaves the file where it is.
+	PolicySkip Policy = "skip"
+	// PolicyOverwrite replaces the existing file.
+	PolicyOverwrite Policy = "overwrite"
+	// PolicyHashDedupe removes the file when its content is identical to
+	// the existing one, and renames it otherwise.
+	PolicyHashDedupe Policy = "hash-dedupe"
+)
+
+// Policies are the accepted policies; the first is the default.
+var Policies = []Policy{PolicyRename, PolicySkip, PolicyOverwrite, PolicyHashDedupe}
+
+// ParsePolicy parses a --on-conflict value.
+func ParsePolicy(s string) (Policy, error) {
+	for _, p := range Policies {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("invalid conflict policy %q (want skip, rename, overwrite or hash-dedupe)", s)
+}
+
+// Resolution is what a plan does with a conflicting file.
+type Resolution string
+
+const (
+	Renamed     Resolution = "renamed"
+	Skipped     Resolution = "skipped"
+	Overwritten Resolution = "overwrites"
+	Duplicate   Resolution = "duplicate"
+)
+
+// Conflict records how the plan resolved a source whose destination was
+// taken. Renamed and overwriting sources are still in Plan.Moves; skipped
+// ones are not, and duplicates are removed by Apply.
+type Conflict struct {
+	Dst        string // the destination that was taken
+	Resolution Resolution
+}
+
+// resolver assigns destinations in walk order, so the first file to claim a
+// destination keeps its name.
+type resolver struct {
+	policy  Policy
+	claimed map[string]string // dstAbs -> srcAbs planned to move there
+}
+
+func newResolver(policy Policy) *resolver {
+	if policy == "" {
+		policy = Policies[0]
+	}
+	return &resolver{policy: policy, claimed: make(map[string]string)}
+}
+
+// resolve returns where src goes, or "" when it does not move. The conflict
+// is set when dst was taken.
+func (r *resolver) resolve(src, dst string) (string, *Conflict, error) {
+	existing, taken, err := r.occupant(dst)
+	if err != nil {
+		return "", nil, err
+	}
+	if !taken {
+		r.claimed[dst] = src
+		return dst, nil, nil
+	}
+
+	policy := r.policy
+	if info, err := os.Stat(existing); err != nil || !info.Mode().IsRegular() {
+		// Only files can be replaced or compared.
+		if policy == PolicyOverwrite || policy == PolicyHashDedupe {
+			policy = PolicyRename
+		}
+	}
+	switch policy {
+	case PolicySkip:
+		return "", &Conflict{Dst: dst, Resolution: Skipped}, nil
+	case PolicyOverwrite:
+		if _, planned := r.claimed[dst]; !planned {
+			r.claimed[dst] = src
+			return dst, &Conflict{Dst: dst, Resolution: Overwritten}, nil
+		}
+		// Two files of the plan cannot both end up in dst.
+	case PolicyHashDedupe:
+		// Files that cannot be compared are kept under a new name.
+		if same, err := sameContent(src, existing); err == nil && same {
+			return "", &Conflict{Dst: dst, Resolution: Duplicate}, nil
+		}
+	}
+	to, err := r.free(dst)
+	if err != nil {
+		return "", nil, err
+	}
+	r.claimed[to] = src
+	return to, &Conflict{Dst: dst, Resolution: Renamed}, nil
+}
+
+// occupant is the file that takes dst: one planned to move there, or one
+// already on disk.
+func (r *resolver) occupant(dst string) (string, bool, error) {
+	if src, ok := r.claimed[dst]; ok {
+		return src, true, nil
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return dst, true, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", false, err
+	}
+	return "", false, nil
+}
+
+// free returns the first "name (n).ext" next to dst that nothing takes.
+func (r *resolver) free(dst string) (string, error) {
+	dir, name := filepath.Split(dst)
+	stem, ext := splitExt(name)
+	for n := 1; ; n++ {
+		cand := filepath.Join(dir, fmt.Sprintf("%s (%d)%s", stem, n, ext))
+		_, taken, err := r.occupant(cand)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return cand, nil
+		}
+	}
+}
+
+// splitExt splits name before its extension, keeping ".tar" with the
+// compression extension: "a.tar.gz" is "a" and ".tar.gz". Dotfiles such as
+// ".bashrc" have no extension.
+func splitExt(name string) (string, string) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	if stem == "" {
+		return name, ""
+	}
+	if strings.HasSuffix(stem, ".tar") && stem != ".tar" {
+		stem, ext = strings.TrimSuffix(stem, ".tar"), ".tar"+ext
+	}
+	return stem, ext
+}
+
+// sameContent reports whether two regular files have identical content.
+// Anything else, such as a symlink or a FIFO, is never read and never the
+// same.
+func sameContent(a, b string) (bool, error) {
+	ia, err := os.Lstat(a)
+	if err != nil {
+		return false, err
+	}
+	ib, err := os.Lstat(b)
+	if err != nil {
+		return false, err
+	}
+	if !ia.Mode().IsRegular() || !ib.Mode().IsRegular() || ia.Size() != ib.Size() {
+		return false, nil
+	}
+	ha, err := hashFile(a)
+	if err != nil {
+		return false, err
+	}
+	hb, err := hashFile(b)
+	if err != nil {
+		return false, err
+	}
+	return ha == hb, nil
+}
+
+func hashFile(name string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	f, err := os.Open(name)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
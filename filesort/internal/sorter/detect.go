@@ -0,0 +1,241 @@
+package sorter
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// sniffLen is how much of a file DetectType reads; tar keeps its magic at
+// offset 257.
+const sniffLen = 512
+
+// signature is a magic number at a fixed offset.
+type signature struct {
+	offset int
+	magic  string
+	mime   string
+}
+
+// signatures cover what http.DetectContentType does not know or only knows
+// as a generic type. They are tried first, in order.
+var signatures = []signature{
+	{0, "7z\xbc\xaf\x27\x1c", "application/x-7z-compressed"},
+	{0, "\xfd7zXZ\x00", "application/x-xz"},
+	{0, "BZh", "application/x-bzip2"},
+	{0, "\x28\xb5\x2f\xfd", "application/zstd"},
+	{257, "ustar", "application/x-tar"},
+	{0, "fLaC", "audio/flac"},
+	{0, "II*\x00", "image/tiff"},
+	{0, "MM\x00*", "image/tiff"},
+	{0, "\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1", "application/x-ole-storage"},
+	{0, `{\rtf`, "application/rtf"},
+}
+
+// ftypBrands are the ISO base media brands that are not plain MP4 video.
+var ftypBrands = map[string]string{
+	"qt  ": "video/quicktime",
+	"M4A ": "audio/mp4",
+	"M4B ": "audio/mp4",
+	"heic": "image/heic",
+	"heix": "image/heic",
+	"mif1": "image/heic",
+	"avif": "image/avif",
+	"3gp4": "video/3gpp",
+	"3gp5": "video/3gpp",
+	"3gp6": "video/3gpp",
+}
+
+// mimeType is what filesort knows about a detected type: its class and the
+// extensions it is expected to have.
+type mimeType struct {
+	class Class
+	exts  []string
+}
+
+var mimeTypes = map[string]mimeType{
+	"image/jpeg":   {ClassImages, []string{".jpg", ".jpeg", ".jpe", ".jfif"}},
+	"image/png":    {ClassImages, []string{".png"}},
+	"image/gif":    {ClassImages, []string{".gif"}},
+	"image/webp":   {ClassImages, []string{".webp"}},
+	"image/bmp":    {ClassImages, []string{".bmp"}},
+	"image/x-icon": {ClassImages, []string{".ico"}},
+	"image/tiff":   {ClassImages, []string{".tif", ".tiff"}},
+	"image/heic":   {ClassImages, []string{".heic", ".heif"}},
+	"image/avif":   {ClassImages, []string{".avif"}},
+
+	"video/mp4":        {ClassVideos, []string{".mp4", ".m4v"}},
+	"video/quicktime":  {ClassVideos, []string{".mov", ".qt"}},
+	"video/webm":       {ClassVideos, []string{".webm"}},
+	"video/x-matroska": {ClassVideos, []string{".mkv"}},
+	"video/avi":        {ClassVideos, []string{".avi"}},
+	"video/3gpp":       {ClassVideos, []string{".3gp"}},
+	"video/ogg":        {ClassVideos, []string{".ogv", ".ogg"}},
+
+	"audio/mpeg":  {ClassOther, []string{".mp3"}},
+	"audio/flac":  {ClassOther, []string{".flac"}},
+	"audio/ogg":   {ClassOther, []string{".ogg", ".oga", ".opus"}},
+	"audio/wave":  {ClassOther, []string{".wav"}},
+	"audio/aiff":  {ClassOther, []string{".aif", ".aiff"}},
+	"audio/mp4":   {ClassOther, []string{".m4a", ".m4b"}},
+	"audio/midi":  {ClassOther, []string{".mid", ".midi"}},
+	"audio/basic": {ClassOther, []string{".au", ".snd"}},
+
+	"application/zip":              {ClassOther, []string{".zip", ".jar", ".apk"}},
+	"application/x-gzip":           {ClassOther, []string{".gz", ".tgz"}},
+	"application/x-rar-compressed": {ClassOther, []string{".rar"}},
+	"application/x-7z-compressed":  {ClassOther, []string{".7z"}},
+	"application/x-xz":             {ClassOther, []string{".xz", ".txz"}},
+	"application/x-bzip2":          {ClassOther, []string{".bz2", ".tbz2"}},
+	"application/zstd":             {ClassOther, []string{".zst"}},
+	"application/x-tar":            {ClassOther, []string{".tar"}},
+
+	"application/pdf":           {ClassDocs, []string{".pdf"}},
+	"application/rtf":           {ClassDocs, []string{".rtf"}},
+	"application/x-ole-storage": {ClassDocs, []string{".doc", ".xls", ".ppt", ".msg"}},
+	"application/epub+zip":      {ClassDocs, []string{".epub"}},
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   {ClassDocs, []string{".docx"}},
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         {ClassDocs, []string{".xlsx"}},
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": {ClassDocs, []string{".pptx"}},
+	"application/vnd.oasis.opendocument.text":                                   {ClassDocs, []string{".odt"}},
+	"application/vnd.oasis.opendocument.spreadsheet":                            {ClassDocs, []string{".ods"}},
+	"application/vnd.oasis.opendocument.presentation":                           {ClassDocs, []string{".odp"}},
+}
+
+// officeParts identify Office Open XML documents by a part of the zip.
+var officeParts = map[string]string{
+	"word/document.xml":    "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"xl/workbook.xml":      "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"ppt/presentation.xml": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// DetectType sniffs the MIME type of a file from its content, without
+// parameters such as the charset. Types neither our signatures nor
+// http.DetectContentType recognize are "application/octet-stream".
+func DetectType(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	mime := sniff(head[:n])
+	if mime == "application/zip" {
+		mime = zipType(file)
+	}
+	return mime, nil
+}
+
+func sniff(head []byte) string {
+	for _, s := range signatures {
+		if len(head) >= s.offset+len(s.magic) && string(head[s.offset:s.offset+len(s.magic)]) == s.magic {
+			return s.mime
+		}
+	}
+	if len(head) >= 12 && string(head[4:8]) == "ftyp" {
+		brand := string(head[8:12])
+		if mime, ok := ftypBrands[brand]; ok {
+			return mime
+		}
+		if strings.HasPrefix(brand, "3gp") {
+			return "video/3gpp"
+		}
+		return "video/mp4"
+	}
+	if len(head) >= 12 && string(head[:4]) == "FORM" && (string(head[8:12]) == "AIFF" || string(head[8:12]) == "AIFC") {
+		return "audio/aiff"
+	}
+	if bytes.HasPrefix(head, []byte("\x1a\x45\xdf\xa3")) && !bytes.Contains(head, []byte("webm")) {
+		return "video/x-matroska"
+	}
+
+	mime, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	if mime == "application/ogg" {
+		switch {
+		case bytes.Contains(head, []byte("theora")):
+			return "video/ogg"
+		case bytes.Contains(head, []byte("OpusHead")), bytes.Contains(head, []byte("vorbis")):
+			return "audio/ogg"
+		}
+	}
+	return mime
+}
+
+// zipType tells zip-based documents from plain archives: OpenDocument and
+// EPUB name their type in a "mimetype" entry, Office Open XML is recognized
+// by its parts.
+func zipType(file string) string {
+	const plain = "application/zip"
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return plain
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if mime, ok := officeParts[f.Name]; ok {
+			return mime
+		}
+		if f.Name != "mimetype" || f.UncompressedSize64 > 128 {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return plain
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if mime := strings.TrimSpace(string(b)); err == nil {
+			if _, ok := mimeTypes[mime]; ok {
+				return mime
+			}
+		}
+	}
+	return plain
+}
+
+// classifyByType is the class of a detected MIME type, or "" when the type
+// is too generic to go by, such as text or unknown binary data.
+func classifyByType(mime string) Class {
+	if t, ok := mimeTypes[mime]; ok {
+		return t.class
+	}
+	switch {
+	case strings.HasPrefix(mime, "image/"):
+		return ClassImages
+	case strings.HasPrefix(mime, "video/"):
+		return ClassVideos
+	case strings.HasPrefix(mime, "audio/"):
+		return ClassOther
+	}
+	return ""
+}
+
+// Mismatch reports a file whose extension disagrees with its content.
+type Mismatch struct {
+	Src  string   // absolute path
+	MIME string   // detected type
+	Want []string // extensions expected for MIME
+}
+
+// checkExt returns the mismatch between name's extension and the detected
+// type, if any. Only types with known extensions are checked.
+func checkExt(src, mime string) (Mismatch, bool) {
+	t, ok := mimeTypes[mime]
+	if !ok {
+		return Mismatch{}, false
+	}
+	ext := strings.ToLower(filepath.Ext(src))
+	if slices.Contains(t.exts, ext) {
+		return Mismatch{}, false
+	}
+	return Mismatch{Src: src, MIME: mime, Want: t.exts}, true
+}
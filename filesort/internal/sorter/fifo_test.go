@@ -0,0 +1,43 @@
+//go:build unix
+
+package sorter_test
+
+import (
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pekomon/go-sandbox/filesort/internal/sorter"
+)
+
+func TestBuildPlanWith_NeverReadsSpecialFiles(t *testing.T) {
+	root := t.TempDir()
+	mkdirs(t, root, "docs")
+	writeFile(t, root, "docs/pipe.txt", []byte("text"))
+	if err := syscall.Mkfifo(filepath.Join(root, "pipe.txt"), 0o644); err != nil {
+		t.Skipf("mkfifo: %v", err)
+	}
+
+	// Opening the FIFO for content detection or hashing would block forever.
+	done := make(chan struct{})
+	var p sorter.Plan
+	var err error
+	go func() {
+		defer close(done)
+		p, err = sorter.BuildPlanWith(root, sorter.Options{Detect: true, OnConflict: sorter.PolicyHashDedupe})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("build plan blocked on a FIFO")
+	}
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	want := []string{"pipe.txt -> docs/pipe (1).txt"}
+	if got := relMoves(t, p); !slices.Equal(got, want) {
+		t.Fatalf("plan\nGOT:  %v\nWANT: %v", got, want)
+	}
+}
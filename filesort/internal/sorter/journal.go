@@ -0,0 +1,381 @@
+package sorter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JournalPrefix starts the name of the journals Apply writes into the root,
+// e.g. ".filesort-journal-20240501T103000.000Z.json". Journals are never
+// moved; once undone they get an ".undone" suffix.
+const JournalPrefix = ".filesort-journal-"
+
+const (
+	journalExt    = ".json"
+	undoneSuffix  = ".undone"
+	journalLayout = "20060102T150405.000Z"
+)
+
+// Journal records what Apply did, so that Undo can reverse it. Paths are
+// slash-separated and relative to the directory holding the journal, which
+// is the root that was sorted.
+type Journal struct {
+	// Root is where the sort ran, for reference; Undo goes by the location
+	// of the journal instead, so a sorted tree can be moved as a whole.
+	Root    string           `json:"root"`
+	Time    time.Time        `json:"time"`
+	Moves   []JournalMove    `json:"moves"`
+	Removed []JournalRemoval `json:"removed,omitempty"`
+}
+
+// JournalMove is a file moved from Src to Dst. Size and ModTime describe the
+// file as it was moved, to tell whether it changed since.
+type JournalMove struct {
+	Src     string    `json:"src"`
+	Dst     string    `json:"dst"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	// Overwrote is set when the file replaced another one at Dst.
+	Overwrote bool `json:"overwrote,omitempty"`
+}
+
+// JournalRemoval is a duplicate removed from Src because Of has the same
+// content; Undo copies Of back.
+type JournalRemoval struct {
+	Src     string    `json:"src"`
+	Of      string    `json:"duplicate_of"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+}
+
+// isJournal reports whether rel, relative to the root, is a journal.
+func isJournal(rel string) bool {
+	return !strings.Contains(rel, "/") && strings.HasPrefix(rel, JournalPrefix)
+}
+
+func (j *Journal) rel(file string) (string, error) {
+	rel, err := filepath.Rel(j.Root, file)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func (j *Journal) addMove(src, dst string, overwrote bool) error {
+	info, err := os.Lstat(dst)
+	if err != nil {
+		return err
+	}
+	m := JournalMove{Size: info.Size(), ModTime: info.ModTime(), Overwrote: overwrote}
+	if m.Src, err = j.rel(src); err != nil {
+		return err
+	}
+	if m.Dst, err = j.rel(dst); err != nil {
+		return err
+	}
+	j.Moves = append(j.Moves, m)
+	return nil
+}
+
+func (j *Journal) addRemoval(src, of string) error {
+	info, err := os.Lstat(of)
+	if err != nil {
+		return err
+	}
+	r := JournalRemoval{Size: info.Size(), ModTime: info.ModTime()}
+	if r.Src, err = j.rel(src); err != nil {
+		return err
+	}
+	if r.Of, err = j.rel(of); err != nil {
+		return err
+	}
+	j.Removed = append(j.Removed, r)
+	return nil
+}
+
+// write creates a new journal file in the root, named after j.Time.
+func (j *Journal) write() (string, error) {
+	file := filepath.Join(j.Root, JournalPrefix+j.Time.Format(journalLayout)+journalExt)
+	b, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return "", err
+	}
+	return file, f.Close()
+}
+
+// readJournal loads a journal and checks that its paths stay inside the
+// directory it is in.
+func readJournal(file string) (*Journal, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var j Journal
+	if err := json.Unmarshal(b, &j); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	var paths []string
+	for _, m := range j.Moves {
+		paths = append(paths, m.Src, m.Dst)
+	}
+	for _, r := range j.Removed {
+		paths = append(paths, r.Src, r.Of)
+	}
+	for _, p := range paths {
+		if !filepath.IsLocal(filepath.FromSlash(p)) {
+			return nil, fmt.Errorf("%s: path %q is outside the root", file, p)
+		}
+	}
+	return &j, nil
+}
+
+// LatestJournal returns the most recent journal in root that has not been
+// undone.
+func LatestJournal(root string) (string, error) {
+	ents, err := os.ReadDir(root)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for _, e := range ents {
+		if isJournal(e.Name()) && strings.HasSuffix(e.Name(), journalExt) && e.Type().IsRegular() {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no journal in %s", root)
+	}
+	// The timestamps have a fixed width, so names sort by time.
+	return filepath.Join(root, slices.Max(names)), nil
+}
+
+// UndoResult reports what Undo did. Paths are absolute.
+type UndoResult struct {
+	// Restored counts the files put back where they were.
+	Restored int
+	// Changed are files modified since they were moved; they are left in
+	// place unless Undo is forced. Duplicates whose kept copy changed are
+	// never restored.
+	Changed []string
+	// Missing are files that were moved or deleted since.
+	Missing []string
+	// Taken are original locations another file occupies again.
+	Taken []string
+	// Lost are restored files that had overwritten a file; that file is gone.
+	Lost []string
+	// Removed are the directories that became empty and were removed.
+	Removed []string
+	// Remaining is the journal of what was not undone, when something was
+	// not; it can be undone again later. Otherwise it is empty and the
+	// journal is marked as undone.
+	Remaining string
+}
+
+// Undo reverses the journal: duplicates are copied back from the file they
+// duplicated, moved files are moved back and the directories left empty are
+// removed. Files changed since they were moved are reported and left alone
+// unless force is set; files that are gone, or whose original location is
+// taken, are reported. The journal is then rewritten with only what was not
+// undone, or renamed with an ".undone" suffix when everything was.
+func Undo(journal string, force bool) (UndoResult, error) {
+	var res UndoResult
+	file, err := filepath.Abs(journal)
+	if err != nil {
+		return res, err
+	}
+	j, err := readJournal(file)
+	if err != nil {
+		return res, err
+	}
+	root := filepath.Dir(file)
+	abs := func(rel string) string { return filepath.Join(root, filepath.FromSlash(rel)) }
+	left := &Journal{Root: j.Root, Time: j.Time}
+	dirs := make(map[string]bool)
+
+	// Apply removed duplicates last, so they come back first: the file they
+	// duplicated may itself be moved back below.
+	for _, r := range j.Removed {
+		src, of := abs(r.Src), abs(r.Of)
+		ok, err := restoreDuplicate(src, of, r, &res)
+		if err != nil {
+			return res, err
+		}
+		if !ok {
+			left.Removed = append(left.Removed, r)
+		}
+	}
+	for i := len(j.Moves) - 1; i >= 0; i-- {
+		m := j.Moves[i]
+		src, dst := abs(m.Src), abs(m.Dst)
+		dirs[filepath.Dir(dst)] = true
+		ok, err := moveBack(src, dst, m, force, &res)
+		if err != nil {
+			return res, err
+		}
+		if !ok {
+			left.Moves = append([]JournalMove{m}, left.Moves...)
+		}
+	}
+
+	res.Removed, err = removeEmptyDirs(root, dirs)
+	if err != nil {
+		return res, err
+	}
+	if len(left.Moves) > 0 || len(left.Removed) > 0 {
+		b, err := json.MarshalIndent(left, "", "  ")
+		if err != nil {
+			return res, err
+		}
+		res.Remaining = file
+		return res, os.WriteFile(file, append(b, '\n'), 0o644)
+	}
+	return res, os.Rename(file, file+undoneSuffix)
+}
+
+// moveBack moves one file back to src. It reports whether the move is done,
+// now or before.
+func moveBack(src, dst string, m JournalMove, force bool, res *UndoResult) (bool, error) {
+	info, err := os.Lstat(dst)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Already moved back by hand?
+		if back, err := os.Lstat(src); err == nil && unchanged(back, m.Size, m.ModTime) {
+			res.Restored++
+			return true, nil
+		}
+		res.Missing = append(res.Missing, dst)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !unchanged(info, m.Size, m.ModTime) && !force {
+		res.Changed = append(res.Changed, dst)
+		return false, nil
+	}
+	if _, err := os.Lstat(src); err == nil {
+		res.Taken = append(res.Taken, src)
+		return false, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(src), 0o755); err != nil {
+		return false, err
+	}
+	if err := os.Rename(dst, src); err != nil {
+		return false, err
+	}
+	res.Restored++
+	if m.Overwrote {
+		res.Lost = append(res.Lost, src)
+	}
+	return true, nil
+}
+
+// restoreDuplicate copies of back to src. It reports whether src is restored.
+func restoreDuplicate(src, of string, r JournalRemoval, res *UndoResult) (bool, error) {
+	info, err := os.Lstat(of)
+	if errors.Is(err, fs.ErrNotExist) {
+		res.Missing = append(res.Missing, of)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !unchanged(info, r.Size, r.ModTime) || !info.Mode().IsRegular() {
+		res.Changed = append(res.Changed, of)
+		return false, nil
+	}
+	if _, err := os.Lstat(src); err == nil {
+		res.Taken = append(res.Taken, src)
+		return false, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(src), 0o755); err != nil {
+		return false, err
+	}
+	if err := copyFile(src, of, info); err != nil {
+		return false, err
+	}
+	res.Restored++
+	return true, nil
+}
+
+func unchanged(info fs.FileInfo, size int64, mtime time.Time) bool {
+	return info.Size() == size && info.ModTime().Equal(mtime)
+}
+
+// copyFile copies from to a new file to, with from's mode and times.
+func copyFile(to, from string, info fs.FileInfo) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(to)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(to, info.ModTime(), info.ModTime())
+}
+
+// removeEmptyDirs removes the given directories and their parents below
+// root, deepest first, as long as they are empty.
+func removeEmptyDirs(root string, dirs map[string]bool) ([]string, error) {
+	all := make(map[string]bool)
+	for dir := range dirs {
+		for ; dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+			all[dir] = true
+		}
+	}
+	list := make([]string, 0, len(all))
+	for dir := range all {
+		list = append(list, dir)
+	}
+	// Children sort after their parents; go in reverse.
+	sort.Sort(sort.Reverse(sort.StringSlice(list)))
+	var removed []string
+	for _, dir := range list {
+		ents, err := os.ReadDir(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return removed, err
+		}
+		if len(ents) > 0 {
+			continue
+		}
+		if err := os.Remove(dir); err != nil {
+			return removed, err
+		}
+		removed = append(removed, dir)
+	}
+	slices.Sort(removed)
+	return removed, nil
+}
@@ -0,0 +1,140 @@
+package sorter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile is read from the root directory; it lists exclude patterns, one
+// per line, in the same gitignore-style syntax as --exclude. It is never moved.
+const IgnoreFile = ".filesortignore"
+
+// pattern is one compiled gitignore-style pattern.
+type pattern struct {
+	negate  bool
+	dirOnly bool
+	segs    []string
+}
+
+// patternList matches slash-separated paths relative to the root. Like
+// .gitignore, the last matching pattern wins, so "!keep.tmp" after "*.tmp"
+// re-includes a file.
+type patternList []pattern
+
+// compilePatterns parses gitignore-style patterns:
+//   - blank lines and lines starting with # are ignored;
+//   - a leading ! negates the pattern;
+//   - a trailing / matches directories only;
+//   - a pattern containing a / (other than a trailing one) is anchored at the
+//     root, anything else matches the name at any depth;
+//   - *, ? and [...] match within a path segment and ** matches any number of
+//     segments.
+func compilePatterns(lines []string) (patternList, error) {
+	var list patternList
+	for _, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var p pattern
+		if rest, ok := strings.CutPrefix(line, "!"); ok {
+			p.negate, line = true, rest
+		}
+		if rest, ok := strings.CutSuffix(line, "/"); ok {
+			p.dirOnly, line = true, rest
+		}
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			return nil, fmt.Errorf("invalid pattern %q", raw)
+		}
+		p.segs = strings.Split(line, "/")
+		if !anchored {
+			p.segs = append([]string{"**"}, p.segs...)
+		}
+		for _, s := range p.segs {
+			if _, err := path.Match(s, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", raw, err)
+			}
+		}
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+// match reports whether rel is matched by the list, taking negations into
+// account.
+func (l patternList) match(rel string, isDir bool) bool {
+	name := strings.Split(rel, "/")
+	matched := false
+	for _, p := range l {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if matchSegments(p.segs, name) {
+			matched = !p.negate
+		}
+	}
+	return matched
+}
+
+// matchAny reports whether the file rel, or one of the directories it is in,
+// is matched by the list.
+func (l patternList) matchAny(rel string) bool {
+	if l.match(rel, false) {
+		return true
+	}
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		if l.match(dir, true) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			pat = pat[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pat, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// readIgnoreFile returns the lines of root's IgnoreFile; a missing file has
+// none.
+func readIgnoreFile(root string) ([]string, error) {
+	f, err := os.Open(filepath.Join(root, IgnoreFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	return lines, sc.Err()
+}
@@ -0,0 +1,283 @@
+package sorter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RulesFile is the rules file BuildPlanWith picks up from the root when no
+// other rules are given. Like IgnoreFile it is never moved.
+const RulesFile = ".filesortrules.json"
+
+// Rules replace the built-in extension classes with user-defined ones.
+//
+//	{
+//	  "default": "misc",
+//	  "rules": [
+//	    {"name": "screenshots", "class": "screenshots", "priority": 10, "globs": ["Screenshot*"]},
+//	    {"class": "big-videos", "extensions": [".mp4", ".mkv"], "min_size": "500MB"},
+//	    {"class": "invoices", "regexes": ["(?i)^invoice-\\d+\\.pdf$"]}
+//	  ]
+//	}
+type Rules struct {
+	// Default is the class of files no rule matches; "other" when empty.
+	Default string `json:"default,omitempty"`
+	Rules   []Rule `json:"rules"`
+
+	// Path is the file the rules were loaded from, if any.
+	Path string `json:"-"`
+}
+
+// Rule assigns Class to the files it matches. A file matches when it passes
+// every criterion the rule sets: one of the extensions, one of the globs, one
+// of the regexes, one of the MIME type patterns and the size bounds. A rule
+// without criteria matches every file. Globs and regexes are matched against
+// the file name, MIME patterns such as "audio/*" against the type DetectType
+// finds in the content.
+type Rule struct {
+	Name       string   `json:"name,omitempty"`
+	Class      Class    `json:"class"`
+	Priority   int      `json:"priority,omitempty"`
+	Extensions []string `json:"extensions,omitempty"`
+	Globs      []string `json:"globs,omitempty"`
+	Regexes    []string `json:"regexes,omitempty"`
+	MIME       []string `json:"mime,omitempty"`
+	MinSize    *Size    `json:"min_size,omitempty"`
+	MaxSize    *Size    `json:"max_size,omitempty"`
+
+	regexes []*regexp.Regexp
+	pos     int // 1-based position in the file
+}
+
+// Size is a byte count, written in JSON as a number or as a string with an
+// optional unit: "512", "100KB", "1.5GB". Units are powers of 1024.
+type Size int64
+
+var sizeUnits = []struct {
+	suffix string
+	mult   float64
+}{
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as "100KB".
+func ParseSize(s string) (Size, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	mult := 1.0
+	for _, u := range sizeUnits {
+		if rest, ok := strings.CutSuffix(v, u.suffix); ok {
+			v, mult = strings.TrimSpace(rest), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return Size(n * mult), nil
+}
+
+func (s *Size) UnmarshalJSON(b []byte) error {
+	var n int64
+	if err := json.Unmarshal(b, &n); err == nil {
+		if n < 0 {
+			return fmt.Errorf("invalid size %d", n)
+		}
+		*s = Size(n)
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return fmt.Errorf("invalid size %s", b)
+	}
+	v, err := ParseSize(str)
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+// LoadRules reads and validates a rules file.
+func LoadRules(file string) (*Rules, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	r, err := ParseRules(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	r.Path = file
+	return r, nil
+}
+
+// findRules loads root's RulesFile; without one there are no rules.
+func findRules(root string) (*Rules, error) {
+	file := filepath.Join(root, RulesFile)
+	if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return LoadRules(file)
+}
+
+// ParseRules decodes and validates rules. Every problem is reported, not only
+// the first. The rules are returned sorted by priority, highest first; rules
+// of equal priority keep their order.
+func ParseRules(b []byte) (*Rules, error) {
+	var r Rules
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&r); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the rules")
+	}
+
+	var errs []error
+	if r.Default == "" {
+		r.Default = string(ClassOther)
+	} else if err := checkClass(Class(r.Default)); err != nil {
+		errs = append(errs, fmt.Errorf("default: %w", err))
+	}
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		rule.pos = i + 1
+		fail := func(err error) {
+			errs = append(errs, fmt.Errorf("rule %s: %w", rule.Describe(), err))
+		}
+		if err := checkClass(rule.Class); err != nil {
+			fail(err)
+		}
+		for j, ext := range rule.Extensions {
+			ext = strings.ToLower(strings.TrimSpace(ext))
+			if ext != "" && !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			if ext == "" || ext == "." {
+				fail(fmt.Errorf("empty extension"))
+			}
+			rule.Extensions[j] = ext
+		}
+		for _, g := range rule.Globs {
+			if _, err := path.Match(g, ""); err != nil {
+				fail(fmt.Errorf("invalid glob %q: %w", g, err))
+			}
+		}
+		for _, m := range rule.MIME {
+			if _, err := path.Match(m, ""); err != nil || !strings.Contains(m, "/") {
+				fail(fmt.Errorf("invalid MIME pattern %q", m))
+			}
+		}
+		for _, expr := range rule.Regexes {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				fail(fmt.Errorf("invalid regex %q: %w", expr, err))
+				continue
+			}
+			rule.regexes = append(rule.regexes, re)
+		}
+		if rule.MinSize != nil && rule.MaxSize != nil && *rule.MinSize > *rule.MaxSize {
+			fail(fmt.Errorf("min_size is larger than max_size"))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(r.Rules, func(i, j int) bool { return r.Rules[i].Priority > r.Rules[j].Priority })
+	return &r, nil
+}
+
+// checkClass rejects class names that are not a single folder name.
+func checkClass(c Class) error {
+	s := string(c)
+	switch {
+	case s == "":
+		return errors.New("class is required")
+	case s == "." || s == ".." || strings.ContainsAny(s, `/\`):
+		return fmt.Errorf("class %q must be a single folder name", s)
+	}
+	return nil
+}
+
+// Match returns the first rule, in priority order, that matches a file with
+// this name, size and detected MIME type, or nil when the default class
+// applies.
+func (r *Rules) Match(name string, size int64, mime string) *Rule {
+	for i := range r.Rules {
+		if r.Rules[i].matches(name, size, mime) {
+			return &r.Rules[i]
+		}
+	}
+	return nil
+}
+
+// Classes are the classes the rules can assign, default included.
+func (r *Rules) Classes() []Class {
+	classes := []Class{Class(r.Default)}
+	for _, rule := range r.Rules {
+		if !slices.Contains(classes, rule.Class) {
+			classes = append(classes, rule.Class)
+		}
+	}
+	return classes
+}
+
+// Describe names the rule for reports: its name, or else its position in
+// the file, e.g. "#2".
+func (r *Rule) Describe() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return "#" + strconv.Itoa(r.pos)
+}
+
+// needsType reports whether any rule matches on the MIME type.
+func (r *Rules) needsType() bool {
+	return slices.ContainsFunc(r.Rules, func(rule Rule) bool { return len(rule.MIME) > 0 })
+}
+
+func (r *Rule) matches(name string, size int64, mime string) bool {
+	if len(r.Extensions) > 0 {
+		lower := strings.ToLower(name)
+		if !slices.ContainsFunc(r.Extensions, func(ext string) bool { return strings.HasSuffix(lower, ext) }) {
+			return false
+		}
+	}
+	if len(r.Globs) > 0 && !slices.ContainsFunc(r.Globs, func(g string) bool {
+		ok, _ := path.Match(g, name)
+		return ok
+	}) {
+		return false
+	}
+	if len(r.regexes) > 0 && !slices.ContainsFunc(r.regexes, func(re *regexp.Regexp) bool { return re.MatchString(name) }) {
+		return false
+	}
+	if len(r.MIME) > 0 && !slices.ContainsFunc(r.MIME, func(m string) bool {
+		ok, _ := path.Match(m, mime)
+		return ok
+	}) {
+		return false
+	}
+	if r.MinSize != nil && size < int64(*r.MinSize) {
+		return false
+	}
+	if r.MaxSize != nil && size > int64(*r.MaxSize) {
+		return false
+	}
+	return true
+}
@@ -3,9 +3,13 @@ package sorter
 import (
 	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 )
 
 var ErrNotImplemented = errors.New("not implemented")
@@ -24,14 +28,64 @@ const (
 type Plan struct {
 	Root  string
 	Moves map[string]string // srcAbs -> dstAbs
+	// Matched names the rule that classified each source, or is empty for
+	// files that got the default class. It is nil without rules.
+	Matched map[string]string
+	// Types are the MIME types detected from the content of the sources,
+	// with Options.Detect or rules matching on types.
+	Types map[string]string
+	// Mismatches are the sources whose extension disagrees with their
+	// detected type, in the order they were found. Only reported with
+	// Options.Detect.
+	Mismatches []Mismatch
+	// Conflicts are the sources whose destination was taken, with how the
+	// conflict policy resolved them.
+	Conflicts map[string]Conflict
+}
+
+// Options control which files BuildPlanWith considers.
+type Options struct {
+	// Recursive also sorts files in subdirectories. The class folders in the
+	// root (images/, docs/, ...) are never descended into.
+	Recursive bool
+	// MaxDepth limits how many directory levels below the root a recursive
+	// plan descends; 0 means no limit.
+	MaxDepth int
+	// Include, when not empty, restricts the plan to files matching one of
+	// these gitignore-style patterns, directly or through a parent directory.
+	Include []string
+	// Exclude skips matching files and directories, after the patterns of the
+	// root's IgnoreFile.
+	Exclude []string
+	// Rules classify the files instead of the built-in extension classes.
+	// When nil, the root's RulesFile is used if there is one.
+	Rules *Rules
+	// Detect classifies by the MIME type sniffed from the file content
+	// rather than by extension, and reports extensions that disagree with
+	// it. Text and unrecognized content still go by extension.
+	Detect bool
+	// OnConflict is the policy for destinations that are already taken;
+	// PolicyRename when empty.
+	OnConflict Policy
 }
 
 // BuildPlan analyzes files under root (non-recursive) and computes destination moves.
 // When dryRun is true, the filesystem must remain untouched (this function only returns the plan).
 func BuildPlan(root string, dryRun bool) (Plan, error) {
+	return BuildPlanWith(root, Options{})
+}
+
+// BuildPlanWith computes destination moves for the files under root selected
+// by opts. Files in subdirectories keep their relative directory below the
+// class folder, e.g. a/b/x.jpg goes to images/a/b/x.jpg. It never touches the
+// filesystem.
+func BuildPlanWith(root string, opts Options) (Plan, error) {
 	if root == "" {
 		return Plan{}, fmt.Errorf("root is required")
 	}
+	if opts.MaxDepth < 0 {
+		return Plan{}, fmt.Errorf("max depth must not be negative")
+	}
 	absRoot, err := filepath.Abs(root)
 	if err != nil {
 		return Plan{}, err
@@ -44,46 +98,196 @@ func BuildPlan(root string, dryRun bool) (Plan, error) {
 		return Plan{}, fmt.Errorf("not a directory: %s", absRoot)
 	}
 
-	ents, err := os.ReadDir(absRoot)
+	ignored, err := readIgnoreFile(absRoot)
 	if err != nil {
 		return Plan{}, err
 	}
+	exclude, err := compilePatterns(append(ignored, opts.Exclude...))
+	if err != nil {
+		return Plan{}, err
+	}
+	include, err := compilePatterns(opts.Include)
+	if err != nil {
+		return Plan{}, err
+	}
+	rules := opts.Rules
+	if rules == nil {
+		if rules, err = findRules(absRoot); err != nil {
+			return Plan{}, err
+		}
+	}
+	// Class folders at the top are output, not input. With rules the
+	// built-in ones stay skipped too, so the output of an earlier run without
+	// rules is not sorted again.
+	classes := []Class{ClassImages, ClassDocs, ClassVideos, ClassOther}
+	var matched, types map[string]string
+	if rules != nil {
+		for _, c := range rules.Classes() {
+			if !slices.Contains(classes, c) {
+				classes = append(classes, c)
+			}
+		}
+		matched = make(map[string]string)
+	}
+	detect := opts.Detect || (rules != nil && rules.needsType())
+	if detect {
+		types = make(map[string]string)
+	}
+	var mismatches []Mismatch
+	resolver := newResolver(opts.OnConflict)
+	conflicts := make(map[string]Conflict)
 
 	moves := make(map[string]string)
-	for _, e := range ents {
-		if e.IsDir() {
-			// Non-recursive by design (future PR could add recursion).
-			continue
+	err = filepath.WalkDir(absRoot, func(src string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if src == absRoot {
+			return nil
+		}
+		rel, err := filepath.Rel(absRoot, src)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		depth := strings.Count(rel, "/")
+		if d.IsDir() {
+			switch {
+			case !opts.Recursive,
+				opts.MaxDepth > 0 && depth >= opts.MaxDepth,
+				depth == 0 && slices.Contains(classes, Class(d.Name())),
+				exclude.match(rel, true):
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel == IgnoreFile || rel == RulesFile || isJournal(rel) || exclude.match(rel, false) {
+			return nil
+		}
+		if len(include) > 0 && !include.matchAny(rel) {
+			return nil
 		}
-		name := e.Name()
-		src := filepath.Join(absRoot, name)
 
-		cl := classifyByExt(name)
-		dstDir := filepath.Join(absRoot, string(cl))
-		dst := filepath.Join(dstDir, name)
+		mime := ""
+		if detect && d.Type().IsRegular() {
+			// Only regular files are read: opening a FIFO would block. Files
+			// that cannot be read are classified by their extension.
+			if t, err := DetectType(src); err == nil {
+				mime = t
+				types[src] = mime
+				if m, ok := checkExt(src, mime); ok && opts.Detect {
+					mismatches = append(mismatches, m)
+				}
+			}
+		}
+		cl := classifyByExt(d.Name())
+		if c := classifyByType(mime); c != "" && opts.Detect {
+			cl = c
+		}
+		if rules != nil {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			cl = Class(rules.Default)
+			matched[src] = ""
+			if rule := rules.Match(d.Name(), info.Size(), mime); rule != nil {
+				cl = rule.Class
+				matched[src] = rule.Describe()
+			}
+		}
+		dst := filepath.Join(absRoot, string(cl), filepath.FromSlash(rel))
 
 		// Skip no-op moves (e.g., already in place, though this shouldn't happen for root files).
 		if src == dst {
-			continue
+			return nil
+		}
+		to, c, err := resolver.resolve(src, dst)
+		if err != nil {
+			return err
 		}
-		moves[src] = dst
+		if c != nil {
+			conflicts[src] = *c
+		}
+		if to != "" {
+			moves[src] = to
+		}
+		return nil
+	})
+	if err != nil {
+		return Plan{}, err
 	}
 
 	return Plan{
-		Root:  absRoot,
-		Moves: moves,
+		Root:       absRoot,
+		Moves:      moves,
+		Matched:    matched,
+		Types:      types,
+		Mismatches: mismatches,
+		Conflicts:  conflicts,
 	}, nil
 }
 
-// Apply executes the plan: create destination dirs and move files with os.Rename.
+// Apply executes the plan: create destination dirs and move files with os.Rename,
+// then remove the duplicates the plan found. It refuses to replace a file
+// the plan did not expect to overwrite, and to remove a duplicate whose
+// content changed since the plan was built. What it did is recorded in a
+// journal in the root, see ApplyJournal.
 func Apply(p Plan) error {
-	for src, dst := range p.Moves {
+	_, err := ApplyJournal(p)
+	return err
+}
+
+// ApplyJournal is Apply, returning the journal it wrote for Undo. The journal
+// also records the moves done before a failure; when nothing changed, no
+// journal is written and the path is empty.
+func ApplyJournal(p Plan) (string, error) {
+	j := &Journal{Root: p.Root, Time: time.Now().UTC()}
+	err := apply(p, j)
+	if len(j.Moves) == 0 && len(j.Removed) == 0 {
+		return "", err
+	}
+	file, jerr := j.write()
+	return file, errors.Join(err, jerr)
+}
+
+func apply(p Plan, j *Journal) error {
+	for _, src := range slices.Sorted(maps.Keys(p.Moves)) {
+		dst := p.Moves[src]
 		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
 			return err
 		}
+		overwrites := p.Conflicts[src].Resolution == Overwritten
+		if !overwrites {
+			if _, err := os.Lstat(dst); err == nil {
+				return fmt.Errorf("%s: destination already exists", dst)
+			}
+		}
 		if err := os.Rename(src, dst); err != nil {
 			return err
 		}
+		if err := j.addMove(src, dst, overwrites); err != nil {
+			return err
+		}
+	}
+	for _, src := range slices.Sorted(maps.Keys(p.Conflicts)) {
+		c := p.Conflicts[src]
+		if c.Resolution != Duplicate {
+			continue
+		}
+		same, err := sameContent(src, c.Dst)
+		if err != nil {
+			return err
+		}
+		if !same {
+			return fmt.Errorf("%s: no longer a duplicate of %s", src, c.Dst)
+		}
+		if err := os.Remove(src); err != nil {
+			return err
+		}
+		if err := j.addRemoval(src, c.Dst); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -1,6 +1,9 @@
 package sorter_test
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
 	"os"
 	"path/filepath"
 	"slices"
@@ -83,7 +86,10 @@ func TestApply_ExecutesMoves(t *testing.T) {
 		t.Fatalf("apply: %v", err)
 	}
 
-	gotDirs := listDir(t, root)
+	// Apply leaves a journal next to the class folders.
+	gotDirs := slices.DeleteFunc(listDir(t, root), func(name string) bool {
+		return strings.HasPrefix(name, sorter.JournalPrefix)
+	})
 	wantDirs := []string{"docs", "images", "other", "videos"}
 	slices.Sort(wantDirs)
 	if strings.Join(gotDirs, ",") != strings.Join(wantDirs, ",") {
@@ -104,3 +110,564 @@ func TestApply_ExecutesMoves(t *testing.T) {
 		t.Fatalf("missing moved other: %v", err)
 	}
 }
+
+// relMoves renders a plan as sorted "src -> dst" lines relative to the root.
+func relMoves(t *testing.T, p sorter.Plan) []string {
+	t.Helper()
+	var out []string
+	for src, dst := range p.Moves {
+		rs, err1 := filepath.Rel(p.Root, src)
+		rd, err2 := filepath.Rel(p.Root, dst)
+		if err1 != nil || err2 != nil {
+			t.Fatalf("move outside root: %s -> %s", src, dst)
+		}
+		out = append(out, filepath.ToSlash(rs)+" -> "+filepath.ToSlash(rd))
+	}
+	slices.Sort(out)
+	return out
+}
+
+func mkdirs(t *testing.T, root string, dirs ...string) {
+	t.Helper()
+	for _, d := range dirs {
+		if err := os.MkdirAll(filepath.Join(root, d), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", d, err)
+		}
+	}
+}
+
+func TestBuildPlanWith_RecursiveSkipsClassDirsAndHonorsDepth(t *testing.T) {
+	root := t.TempDir()
+	mkdirs(t, root, "images", "trip/day1/raw")
+	touch(t, root, "top.txt")
+	touch(t, root, "images/sorted.jpg")
+	touch(t, root, "trip/a.jpg")
+	touch(t, root, "trip/day1/b.mp4")
+	touch(t, root, "trip/day1/raw/c.png")
+
+	p, err := sorter.BuildPlanWith(root, sorter.Options{Recursive: true})
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	want := []string{
+		"top.txt -> docs/top.txt",
+		"trip/a.jpg -> images/trip/a.jpg",
+		"trip/day1/b.mp4 -> videos/trip/day1/b.mp4",
+		"trip/day1/raw/c.png -> images/trip/day1/raw/c.png",
+	}
+	if got := relMoves(t, p); !slices.Equal(got, want) {
+		t.Fatalf("recursive plan\nGOT:  %v\nWANT: %v", got, want)
+	}
+
+	p, err = sorter.BuildPlanWith(root, sorter.Options{Recursive: true, MaxDepth: 1})
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	want = []string{"top.txt -> docs/top.txt", "trip/a.jpg -> images/trip/a.jpg"}
+	if got := relMoves(t, p); !slices.Equal(got, want) {
+		t.Fatalf("depth-limited plan\nGOT:  %v\nWANT: %v", got, want)
+	}
+
+	// Sorting twice must not move anything out of the class folders again.
+	p, _ = sorter.BuildPlanWith(root, sorter.Options{Recursive: true})
+	if err := sorter.Apply(p); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	p, _ = sorter.BuildPlanWith(root, sorter.Options{Recursive: true})
+	if len(p.Moves) != 0 {
+		t.Fatalf("second recursive run planned %v", relMoves(t, p))
+	}
+}
+
+func TestBuildPlanWith_IncludeExcludeAndIgnoreFile(t *testing.T) {
+	root := t.TempDir()
+	mkdirs(t, root, "node_modules/pkg", "src/build", "photos")
+	touch(t, root, "node_modules/pkg/logo.png")
+	touch(t, root, "src/main.txt")
+	touch(t, root, "src/build/out.txt")
+	touch(t, root, "photos/a.jpg")
+	touch(t, root, "scratch.tmp")
+	touch(t, root, "keep.tmp")
+	if err := os.WriteFile(filepath.Join(root, sorter.IgnoreFile), []byte("# generated\nnode_modules/\n*.tmp\n!keep.tmp\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := sorter.BuildPlanWith(root, sorter.Options{Recursive: true, Exclude: []string{"src/build"}})
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	want := []string{
+		"keep.tmp -> other/keep.tmp",
+		"photos/a.jpg -> images/photos/a.jpg",
+		"src/main.txt -> docs/src/main.txt",
+	}
+	if got := relMoves(t, p); !slices.Equal(got, want) {
+		t.Fatalf("excluded plan\nGOT:  %v\nWANT: %v", got, want)
+	}
+
+	p, err = sorter.BuildPlanWith(root, sorter.Options{Recursive: true, Include: []string{"photos/", "**/*.txt"}})
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	want = []string{
+		"photos/a.jpg -> images/photos/a.jpg",
+		"src/build/out.txt -> docs/src/build/out.txt",
+		"src/main.txt -> docs/src/main.txt",
+	}
+	if got := relMoves(t, p); !slices.Equal(got, want) {
+		t.Fatalf("included plan\nGOT:  %v\nWANT: %v", got, want)
+	}
+
+	if _, err := sorter.BuildPlanWith(root, sorter.Options{Exclude: []string{"[z-"}}); err == nil {
+		t.Fatal("expected an error for a malformed pattern")
+	}
+}
+
+func TestParseRules_PriorityAndValidation(t *testing.T) {
+	rules, err := sorter.ParseRules([]byte(`{
+		"default": "misc",
+		"rules": [
+			{"class": "text", "extensions": ["TXT", ".md"]},
+			{"name": "notes", "class": "notes", "priority": 5, "globs": ["note-*"], "max_size": "1KB"},
+			{"class": "archives", "extensions": [".tar.gz"]},
+			{"class": "reports", "regexes": ["(?i)^report-\\d+"], "min_size": 10}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	cases := []struct {
+		name string
+		size int64
+		want string
+	}{
+		{"note-1.txt", 10, "notes"}, // higher priority wins over the earlier text rule
+		{"note-2.txt", 4096, "text"},
+		{"README.MD", 0, "text"},
+		{"backup.TAR.GZ", 0, "archives"},
+		{"Report-7.pdf", 10, "reports"},
+		{"report-8.pdf", 9, ""},
+		{"photo.jpg", 0, ""},
+	}
+	for _, tc := range cases {
+		got := ""
+		if rule := rules.Match(tc.name, tc.size, ""); rule != nil {
+			got = string(rule.Class)
+		}
+		if got != tc.want {
+			t.Errorf("Match(%q, %d) = %q, want %q", tc.name, tc.size, got, tc.want)
+		}
+	}
+	if got := rules.Classes(); !slices.Equal(got, []sorter.Class{"misc", "notes", "text", "archives", "reports"}) {
+		t.Errorf("classes = %v", got)
+	}
+
+	_, err = sorter.ParseRules([]byte(`{"rules": [
+		{"class": "../up"},
+		{"name": "bad", "class": "x", "globs": ["[a-"], "regexes": ["("], "min_size": "2MB", "max_size": "1MB"}
+	]}`))
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	requireAll(t, err.Error(), `rule #1: class "../up"`, "rule bad: invalid glob", "rule bad: invalid regex", "min_size is larger")
+
+	for _, doc := range []string{`{"rules": [{"class": "x", "priority": "high"}]}`, `{"rulez": []}`, `{"rules": [{"class": "x", "min_size": "lots"}]}`} {
+		if _, err := sorter.ParseRules([]byte(doc)); err == nil {
+			t.Errorf("expected an error for %s", doc)
+		}
+	}
+}
+
+func TestBuildPlanWith_RulesFile(t *testing.T) {
+	root := t.TempDir()
+	mkdirs(t, root, "shots", "inbox", "images")
+	touch(t, root, "Screenshot 1.png")
+	touch(t, root, "inbox/Screenshot 2.png")
+	touch(t, root, "shots/old.png")
+	// Left by a run without rules; not re-sorted into rest/images.
+	touch(t, root, "images/x.jpg")
+	touch(t, root, "inbox/photo.jpg")
+	rules := `{"default": "rest", "rules": [{"name": "screens", "class": "shots", "globs": ["Screenshot*"]}]}`
+	if err := os.WriteFile(filepath.Join(root, sorter.RulesFile), []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := sorter.BuildPlanWith(root, sorter.Options{Recursive: true})
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	want := []string{
+		"Screenshot 1.png -> shots/Screenshot 1.png",
+		"inbox/Screenshot 2.png -> shots/inbox/Screenshot 2.png",
+		"inbox/photo.jpg -> rest/inbox/photo.jpg",
+	}
+	if got := relMoves(t, p); !slices.Equal(got, want) {
+		t.Fatalf("rules plan\nGOT:  %v\nWANT: %v", got, want)
+	}
+	if got := p.Matched[filepath.Join(root, "Screenshot 1.png")]; got != "screens" {
+		t.Fatalf("matched rule = %q, want screens", got)
+	}
+	if got, ok := p.Matched[filepath.Join(root, "inbox", "photo.jpg")]; !ok || got != "" {
+		t.Fatalf("default file: matched = %q, %v", got, ok)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, sorter.RulesFile), []byte(`{"rules": [{}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sorter.BuildPlanWith(root, sorter.Options{}); err == nil || !strings.Contains(err.Error(), "class is required") {
+		t.Fatalf("expected the invalid rules file to fail the plan, got %v", err)
+	}
+}
+
+func requireAll(t *testing.T, s string, parts ...string) {
+	t.Helper()
+	for _, p := range parts {
+		if !strings.Contains(s, p) {
+			t.Fatalf("expected %q in %q", p, s)
+		}
+	}
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		t.Fatalf("write %s: %v", p, err)
+	}
+	return p
+}
+
+func zipFile(t *testing.T, dir, name string, files ...string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		w, err := zw.Create(files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(files[i+1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return writeFile(t, dir, name, buf.Bytes())
+}
+
+func TestDetectType(t *testing.T) {
+	dir := t.TempDir()
+	var tarball bytes.Buffer
+	tw := tar.NewWriter(&tarball)
+	_ = tw.WriteHeader(&tar.Header{Name: "a.txt", Mode: 0o644, Size: 1})
+	_, _ = tw.Write([]byte("x"))
+	_ = tw.Close()
+
+	cases := map[string]string{
+		writeFile(t, dir, "png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")):                   "image/png",
+		writeFile(t, dir, "jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF")):                         "image/jpeg",
+		writeFile(t, dir, "pdf", []byte("%PDF-1.7\n")):                                            "application/pdf",
+		writeFile(t, dir, "7z", []byte("7z\xbc\xaf\x27\x1c\x00\x04")):                             "application/x-7z-compressed",
+		writeFile(t, dir, "tar", tarball.Bytes()):                                                 "application/x-tar",
+		writeFile(t, dir, "flac", []byte("fLaC\x00\x00\x00\x22")):                                 "audio/flac",
+		writeFile(t, dir, "m4a", []byte("\x00\x00\x00\x20ftypM4A \x00\x00\x00\x00")):              "audio/mp4",
+		writeFile(t, dir, "mp4", []byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isommp41")):      "video/mp4",
+		writeFile(t, dir, "mov", []byte("\x00\x00\x00\x14ftypqt  \x00\x00\x02\x00")):              "video/quicktime",
+		writeFile(t, dir, "mkv", []byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01B\x82\x88matroska")): "video/x-matroska",
+		writeFile(t, dir, "doc", []byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1\x00\x00")):              "application/x-ole-storage",
+		writeFile(t, dir, "text", []byte("just some notes\n")):                                    "text/plain",
+		writeFile(t, dir, "empty", nil):                                                           "text/plain",
+		writeFile(t, dir, "binary", []byte{0, 1, 2, 3, 4, 5}):                                     "application/octet-stream",
+		zipFile(t, dir, "zip", "a.txt", "x"):                                                      "application/zip",
+		zipFile(t, dir, "docx", "[Content_Types].xml", "<Types/>", "word/document.xml", "<w/>"):   "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		zipFile(t, dir, "odt", "mimetype", "application/vnd.oasis.opendocument.text"):             "application/vnd.oasis.opendocument.text",
+	}
+	for file, want := range cases {
+		got, err := sorter.DetectType(file)
+		if err != nil {
+			t.Fatalf("detect %s: %v", file, err)
+		}
+		if got != want {
+			t.Errorf("DetectType(%s) = %q, want %q", filepath.Base(file), got, want)
+		}
+	}
+}
+
+func TestBuildPlanWith_DetectClassifiesByContentAndReportsMismatches(t *testing.T) {
+	root := t.TempDir()
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	writeFile(t, root, "photo.jpg", png)           // an image either way, wrong extension
+	writeFile(t, root, "scan", []byte("%PDF-1.4")) // no extension
+	writeFile(t, root, "clip.txt", []byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isommp41"))
+	writeFile(t, root, "notes.md", []byte("# notes\n")) // text goes by extension
+	writeFile(t, root, "right.png", png)
+
+	p, err := sorter.BuildPlanWith(root, sorter.Options{Detect: true})
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	want := []string{
+		"clip.txt -> videos/clip.txt",
+		"notes.md -> docs/notes.md",
+		"photo.jpg -> images/photo.jpg",
+		"right.png -> images/right.png",
+		"scan -> docs/scan",
+	}
+	if got := relMoves(t, p); !slices.Equal(got, want) {
+		t.Fatalf("detect plan\nGOT:  %v\nWANT: %v", got, want)
+	}
+	var mismatches []string
+	for _, m := range p.Mismatches {
+		mismatches = append(mismatches, filepath.Base(m.Src)+" "+m.MIME+" "+strings.Join(m.Want, ","))
+	}
+	wantMismatches := []string{"clip.txt video/mp4 .mp4,.m4v", "photo.jpg image/png .png", "scan application/pdf .pdf"}
+	if !slices.Equal(mismatches, wantMismatches) {
+		t.Fatalf("mismatches\nGOT:  %v\nWANT: %v", mismatches, wantMismatches)
+	}
+	if got := p.Types[filepath.Join(root, "notes.md")]; got != "text/plain" {
+		t.Fatalf("notes.md type = %q", got)
+	}
+
+	// Without --detect, rules on MIME types still see the content type but no
+	// mismatches are reported.
+	rules, err := sorter.ParseRules([]byte(`{"rules": [{"class": "pictures", "mime": ["image/*"]}]}`))
+	if err != nil {
+		t.Fatalf("parse rules: %v", err)
+	}
+	p, err = sorter.BuildPlanWith(root, sorter.Options{Rules: rules})
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	if got := relMoves(t, p); !slices.Contains(got, "photo.jpg -> pictures/photo.jpg") || !slices.Contains(got, "scan -> other/scan") {
+		t.Fatalf("MIME rules plan: %v", got)
+	}
+	if len(p.Mismatches) != 0 {
+		t.Fatalf("mismatches without Detect: %v", p.Mismatches)
+	}
+}
+
+func TestBuildPlanWith_ConflictPolicies(t *testing.T) {
+	seed := func(t *testing.T) string {
+		root := t.TempDir()
+		mkdirs(t, root, "docs", "other", "inbox")
+		writeFile(t, root, "same.txt", []byte("same"))
+		writeFile(t, root, "docs/same.txt", []byte("same"))
+		writeFile(t, root, "diff.txt", []byte("new"))
+		writeFile(t, root, "docs/diff.txt", []byte("old"))
+		writeFile(t, root, "docs/diff (1).txt", []byte("older"))
+		writeFile(t, root, "backup.tar.gz", []byte("gz"))
+		writeFile(t, root, "other/backup.tar.gz", []byte("gz2"))
+		return root
+	}
+	resolutions := func(p sorter.Plan) []string {
+		var out []string
+		for src, c := range p.Conflicts {
+			out = append(out, filepath.Base(src)+" "+string(c.Resolution))
+		}
+		slices.Sort(out)
+		return out
+	}
+
+	cases := []struct {
+		policy      sorter.Policy
+		moves       []string
+		resolutions []string
+		files       map[string]string // content after Apply
+	}{
+		{
+			policy: sorter.PolicyRename,
+			moves: []string{
+				"backup.tar.gz -> other/backup (1).tar.gz",
+				"diff.txt -> docs/diff (2).txt",
+				"same.txt -> docs/same (1).txt",
+			},
+			resolutions: []string{"backup.tar.gz renamed", "diff.txt renamed", "same.txt renamed"},
+			files:       map[string]string{"docs/diff.txt": "old", "docs/diff (2).txt": "new", "other/backup (1).tar.gz": "gz"},
+		},
+		{
+			policy:      sorter.PolicySkip,
+			resolutions: []string{"backup.tar.gz skipped", "diff.txt skipped", "same.txt skipped"},
+			files:       map[string]string{"diff.txt": "new", "docs/diff.txt": "old", "same.txt": "same"},
+		},
+		{
+			policy: sorter.PolicyOverwrite,
+			moves: []string{
+				"backup.tar.gz -> other/backup.tar.gz",
+				"diff.txt -> docs/diff.txt",
+				"same.txt -> docs/same.txt",
+			},
+			resolutions: []string{"backup.tar.gz overwrites", "diff.txt overwrites", "same.txt overwrites"},
+			files:       map[string]string{"docs/diff.txt": "new", "other/backup.tar.gz": "gz"},
+		},
+		{
+			policy: sorter.PolicyHashDedupe,
+			moves: []string{
+				"backup.tar.gz -> other/backup (1).tar.gz",
+				"diff.txt -> docs/diff (2).txt",
+			},
+			resolutions: []string{"backup.tar.gz renamed", "diff.txt renamed", "same.txt duplicate"},
+			files:       map[string]string{"docs/same.txt": "same", "docs/diff (2).txt": "new"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			root := seed(t)
+			p, err := sorter.BuildPlanWith(root, sorter.Options{OnConflict: tc.policy})
+			if err != nil {
+				t.Fatalf("build plan: %v", err)
+			}
+			if got := relMoves(t, p); !slices.Equal(got, tc.moves) {
+				t.Fatalf("moves\nGOT:  %v\nWANT: %v", got, tc.moves)
+			}
+			if got := resolutions(p); !slices.Equal(got, tc.resolutions) {
+				t.Fatalf("resolutions\nGOT:  %v\nWANT: %v", got, tc.resolutions)
+			}
+			if err := sorter.Apply(p); err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			for name, want := range tc.files {
+				got, err := os.ReadFile(filepath.Join(root, name))
+				if err != nil || string(got) != want {
+					t.Fatalf("%s = %q, %v; want %q", name, got, err, want)
+				}
+			}
+			if tc.policy == sorter.PolicyHashDedupe {
+				if _, err := os.Stat(filepath.Join(root, "same.txt")); !os.IsNotExist(err) {
+					t.Fatalf("duplicate should be removed, stat: %v", err)
+				}
+			}
+		})
+	}
+
+	if _, err := sorter.ParsePolicy("merge"); err == nil {
+		t.Fatal("expected an error for an unknown policy")
+	}
+}
+
+func TestApply_RefusesUnplannedOverwrites(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "a.txt", []byte("a"))
+	writeFile(t, root, "b.txt", []byte("b"))
+	p, err := sorter.BuildPlanWith(root, sorter.Options{OnConflict: sorter.PolicyHashDedupe})
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	// Something else creates the destination after the plan was built.
+	mkdirs(t, root, "docs")
+	writeFile(t, root, "docs/a.txt", []byte("other"))
+	err = sorter.Apply(p)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected apply to refuse the overwrite, got %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(root, "docs", "a.txt")); string(got) != "other" {
+		t.Fatalf("existing file was replaced: %q", got)
+	}
+}
+
+func TestUndo_ReversesJournalAndReportsChanges(t *testing.T) {
+	root := t.TempDir()
+	mkdirs(t, root, "trip", "docs")
+	writeFile(t, root, "a.jpg", []byte("a"))
+	writeFile(t, root, "trip/b.txt", []byte("b"))
+	writeFile(t, root, "dup.txt", []byte("same"))
+	writeFile(t, root, "docs/dup.txt", []byte("same"))
+	writeFile(t, root, "changed.md", []byte("c"))
+	writeFile(t, root, "gone.mp4", []byte("g"))
+	writeFile(t, root, "taken.png", []byte("t"))
+
+	p, err := sorter.BuildPlanWith(root, sorter.Options{Recursive: true, OnConflict: sorter.PolicyHashDedupe})
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	journal, err := sorter.ApplyJournal(p)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if latest, err := sorter.LatestJournal(root); err != nil || latest != journal {
+		t.Fatalf("latest journal = %q, %v; want %q", latest, err, journal)
+	}
+	if p, err := sorter.BuildPlanWith(root, sorter.Options{}); err != nil || len(p.Moves) != 0 {
+		t.Fatalf("the journal must not be sorted: %v, %v", relMoves(t, p), err)
+	}
+
+	// Things happen between the sort and the undo.
+	writeFile(t, root, "docs/changed.md", []byte("edited"))
+	if err := os.Remove(filepath.Join(root, "videos", "gone.mp4")); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, root, "taken.png", []byte("new"))
+
+	res, err := sorter.Undo(journal, false)
+	if err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	abs := func(rels ...string) []string {
+		var out []string
+		for _, rel := range rels {
+			out = append(out, filepath.Join(root, filepath.FromSlash(rel)))
+		}
+		return out
+	}
+	if res.Restored != 3 {
+		t.Errorf("restored %d files, want 3", res.Restored)
+	}
+	for name, got := range map[string][]string{"changed": res.Changed, "missing": res.Missing, "taken": res.Taken, "removed": res.Removed} {
+		want := map[string][]string{
+			"changed": abs("docs/changed.md"),
+			"missing": abs("videos/gone.mp4"),
+			"taken":   abs("taken.png"),
+			"removed": abs("docs/trip", "videos"),
+		}[name]
+		if !slices.Equal(got, want) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+	for rel, want := range map[string]string{"a.jpg": "a", "trip/b.txt": "b", "dup.txt": "same", "docs/dup.txt": "same", "taken.png": "new"} {
+		if got, err := os.ReadFile(filepath.Join(root, rel)); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", rel, got, err, want)
+		}
+	}
+	if res.Remaining != journal {
+		t.Fatalf("remaining journal = %q, want %q", res.Remaining, journal)
+	}
+
+	// Forcing moves the changed file back; what is gone stays reported.
+	res, err = sorter.Undo(journal, true)
+	if err != nil {
+		t.Fatalf("forced undo: %v", err)
+	}
+	if res.Restored != 1 || len(res.Missing) != 1 || len(res.Taken) != 1 {
+		t.Fatalf("forced undo = %+v", res)
+	}
+	if got, _ := os.ReadFile(filepath.Join(root, "changed.md")); string(got) != "edited" {
+		t.Fatalf("changed.md = %q", got)
+	}
+
+}
+
+func TestUndo_SetsCompletedJournalAside(t *testing.T) {
+	root := t.TempDir()
+	touch(t, root, "a.jpg")
+	touch(t, root, "b.txt")
+	p, err := sorter.BuildPlan(root, false)
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	journal, err := sorter.ApplyJournal(p)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	res, err := sorter.Undo(journal, false)
+	if err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	if res.Restored != 2 || res.Remaining != "" || len(res.Removed) != 2 {
+		t.Fatalf("undo = %+v", res)
+	}
+	want := []string{filepath.Base(journal) + ".undone", "a.jpg", "b.txt"}
+	if got := listDir(t, root); !slices.Equal(got, want) {
+		t.Fatalf("root after undo = %v, want %v", got, want)
+	}
+	if _, err := sorter.LatestJournal(root); err == nil {
+		t.Fatal("an undone journal must not be picked up again")
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/render"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// bulkCommand is `done`, `undone` or `rm`. Each accepts IDs, ranges such as 3-7
+// and filter flags such as --done or --tag, e.g. `rm --done`.
+func bulkCommand(cmd string, fs *flag.FlagSet) runFunc {
+	var ff filterFlags
+	ff.register(fs)
+	format := resultFormatFlag(fs)
+	var force bool
+	if cmd == "done" {
+		fs.BoolVar(&force, "force", false, "complete parents even when subtasks are still open")
+	}
+	return func(e *env, words []string) int {
+		if err := render.CheckFormat(*format, resultFormats); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		sel, err := tasks.ParseSelection(words)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid ID")
+			return 2
+		}
+		if ff.set() {
+			filter, err := ff.build(now())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+			sel.Filter = &filter
+		}
+		if sel.Empty() {
+			fmt.Fprintf(os.Stderr, "%s requires an ID, range or filter\n", cmd)
+			return 2
+		}
+
+		var changed, unchanged, spawned []int
+		var affected, next []tasks.Task
+		err = mutate(e.globals, e.jsonPath, e.store, cmd, func(list []tasks.Task) ([]tasks.Task, string, error) {
+			current := now()
+			ids, err := sel.Resolve(list, current)
+			if err != nil {
+				return nil, "", err
+			}
+			switch cmd {
+			case "done", "undone":
+				list, changed = tasks.SetDone(list, ids, cmd == "done", current)
+				unchanged = without(ids, changed)
+				if cmd == "done" && !force {
+					if err := tasks.CheckChildrenDone(list, changed); err != nil {
+						return nil, "", err
+					}
+				}
+				if cmd == "done" {
+					list, spawned = tasks.Recur(list, changed, current)
+					next = pick(list, spawned)
+				}
+				affected = pick(list, changed)
+			case "rm":
+				affected = pick(list, ids)
+				list, changed = tasks.RemoveAll(list, ids), ids
+			}
+			return list, fmt.Sprintf("%s %s", pastTense(cmd), tasks.FormatIDs(changed)), nil
+		})
+		if err != nil {
+			return selectionFailure(err)
+		}
+
+		if *format == render.FormatJSON {
+			return writeResult(render.Result{Op: cmd, IDs: changed, Unchanged: unchanged, Spawned: spawned, Tasks: affected})
+		}
+		defer printNext(next)
+		if id, ok := sel.Single(); ok {
+			fmt.Fprintf(os.Stdout, "%s #%d\n", pastTense(cmd), id)
+			return 0
+		}
+		if len(changed) > 0 {
+			fmt.Fprintf(os.Stdout, "%s %s (%s)\n", pastTense(cmd), tasks.FormatIDs(changed), countTasks(len(changed)))
+		}
+		if len(unchanged) > 0 {
+			state := "done"
+			if cmd == "undone" {
+				state = "open"
+			}
+			fmt.Fprintf(os.Stdout, "already %s: %s\n", state, tasks.FormatIDs(unchanged))
+		}
+		return 0
+	}
+}
+
+// editCommand is `edit <id> [text...] [--parent id] [--block ids] [--unblock ids]`.
+func editCommand(fs *flag.FlagSet) runFunc {
+	due := fs.String("due", "", "new due date, or none to clear it")
+	remind := fs.String("remind", "", "new reminder time (absolute or -30min, -1d before the due date), or none")
+	parent := fs.String("parent", "", "make the task a subtask of this ID (0 or none for top level)")
+	block := fs.String("block", "", "IDs of tasks that must be done first")
+	unblock := fs.String("unblock", "", "IDs of blockers to remove")
+	format := resultFormatFlag(fs)
+	return func(e *env, words []string) int {
+		if err := render.CheckFormat(*format, resultFormats); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		fields := *due != "" || *remind != "" || *parent != "" || *block != "" || *unblock != ""
+		if len(words) < 1 || (len(words) < 2 && !fields) {
+			fmt.Fprintln(os.Stderr, "edit requires an ID and new text")
+			return 2
+		}
+		id, err := strconv.Atoi(words[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid ID")
+			return 2
+		}
+		text := strings.TrimSpace(strings.Join(words[1:], " "))
+		if text == "" && !fields {
+			fmt.Fprintln(os.Stderr, "edit requires new text")
+			return 2
+		}
+		parentID := -1
+		switch *parent {
+		case "":
+		case "none":
+			parentID = 0
+		default:
+			if parentID, err = strconv.Atoi(strings.TrimPrefix(*parent, "#")); err != nil || parentID < 0 {
+				fmt.Fprintln(os.Stderr, "invalid parent ID")
+				return 2
+			}
+		}
+		current := now()
+		var dueAt *time.Time
+		if *due != "" && *due != "none" {
+			d, err := tasks.ParseDue(*due, current)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+			dueAt = &d
+		}
+		if *remind != "" && *remind != "none" {
+			// Check the syntax now; offsets are resolved against the due date
+			// the task ends up with.
+			if _, err := tasks.ParseRemind(*remind, &current, current); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+		}
+		blockIDs, err := parseIDs(*block)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		unblockIDs, err := parseIDs(*unblock)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+
+		var edited []tasks.Task
+		err = mutate(e.globals, e.jsonPath, e.store, "edit", func(list []tasks.Task) ([]tasks.Task, string, error) {
+			var err error
+			if text != "" {
+				if list, err = tasks.Edit(list, id, text); err != nil {
+					return list, "", err
+				}
+			}
+			if *due != "" {
+				if list, err = tasks.SetDue(list, id, dueAt); err != nil {
+					return list, "", err
+				}
+			}
+			if *remind != "" {
+				var at *time.Time
+				if *remind != "none" {
+					t := pick(list, []int{id})
+					if len(t) == 0 {
+						return list, "", tasks.ErrNotFound
+					}
+					r, err := tasks.ParseRemind(*remind, t[0].Due, current)
+					if err != nil {
+						return list, "", err
+					}
+					at = &r
+				}
+				if list, err = tasks.SetRemind(list, id, at); err != nil {
+					return list, "", err
+				}
+			}
+			if parentID >= 0 {
+				if list, err = tasks.SetParent(list, id, parentID); err != nil {
+					return list, "", err
+				}
+			}
+			if len(blockIDs) > 0 {
+				if list, err = tasks.Block(list, id, blockIDs); err != nil {
+					return list, "", err
+				}
+			}
+			if len(unblockIDs) > 0 {
+				if list, err = tasks.Unblock(list, id, unblockIDs); err != nil {
+					return list, "", err
+				}
+			}
+			edited = pick(list, []int{id})
+			return list, strings.TrimSpace(fmt.Sprintf("edited #%d %s", id, text)), nil
+		})
+		if err != nil {
+			return failure(err)
+		}
+		if *format == render.FormatJSON {
+			return writeResult(render.Result{Op: "edit", IDs: []int{id}, Tasks: edited})
+		}
+		fmt.Fprintf(os.Stdout, "edited #%d\n", id)
+		return 0
+	}
+}
+
+// selectionFailure is failure for commands that take a selection: an empty
+// match and several missing IDs are reported as usage errors.
+func selectionFailure(err error) int {
+	if errors.Is(err, tasks.ErrEmptySelection) {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	var missing *tasks.MissingError
+	if errors.As(err, &missing) && len(missing.IDs) > 1 {
+		fmt.Fprintln(os.Stderr, "no such tasks:", tasks.FormatIDs(missing.IDs))
+		return 2
+	}
+	return failure(err)
+}
+
+// printNext reports the occurrences spawned by completing recurring tasks.
+func printNext(next []tasks.Task) {
+	current := now()
+	for _, t := range next {
+		fmt.Fprintln(os.Stdout, "next: "+render.Line(t, current))
+	}
+}
+
+func pastTense(cmd string) string {
+	switch cmd {
+	case "rm":
+		return "removed"
+	case "undone":
+		return "reopened"
+	}
+	return cmd
+}
+
+func countTasks(n int) string {
+	if n == 1 {
+		return "1 task"
+	}
+	return fmt.Sprintf("%d tasks", n)
+}
+
+// pick returns copies of the tasks in list whose IDs are in ids.
+func pick(list []tasks.Task, ids []int) []tasks.Task {
+	want := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	var out []tasks.Task
+	for _, t := range list {
+		if want[t.ID] {
+			out = append(out, t.Clone())
+		}
+	}
+	return out
+}
+
+// without returns the IDs in all that are not in some.
+func without(all, some []int) []int {
+	skip := make(map[int]bool, len(some))
+	for _, id := range some {
+		skip[id] = true
+	}
+	var out []int
+	for _, id := range all {
+		if !skip[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// parseIDs reads a comma- or space-separated list of task IDs such as "3,5".
+func parseIDs(s string) ([]int, error) {
+	sel, err := tasks.ParseSelection(strings.Fields(s))
+	if err != nil || len(sel.Ranges) > 0 {
+		return nil, fmt.Errorf("invalid ID list %q", s)
+	}
+	return sel.IDs, nil
+}
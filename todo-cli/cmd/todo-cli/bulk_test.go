@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBulkDoneRemoveEditAndUndone(t *testing.T) {
+	t.Setenv("TODO_CLI_PATH", filepath.Join(t.TempDir(), "tasks.json"))
+
+	run := func(wantExit int, args ...string) (string, string) {
+		t.Helper()
+		stdout, stderr, exit := runMenuHarness(t, args, "")
+		if exit != wantExit {
+			t.Fatalf("%v: expected exit %d, got %d (stdout=%q stderr=%q)", args, wantExit, exit, stdout, stderr)
+		}
+		return stdout, stderr
+	}
+
+	for i := 0; i < 8; i++ {
+		run(0, "add", "task", string(rune('A'+i)))
+	}
+
+	out, _ := run(0, "done", "1", "3-5")
+	requireContainsAll(t, out, []string{"done #1, #3, #4, #5 (4 tasks)"})
+
+	out, _ = run(0, "done", "4-6")
+	requireContainsAll(t, out, []string{"done #6 (1 task)", "already done: #4, #5"})
+
+	out, _ = run(0, "undone", "5")
+	requireContainsAll(t, out, []string{"reopened #5"})
+
+	out, _ = run(0, "edit", "2", "task", "B", "fixed")
+	requireContainsAll(t, out, []string{"edited #2"})
+
+	out, _ = run(0, "rm", "--done")
+	requireContainsAll(t, out, []string{"removed #1, #3, #4, #6 (4 tasks)"})
+
+	list, _ := run(0, "list", "--reverse")
+	requireContainsInOrder(t, list, []string{"[ ] #2 task B fixed", "[ ] #5 task E", "[ ] #7 task G", "[ ] #8 task H"})
+	if strings.Contains(list, "#1 ") || strings.Contains(list, "#6 ") {
+		t.Fatalf("expected completed tasks removed, got %q", list)
+	}
+
+	_, stderr := run(2, "done", "2", "40", "41")
+	requireContainsAll(t, stderr, []string{"no such tasks: #40, #41"})
+	if list, _ := run(0, "list", "--done"); list != "" {
+		t.Fatalf("failed bulk done must not change anything, got %q", list)
+	}
+
+	_, stderr = run(2, "rm", "--done")
+	requireContainsAll(t, stderr, []string{"no tasks match"})
+	_, stderr = run(2, "edit", "99", "nothing")
+	requireContainsAll(t, stderr, []string{"no such task"})
+	run(2, "done")
+	run(2, "rm", "3-x")
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+)
+
+// env is what a command runs against: the global options, the selected list
+// and, for commands that read or change its tasks, the opened store.
+type env struct {
+	globals  globals
+	list     string
+	jsonPath string
+	store    storage.Store
+}
+
+// runFunc runs a command once its flags are parsed. args are the positional
+// arguments that are left.
+type runFunc func(e *env, args []string) int
+
+// command is an entry of the command registry, which Run, ParseCommand,
+// help and the completion scripts all work from.
+type command struct {
+	Name string
+	// Usage sketches the positional arguments, e.g. "<text...>".
+	Usage   string
+	Summary string
+	// Help is an optional longer description for `help <command>`.
+	Help string
+	// Flags registers the command's flags on fs and returns the function
+	// that runs the command once they are parsed. It must not do anything
+	// else: help and completion call it only to list the flags.
+	Flags func(fs *flag.FlagSet) runFunc
+	// Store opens the selected list before the command runs.
+	Store bool
+	// Raw commands get their arguments exactly as given, without flag
+	// parsing, e.g. config values such as "add --tag today".
+	Raw bool
+	// Early commands run before the config file is read, so a broken
+	// setting can still be fixed.
+	Early bool
+	// Hidden commands are left out of help and completion.
+	Hidden bool
+
+	// IDs selects the tasks whose IDs complete the arguments: open, done or
+	// all. Words are fixed words that complete them, and Files completes
+	// file names.
+	IDs   string
+	Words []string
+	Files bool
+}
+
+// noFlags adapts a command without flags of its own.
+func noFlags(run runFunc) func(*flag.FlagSet) runFunc {
+	return func(*flag.FlagSet) runFunc { return run }
+}
+
+// commands is the registry, in the order help lists it. It is filled in
+// init because the help command refers back to it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{Name: "add", Usage: "[--] <text...>", Summary: "Add a task", Flags: addCommand, Store: true,
+			Help: "Put text that starts with a dash after --: todo-cli add -- -5 degrees, wear a coat"},
+		{Name: "list", Summary: "List tasks, newest first", Flags: listCommand("list"), Store: true},
+		{Name: "ready", Summary: "List open tasks that nothing holds up", Flags: listCommand("ready"), Store: true},
+		{Name: "edit", Usage: "<id> [text...]", Summary: "Change the text, dates or links of a task", Flags: editCommand, Store: true, IDs: "all"},
+		{Name: "done", Usage: "<id|range...>", Summary: "Mark the selected tasks as done", Flags: bulk("done"), Store: true, IDs: "open"},
+		{Name: "undone", Usage: "<id|range...>", Summary: "Reopen the selected tasks", Flags: bulk("undone"), Store: true, IDs: "done"},
+		{Name: "rm", Usage: "<id|range...>", Summary: "Remove the selected tasks", Flags: bulk("rm"), Store: true, IDs: "all"},
+		{Name: "mv", Usage: "<id|range...> --to <list>", Summary: "Move the selected tasks to another list", Flags: moveCommand, IDs: "all"},
+		{Name: "lists", Summary: "Show every list with its task counts", Flags: noFlags(runLists)},
+		{Name: "clear", Summary: "Remove all tasks", Flags: noFlags(runClear), Store: true},
+		{Name: "undo", Usage: "[n]", Summary: "Revert the last n changes", Flags: undoRedo("undo"), Store: true},
+		{Name: "redo", Usage: "[n]", Summary: "Reapply the last n undone changes", Flags: undoRedo("redo"), Store: true},
+		{Name: "history", Summary: "Show the change journal, newest first", Flags: historyCommand, Store: true},
+		{Name: "search", Usage: "<query...>", Summary: "Find tasks by text or tag, best matches first", Flags: searchCommand, Store: true},
+		{Name: "import", Usage: "<file|->", Summary: "Add tasks from a todo.txt, Markdown or JSON file", Flags: importCommand, Store: true, Files: true},
+		{Name: "export", Summary: "Write tasks as todo.txt, Markdown or JSON", Flags: exportCommand, Store: true},
+		{Name: "tui", Summary: "Open the full-screen task view", Flags: noFlags(func(e *env, args []string) int {
+			return runTUI(e.globals, e.jsonPath, e.store, args)
+		}), Store: true},
+		{Name: "serve", Summary: "Serve the REST API until interrupted", Flags: serveCommand, Store: true},
+		{Name: "remind", Summary: "Show or watch reminders and due tasks", Flags: remindCommand, Store: true},
+		{Name: "stats", Summary: "Show throughput, backlog and streaks", Flags: statsCommand, Store: true},
+		{Name: "sync", Usage: "[init [url]]", Summary: "Sync the lists with a git remote", Flags: noFlags(func(e *env, args []string) int {
+			return runSync(e.globals, args)
+		}), Words: []string{"init"}},
+		{Name: "config", Usage: "get [key] | set <key> <value...> | unset <key> | path", Summary: "Read or change the config file", Flags: noFlags(func(_ *env, args []string) int {
+			return runConfig(args)
+		}), Raw: true, Early: true, Words: []string{"get", "set", "unset", "path"}},
+		{Name: "completion", Usage: "bash|zsh|fish", Summary: "Print a shell completion script", Flags: noFlags(func(_ *env, args []string) int {
+			return runCompletion(args)
+		}), Raw: true, Words: shells},
+		{Name: "menu", Summary: "Open the interactive menu", Flags: noFlags(func(e *env, args []string) int {
+			if len(args) != 0 {
+				fmt.Fprintln(os.Stderr, "usage: todo-cli menu")
+				return 2
+			}
+			return runMenu(e.globals)
+		})},
+		{Name: "help", Usage: "[command]", Summary: "Show the commands, or the flags of one", Flags: noFlags(runHelp)},
+		{Name: "__complete", Flags: noFlags(func(e *env, args []string) int {
+			return runComplete(e.globals, e.list, args)
+		}), Raw: true, Hidden: true},
+	}
+}
+
+// lookup finds a command by name.
+func lookup(name string) (*command, bool) {
+	for i := range commands {
+		if commands[i].Name == name {
+			return &commands[i], true
+		}
+	}
+	return nil, false
+}
+
+func bulk(cmd string) func(*flag.FlagSet) runFunc {
+	return func(fs *flag.FlagSet) runFunc { return bulkCommand(cmd, fs) }
+}
+
+func undoRedo(cmd string) func(*flag.FlagSet) runFunc {
+	return noFlags(func(e *env, args []string) int {
+		return runUndoRedo(e.globals, e.jsonPath, e.store, cmd, args)
+	})
+}
+
+// globals are the options every command accepts, before or after its name.
+type globals struct {
+	storage string
+	list    string
+}
+
+func (g *globals) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.storage, "storage", g.storage, "tasks file of the default list (overrides $TODO_CLI_PATH and the config)")
+	fs.StringVar(&g.list, "list", g.list, "list to work on (default $TODO_CLI_LIST, the config, or default)")
+}
+
+// args renders the options for passing them on to another Run.
+func (g globals) args() []string {
+	var out []string
+	if g.storage != "" {
+		out = append(out, "--storage", g.storage)
+	}
+	if g.list != "" {
+		out = append(out, "--list", g.list)
+	}
+	return out
+}
+
+// errInvalidFlags reports flags a command does not accept.
+var errInvalidFlags = errors.New("invalid flags")
+
+// splitGlobals parses the global options in front of the command name.
+func splitGlobals(args []string) (globals, []string, error) {
+	var g globals
+	fs := flag.NewFlagSet("todo-cli", flag.ContinueOnError)
+	fs.SetOutput(new(nopWriter))
+	g.register(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return g, nil, err
+		}
+		return g, nil, errInvalidFlags
+	}
+	return g, fs.Args(), nil
+}
+
+// invocation is a parsed command line.
+type invocation struct {
+	cmd     *command
+	globals globals
+	args    []string
+	run     runFunc
+}
+
+// parseCommand looks up the command in args[0] and parses its flags,
+// global options included, out of the rest. Flags may follow positional
+// arguments, e.g. `add buy milk --due tomorrow`.
+func parseCommand(args []string, g globals) (invocation, error) {
+	c, ok := lookup(args[0])
+	if !ok {
+		return invocation{}, ErrUnknownCommand
+	}
+	fs := flag.NewFlagSet(c.Name, flag.ContinueOnError)
+	fs.SetOutput(new(nopWriter))
+	inv := invocation{cmd: c, globals: g, run: c.Flags(fs), args: args[1:]}
+	if c.Raw {
+		return inv, nil
+	}
+	inv.globals.register(fs)
+	words, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return inv, err
+		}
+		return inv, errInvalidFlags
+	}
+	inv.args = words
+	return inv, nil
+}
+
+// resolveList picks the list named by --list, or the default one.
+func resolveList(g globals) (string, error) {
+	list := g.list
+	if list == "" {
+		list = defaultList()
+	}
+	if list != storage.DefaultListName {
+		if err := storage.CheckListName(list); err != nil {
+			return "", err
+		}
+	}
+	return list, nil
+}
+
+// runHelp handles `help [command]`.
+func runHelp(_ *env, args []string) int {
+	switch len(args) {
+	case 0:
+		writeUsage(os.Stdout)
+		return 0
+	case 1:
+		c, ok := lookup(args[0])
+		if !ok || c.Hidden {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+			return 2
+		}
+		writeHelp(os.Stdout, c)
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, "usage: todo-cli help [command]")
+	return 2
+}
+
+// writeUsage lists the commands.
+func writeUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: todo-cli [--storage file] [--list name] <command> [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		if !c.Hidden {
+			fmt.Fprintf(w, "  %-11s %s\n", c.Name, c.Summary)
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run `todo-cli help <command>` for the flags of a command.")
+}
+
+// writeHelp describes one command and its flags.
+func writeHelp(w io.Writer, c *command) {
+	usage := "usage: todo-cli " + c.Name
+	fs := flag.NewFlagSet(c.Name, flag.ContinueOnError)
+	c.Flags(fs)
+	own := flagHelp(fs)
+	if len(own) > 0 {
+		usage += " [flags]"
+	}
+	if c.Usage != "" {
+		usage += " " + c.Usage
+	}
+	fmt.Fprintln(w, usage)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, c.Summary+".")
+	if c.Help != "" {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, c.Help)
+	}
+	if len(own) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Flags:")
+		fmt.Fprint(w, strings.Join(own, ""))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Global flags:")
+	var g globals
+	gs := flag.NewFlagSet("todo-cli", flag.ContinueOnError)
+	g.register(gs)
+	fmt.Fprint(w, strings.Join(flagHelp(gs), ""))
+}
+
+// flagHelp formats the flags of fs as aligned lines, by name.
+func flagHelp(fs *flag.FlagSet) []string {
+	type row struct{ name, usage string }
+	var rows []row
+	width := 0
+	fs.VisitAll(func(f *flag.Flag) {
+		kind, usage := flag.UnquoteUsage(f)
+		name := "--" + f.Name
+		if kind != "" && !isBoolFlag(f) {
+			name += " " + kind
+		}
+		if f.DefValue != "" && f.DefValue != "0" && f.DefValue != "false" {
+			usage += fmt.Sprintf(" (default %s)", f.DefValue)
+		}
+		rows = append(rows, row{name, usage})
+		if len(name) > width {
+			width = len(name)
+		}
+	})
+	sort.Slice(rows, func(i, j int) bool { return rows[i].name < rows[j].name })
+	out := make([]string, len(rows))
+	for i, r := range rows {
+		out[i] = fmt.Sprintf("  %-*s  %s\n", width, r.name, r.usage)
+	}
+	return out
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
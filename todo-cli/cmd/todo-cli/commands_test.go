@@ -0,0 +1,85 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHelp(t *testing.T) {
+	t.Setenv("TODO_CLI_PATH", filepath.Join(t.TempDir(), "tasks.json"))
+
+	out, stderr, exit := runMenuHarness(t, []string{"help"}, "")
+	if exit != 0 {
+		t.Fatalf("help: exit %d (%q)", exit, stderr)
+	}
+	for _, c := range commands {
+		if c.Hidden {
+			if strings.Contains(out, c.Name) {
+				t.Fatalf("help lists hidden command %q", c.Name)
+			}
+			continue
+		}
+		requireContainsAll(t, out, []string{"  " + c.Name + " ", c.Summary})
+	}
+
+	out, _, exit = runMenuHarness(t, []string{"help", "add"}, "")
+	if exit != 0 {
+		t.Fatalf("help add: exit %d", exit)
+	}
+	requireContainsInOrder(t, out, []string{
+		"usage: todo-cli add [flags] [--] <text...>",
+		"Add a task.",
+		"Flags:", "--due string", "--format string", "result format: text or json (default text)", "--tag value",
+		"Global flags:", "--list string", "--storage string",
+	})
+
+	for _, args := range [][]string{{"add", "-h"}, {"add", "buy milk", "--help"}} {
+		got, _, exit := runMenuHarness(t, args, "")
+		if exit != 0 || got != out {
+			t.Fatalf("%v: exit %d, output %q, want the help of add", args, exit, got)
+		}
+	}
+	if out, _, exit := runMenuHarness(t, []string{"--help"}, ""); exit != 0 || !strings.Contains(out, "Commands:") {
+		t.Fatalf("--help: exit %d, output %q", exit, out)
+	}
+	for _, args := range [][]string{{"help", "bogus"}, {"help", "__complete"}, {"help", "add", "list"}, {"bogus"}, {"list", "--bogus"}} {
+		if _, _, exit := runMenuHarness(t, args, ""); exit != 2 {
+			t.Fatalf("%v: exit %d, want 2", args, exit)
+		}
+	}
+}
+
+func TestStorageFlag(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TODO_CLI_PATH", filepath.Join(dir, "env.json"))
+	other := filepath.Join(dir, "other", "tasks.json")
+
+	run := func(args ...string) string {
+		t.Helper()
+		stdout, stderr, exit := runMenuHarness(t, args, "")
+		if exit != 0 {
+			t.Fatalf("%v: exit %d (stdout=%q stderr=%q)", args, exit, stdout, stderr)
+		}
+		return stdout
+	}
+
+	run("add", "Env task")
+	run("--storage", other, "add", "Flag task")
+	run("add", "Second flag task", "--storage", other)
+	run("done", "--storage="+other, "1")
+	run("--storage", other, "--list", "work", "add", "Work task")
+
+	out := run("list", "--storage", other, "--reverse")
+	requireContainsInOrder(t, out, []string{"[x] #1 Flag task", "[ ] #2 Second flag task"})
+	if strings.Contains(out, "Env task") || strings.Contains(out, "Work task") {
+		t.Fatalf("--storage list shows other tasks: %q", out)
+	}
+	if out := run("list"); !strings.Contains(out, "Env task") || strings.Contains(out, "Flag task") {
+		t.Fatalf("list without --storage should use $TODO_CLI_PATH, got %q", out)
+	}
+	out = run("lists", "--storage", other)
+	requireContainsInOrder(t, out, []string{"* default (1 open, 2 tasks)", "  work (1 open, 1 task)"})
+	out = run("history", "--storage", other)
+	requireContainsAll(t, out, []string{"added #2 Second flag task"})
+}
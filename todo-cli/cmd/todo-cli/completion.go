@@ -0,0 +1,370 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+)
+
+// flagValues are the fixed values of flags, for every command using them.
+var flagValues = map[string][]string{
+	"priority": {"low", "medium", "high"},
+	"color":    {"auto", "always", "never"},
+}
+
+// shells are the shells `completion` writes scripts for.
+var shells = []string{"bash", "zsh", "fish"}
+
+// runCompletion handles `completion bash|zsh|fish`.
+func runCompletion(args []string) int {
+	if len(args) != 1 || !contains(shells, args[0]) {
+		fmt.Fprintf(os.Stderr, "usage: todo-cli completion %s\n", strings.Join(shells, "|"))
+		return 2
+	}
+	if err := completionScripts.ExecuteTemplate(os.Stdout, args[0], completionData()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// runComplete handles the hidden `__complete` command the scripts call:
+// `__complete lists` prints the list names and `__complete ids open|done|all`
+// prints the matching tasks of the current list as "<id>\t<text>". Nothing is
+// printed on errors, so a broken tasks file does not garble the prompt.
+func runComplete(g globals, listName string, args []string) int {
+	switch {
+	case len(args) == 1 && args[0] == "lists":
+		base, err := basePath(g)
+		if err != nil {
+			return 1
+		}
+		names, err := storage.ListNames(base)
+		if err != nil {
+			return 1
+		}
+		for _, name := range names {
+			fmt.Fprintln(os.Stdout, name)
+		}
+		return 0
+	case len(args) == 2 && args[0] == "ids" && contains([]string{"open", "done", "all"}, args[1]):
+	default:
+		return 2
+	}
+	jsonPath, store, err := openList(g, listName)
+	if err != nil {
+		return 1
+	}
+	lock, err := storage.AcquireSharedLock(jsonPath)
+	if err != nil {
+		return 1
+	}
+	list, err := store.Load()
+	lock.Release()
+	if err != nil {
+		return 1
+	}
+	for _, t := range list {
+		if (args[1] == "open" && t.Done) || (args[1] == "done" && !t.Done) {
+			continue
+		}
+		text := strings.Join(strings.Fields(t.Text), " ")
+		fmt.Fprintf(os.Stdout, "%d\t%s\n", t.ID, text)
+	}
+	return 0
+}
+
+// completionFlag is a flag as the script templates see it.
+type completionFlag struct {
+	Name   string
+	Value  bool
+	Values []string
+}
+
+type completionCommandData struct {
+	Name    string
+	Summary string
+	Flags   []completionFlag
+	Words   []string
+	IDs     string
+	Files   bool
+}
+
+type completionScriptData struct {
+	Commands []completionCommandData
+	// ValueFlags are the flags of any command that take a value, with
+	// dashes, joined by "|". The flags naming lists or files are handled
+	// separately.
+	ValueFlags string
+	Values     map[string][]string
+}
+
+// completionData describes the registered commands for the scripts. The
+// flags come from registering them on a throwaway FlagSet, global options
+// included.
+func completionData() completionScriptData {
+	data := completionScriptData{Values: flagValues}
+	seen := map[string]bool{"list": true, "to": true, "storage": true, "output": true}
+	var valueFlags []string
+	for _, c := range commands {
+		if c.Hidden {
+			continue
+		}
+		cd := completionCommandData{Name: c.Name, Summary: c.Summary, Words: c.Words, IDs: c.IDs, Files: c.Files}
+		fs := flag.NewFlagSet(c.Name, flag.ContinueOnError)
+		c.Flags(fs)
+		if !c.Raw {
+			new(globals).register(fs)
+		}
+		fs.VisitAll(func(f *flag.Flag) {
+			value := !isBoolFlag(f)
+			cd.Flags = append(cd.Flags, completionFlag{Name: f.Name, Value: value, Values: flagValues[f.Name]})
+			if value && !seen[f.Name] {
+				seen[f.Name] = true
+				valueFlags = append(valueFlags, "--"+f.Name)
+			}
+		})
+		data.Commands = append(data.Commands, cd)
+	}
+	data.ValueFlags = strings.Join(valueFlags, "|")
+	return data
+}
+
+var completionScripts = template.Must(template.New("").Funcs(template.FuncMap{
+	"join": strings.Join,
+	"flags": func(fs []completionFlag) string {
+		names := make([]string, len(fs))
+		for i, f := range fs {
+			names[i] = "--" + f.Name
+		}
+		return strings.Join(names, " ")
+	},
+}).Parse(`{{define "bash"}}# bash completion for todo-cli. Load it with:
+#   source <(todo-cli completion bash)
+
+_todo_cli() {
+    local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+    local cmd="" i
+    local -a opts=()
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case ${COMP_WORDS[i]} in
+        --list|--storage) opts+=("${COMP_WORDS[i]}" "${COMP_WORDS[i+1]}"); ((i++)) ;;
+        -*) ;;
+        *) cmd=${COMP_WORDS[i]}; break ;;
+        esac
+    done
+
+    case $prev in
+    --list|--to)
+        COMPREPLY=($(compgen -W "$("${COMP_WORDS[0]}" "${opts[@]}" __complete lists 2>/dev/null)" -- "$cur"))
+        return ;;
+    --storage|--output)
+        COMPREPLY=($(compgen -f -- "$cur"))
+        return ;;
+{{- range $name, $values := .Values}}
+    --{{$name}})
+        COMPREPLY=($(compgen -W "{{join $values " "}}" -- "$cur"))
+        return ;;
+{{- end}}
+    {{.ValueFlags}})
+        return ;;
+    esac
+
+    if [[ -z $cmd ]]; then
+        COMPREPLY=($(compgen -W "--list --storage{{range .Commands}} {{.Name}}{{end}}" -- "$cur"))
+        return
+    fi
+
+    local flags="" args="" ids="" files=""
+    case $cmd in
+{{- range .Commands}}
+    {{.Name}}) flags="{{flags .Flags}}"{{with .Words}} args="{{join . " "}}"{{end}}{{with .IDs}} ids={{.}}{{end}}{{if .Files}} files=1{{end}} ;;
+{{- end}}
+    esac
+
+    if [[ $cur == -* ]]; then
+        COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+    elif [[ -n $ids ]]; then
+        # Show "id  -- text" while several tasks match; insert the bare ID.
+        local line
+        local -a matches=()
+        while IFS= read -r line; do
+            [[ ${line%%$'\t'*} == "$cur"* ]] && matches+=("$line")
+        done < <("${COMP_WORDS[0]}" "${opts[@]}" __complete ids "$ids" 2>/dev/null)
+        if ((${#matches[@]} == 1)); then
+            COMPREPLY=("${matches[0]%%$'\t'*}")
+        else
+            COMPREPLY=("${matches[@]/$'\t'/  -- }")
+        fi
+    elif [[ -n $args ]]; then
+        COMPREPLY=($(compgen -W "$args" -- "$cur"))
+    elif [[ -n $files ]]; then
+        COMPREPLY=($(compgen -f -- "$cur"))
+    fi
+}
+
+complete -F _todo_cli todo-cli
+{{end}}
+
+{{- define "zsh"}}#compdef todo-cli
+# zsh completion for todo-cli. Load it with:
+#   source <(todo-cli completion zsh)
+# or save it as _todo-cli in a directory on $fpath.
+
+_todo-cli() {
+    local cmd i
+    local -a opts
+    for ((i = 2; i < CURRENT; i++)); do
+        case ${words[i]} in
+        --list|--storage) opts+=(${words[i]} ${words[i+1]}); ((i++)) ;;
+        --list=*|--storage=*) opts+=(${words[i]}) ;;
+        -*) ;;
+        *) cmd=${words[i]}; break ;;
+        esac
+    done
+
+    case ${words[CURRENT-1]} in
+    --list|--to)
+        local -a names
+        names=(${(f)"$(${words[1]} $opts __complete lists 2>/dev/null)"})
+        compadd -a names
+        return ;;
+    --storage|--output)
+        _files
+        return ;;
+{{- range $name, $values := .Values}}
+    --{{$name}})
+        compadd {{join $values " "}}
+        return ;;
+{{- end}}
+    {{.ValueFlags}})
+        return 1 ;;
+    esac
+
+    if [[ -z $cmd ]]; then
+        if [[ $PREFIX == -* ]]; then
+            compadd -- --list --storage
+        else
+            local -a commands
+            commands=(
+{{- range .Commands}}
+                '{{.Name}}:{{.Summary}}'
+{{- end}}
+            )
+            _describe command commands
+        fi
+        return
+    fi
+
+    local -a flags args
+    local ids files
+    case $cmd in
+{{- range .Commands}}
+    {{.Name}}) flags=({{flags .Flags}}){{with .Words}} args=({{join . " "}}){{end}}{{with .IDs}} ids={{.}}{{end}}{{if .Files}} files=1{{end}} ;;
+{{- end}}
+    esac
+
+    if [[ $PREFIX == -* ]]; then
+        compadd -a flags
+    elif [[ -n $ids ]]; then
+        local line
+        local -a tasks
+        for line in ${(f)"$(${words[1]} $opts __complete ids $ids 2>/dev/null)"}; do
+            tasks+=("${line%%$'\t'*}:${line#*$'\t'}")
+        done
+        _describe task tasks
+    elif ((${#args})); then
+        compadd -a args
+    elif [[ -n $files ]]; then
+        _files
+    fi
+}
+
+if [[ $funcstack[1] == _todo-cli ]]; then
+    _todo-cli "$@"
+else
+    compdef _todo-cli todo-cli
+fi
+{{end}}
+
+{{- define "fish"}}# fish completion for todo-cli. Load it with:
+#   todo-cli completion fish | source
+# or save it as ~/.config/fish/completions/todo-cli.fish.
+
+# __todo_cli_cmd prints the command on the line, skipping the global options.
+function __todo_cli_cmd
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    while set -q tokens[1]
+        switch $tokens[1]
+            case --list --storage
+                set -e tokens[1]
+            case '-*'
+            case '*'
+                echo $tokens[1]
+                return 0
+        end
+        set -e tokens[1]
+    end
+    return 1
+end
+
+function __todo_cli_using
+    set -l cmd (__todo_cli_cmd)
+    and contains -- $cmd $argv
+end
+
+# __todo_cli_opts prints the --list and --storage options on the line.
+function __todo_cli_opts
+    set -l tokens (commandline -opc)
+    for i in (seq (count $tokens))
+        switch $tokens[$i]
+            case --list --storage
+                set -q tokens[(math $i + 1)]
+                and printf '%s\n' $tokens[$i] $tokens[(math $i + 1)]
+            case '--list=*' '--storage=*'
+                echo $tokens[$i]
+        end
+    end
+end
+
+function __todo_cli_lists
+    set -l prog (commandline -opc)[1]
+    $prog (__todo_cli_opts) __complete lists 2>/dev/null
+end
+
+function __todo_cli_ids
+    set -l prog (commandline -opc)[1]
+    $prog (__todo_cli_opts) __complete ids $argv[1] 2>/dev/null
+end
+
+complete -c todo-cli -f
+complete -c todo-cli -n 'not __todo_cli_cmd' -l list -x -a '(__todo_cli_lists)' -d 'Use this list'
+complete -c todo-cli -n 'not __todo_cli_cmd' -l storage -r -F -d 'Use this tasks file'
+{{- range .Commands}}
+complete -c todo-cli -n 'not __todo_cli_cmd' -a {{.Name}} -d '{{.Summary}}'
+{{- end}}
+{{- range $c := .Commands}}
+{{- range .Flags}}
+complete -c todo-cli -n '__todo_cli_using {{$c.Name}}' -l {{.Name}}
+{{- if or (eq .Name "to") (eq .Name "list")}} -x -a '(__todo_cli_lists)'
+{{- else if or (eq .Name "storage") (eq .Name "output")}} -r -F
+{{- else if .Values}} -x -a '{{join .Values " "}}'
+{{- else if .Value}} -x
+{{- end}}
+{{- end}}
+{{- if .IDs}}
+complete -c todo-cli -n '__todo_cli_using {{.Name}}' -a '(__todo_cli_ids {{.IDs}})'
+{{- end}}
+{{- if .Words}}
+complete -c todo-cli -n '__todo_cli_using {{.Name}}' -a '{{join .Words " "}}'
+{{- end}}
+{{- if .Files}}
+complete -c todo-cli -n '__todo_cli_using {{.Name}}' -F
+{{- end}}
+{{- end}}
+{{end}}`))
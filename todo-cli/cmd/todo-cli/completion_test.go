@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompletionScripts(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TODO_CLI_PATH", filepath.Join(dir, "tasks.json"))
+
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		out, stderr, exit := runMenuHarness(t, []string{"completion", shell}, "")
+		if exit != 0 {
+			t.Fatalf("completion %s: exit %d (%q)", shell, exit, stderr)
+		}
+		requireContainsAll(t, out, []string{"todo-cli", "__complete ids", "due-before", "stats"})
+	}
+	out, _, _ := runMenuHarness(t, []string{"completion", "fish"}, "")
+	requireContainsAll(t, out, []string{
+		"complete -c todo-cli -n 'not __todo_cli_cmd' -a done -d 'Mark the selected tasks as done'",
+		"complete -c todo-cli -n '__todo_cli_using add' -l priority -x -a 'low medium high'",
+		"complete -c todo-cli -n '__todo_cli_using rm' -a '(__todo_cli_ids all)'",
+		"complete -c todo-cli -n '__todo_cli_using export' -l storage -r -F",
+	})
+	if _, _, exit := runMenuHarness(t, []string{"completion", "tcsh"}, ""); exit != 2 {
+		t.Fatalf("unknown shell: exit %d, want 2", exit)
+	}
+
+	if _, err := exec.LookPath("bash"); err != nil {
+		return
+	}
+	script, _, _ := runMenuHarness(t, []string{"completion", "bash"}, "")
+	if out, err := exec.Command("bash", "-n", "-c", script).CombinedOutput(); err != nil {
+		t.Fatalf("bash script does not parse: %v: %s", err, out)
+	}
+}
+
+func TestCompleteListsTaskIDs(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TODO_CLI_PATH", filepath.Join(dir, "tasks.json"))
+	for _, args := range [][]string{
+		{"add", "Buy milk"},
+		{"add", "Call  mum\ttonight"},
+		{"done", "1"},
+		{"--list", "work", "add", "Write report"},
+	} {
+		if _, stderr, exit := runMenuHarness(t, args, ""); exit != 0 {
+			t.Fatalf("%v: exit %d (%q)", args, exit, stderr)
+		}
+	}
+
+	complete := func(args ...string) string {
+		t.Helper()
+		out, stderr, exit := runMenuHarness(t, args, "")
+		if exit != 0 {
+			t.Fatalf("%v: exit %d (%q)", args, exit, stderr)
+		}
+		return out
+	}
+	if got := complete("__complete", "ids", "open"); got != "2\tCall mum tonight\n" {
+		t.Fatalf("open IDs = %q", got)
+	}
+	if got := complete("__complete", "ids", "done"); got != "1\tBuy milk\n" {
+		t.Fatalf("done IDs = %q", got)
+	}
+	if got := complete("--list", "work", "__complete", "ids", "all"); got != "1\tWrite report\n" {
+		t.Fatalf("work IDs = %q", got)
+	}
+	if got := complete("__complete", "lists"); strings.Join(strings.Fields(got), " ") != "default work" {
+		t.Fatalf("lists = %q", got)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/config"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/render"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+)
+
+// settings is the config file as read at the start of Run.
+var settings config.Config
+
+// isCommand reports whether name is a built-in command, which aliases
+// cannot shadow.
+func isCommand(name string) bool {
+	_, ok := lookup(name)
+	return ok
+}
+
+// loadSettings reads the config file into settings.
+func loadSettings() error {
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+	settings, err = config.Load(path)
+	return err
+}
+
+// basePath is the tasks file of the default list: --storage, then
+// $TODO_CLI_PATH, then the configured path, then ~/.todo-cli/tasks.json.
+func basePath(g globals) (string, error) {
+	if g.storage != "" {
+		return g.storage, nil
+	}
+	if os.Getenv(storage.EnvPath) == "" && settings.Path != "" {
+		return config.ExpandPath(settings.Path)
+	}
+	return storage.DefaultPath()
+}
+
+// defaultList is the list used without --list: $TODO_CLI_LIST, then the
+// configured list.
+func defaultList() string {
+	if os.Getenv(storage.EnvList) == "" && settings.List != "" {
+		return settings.List
+	}
+	return storage.DefaultList()
+}
+
+// defaultFormat is the --format default of the listing commands.
+func defaultFormat() string {
+	if settings.Format != "" {
+		return settings.Format
+	}
+	return render.FormatText
+}
+
+// oldestFirst reports the listing order: --reverse flips the configured
+// order, which is newest first unless set otherwise.
+func oldestFirst(reverse bool) bool {
+	return reverse != (settings.Sort == config.SortOldest)
+}
+
+// expandAlias replaces a leading alias with the arguments it stands for.
+// Built-in commands always win, and aliases do not expand recursively.
+func expandAlias(args []string) []string {
+	if len(args) == 0 || isCommand(args[0]) {
+		return args
+	}
+	words, ok := settings.Alias(args[0])
+	if !ok {
+		return args
+	}
+	return append(words, args[1:]...)
+}
+
+// runConfig handles `config get [key]`, `config set <key> <value...>`,
+// `config unset <key>` and `config path`.
+func runConfig(args []string) int {
+	usage := "usage: todo-cli config get [key] | set <key> <value...> | unset <key> | path"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	path, err := config.Path()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if args[0] == "path" && len(args) == 1 {
+		fmt.Fprintln(os.Stdout, path)
+		return 0
+	}
+	conf, err := config.Load(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch {
+	case args[0] == "get" && len(args) == 1:
+		for _, key := range conf.SetKeys() {
+			v, _ := conf.Get(key)
+			fmt.Fprintf(os.Stdout, "%s = %s\n", key, v)
+		}
+		return 0
+	case args[0] == "get" && len(args) == 2:
+		v, ok := conf.Get(args[1])
+		if !ok {
+			// Like git config: an unset key prints nothing and fails.
+			return 1
+		}
+		fmt.Fprintln(os.Stdout, v)
+		return 0
+	case args[0] == "set" && len(args) >= 3:
+		key := args[1]
+		if name, ok := strings.CutPrefix(key, "alias."); ok && isCommand(name) {
+			fmt.Fprintf(os.Stderr, "%q is a built-in command and cannot be an alias\n", name)
+			return 2
+		}
+		value := strings.Join(args[2:], " ")
+		if strings.HasPrefix(key, "alias.") && len(args) > 3 {
+			// The shell has already split the words: quote them again so
+			// the alias expands to the same arguments. A single value is
+			// kept as given, with its own quotes.
+			value = config.JoinArgs(args[2:])
+		}
+		if err := conf.Set(key, value); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	case args[0] == "unset" && len(args) == 2:
+		if err := conf.Unset(args[1]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	if err := config.Save(path, conf); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain keeps the tests away from the user's own config file.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "todo-cli-config")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("TODO_CLI_CONFIG", filepath.Join(dir, "config.json"))
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestConfigDefaultsAndAliases(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TODO_CLI_CONFIG", filepath.Join(dir, "config.json"))
+	t.Setenv("TODO_CLI_PATH", "")
+	t.Setenv("TODO_CLI_LIST", "")
+
+	run := func(wantExit int, args ...string) string {
+		t.Helper()
+		stdout, stderr, exit := runMenuHarness(t, args, "")
+		if exit != wantExit {
+			t.Fatalf("%v: expected exit %d, got %d (stdout=%q stderr=%q)", args, wantExit, exit, stdout, stderr)
+		}
+		return stdout + stderr
+	}
+
+	tasksPath := filepath.Join(dir, "data", "tasks.json")
+	run(0, "config", "set", "path", tasksPath)
+	run(0, "config", "set", "sort", "oldest")
+	run(0, "config", "set", "alias.t", "add", "--tag", "today")
+	run(0, "config", "set", "alias.today", "list --tag today --format jsonl")
+	run(0, "config", "set", "alias.tw", "add", "--tag", "two words")
+	run(2, "config", "set", "alias.add", "list")
+	run(2, "config", "set", "sort", "sideways")
+	run(2, "config", "set", "format", "template")
+	run(2, "config", "set", "colour", "blue")
+	run(2, "config", "set", "alias.x", `add "unterminated`)
+
+	out := run(0, "config", "get")
+	if out != "path = "+tasksPath+"\nsort = oldest\nalias.t = add --tag today\nalias.today = list --tag today --format jsonl\nalias.tw = add --tag \"two words\"\n" {
+		t.Fatalf("unexpected config get output %q", out)
+	}
+	if out := run(0, "config", "get", "sort"); out != "oldest\n" {
+		t.Fatalf("config get sort = %q", out)
+	}
+	run(1, "config", "get", "format")
+
+	run(0, "t", "Stand-up notes")
+	run(0, "add", "Lunch")
+	run(0, "tw", "Pairing")
+	if _, err := os.Stat(tasksPath); err != nil {
+		t.Fatalf("tasks should be stored at the configured path: %v", err)
+	}
+	out = run(0, "list")
+	requireContainsInOrder(t, out, []string{"#1 Stand-up notes (tags today)", "#2 Lunch", "#3 Pairing (tags two words)"})
+	out = run(0, "list", "--reverse")
+	requireContainsInOrder(t, out, []string{"#2 Lunch", "#1 Stand-up notes"})
+	out = run(0, "today")
+	requireContainsAll(t, out, []string{`"text":"Stand-up notes"`})
+
+	run(0, "config", "set", "format", "csv")
+	requireContainsAll(t, run(0, "list"), []string{"1,false,Stand-up notes"})
+	requireContainsAll(t, run(0, "list", "--format", "text"), []string{"[ ] #1 Stand-up notes"})
+
+	// The environment still wins over the config file.
+	t.Setenv("TODO_CLI_PATH", filepath.Join(dir, "other.json"))
+	if out := run(0, "list", "--format", "text"); out != "" {
+		t.Fatalf("expected the list at $TODO_CLI_PATH to be empty, got %q", out)
+	}
+	t.Setenv("TODO_CLI_PATH", "")
+
+	run(0, "config", "set", "list", "work")
+	run(0, "add", "Deploy")
+	requireContainsAll(t, run(0, "lists"), []string{"* work (1 open, 1 task)"})
+	run(0, "config", "unset", "list")
+	run(0, "config", "unset", "alias.t")
+	run(2, "config", "unset", "alias.t")
+	run(2, "t", "gone")
+}
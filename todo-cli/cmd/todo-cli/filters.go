@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// filterFlags registers the task filter flags shared by list, done, rm and
+// undone.
+type filterFlags struct {
+	tags      stringList
+	dueBefore string
+	priority  string
+	overdue   bool
+	done      bool
+	open      bool
+}
+
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.Var(&f.tags, "tag", "only tasks carrying this tag (repeatable)")
+	fs.StringVar(&f.dueBefore, "due-before", "", "only tasks due before this date")
+	fs.StringVar(&f.priority, "priority", "", "only tasks with this priority")
+	fs.BoolVar(&f.overdue, "overdue", false, "only open tasks past their due date")
+	fs.BoolVar(&f.done, "done", false, "only completed tasks")
+	fs.BoolVar(&f.open, "open", false, "only open tasks")
+}
+
+// set reports whether any filter flag was given.
+func (f *filterFlags) set() bool {
+	return len(f.tags) > 0 || f.dueBefore != "" || f.priority != "" || f.overdue || f.done || f.open
+}
+
+// build turns the parsed flags into a tasks.Filter, resolving relative dates
+// against now.
+func (f *filterFlags) build(now time.Time) (tasks.Filter, error) {
+	filter := tasks.Filter{Tags: tasks.NormalizeTags(f.tags), Overdue: f.overdue}
+	if f.dueBefore != "" {
+		d, err := tasks.ParseDue(f.dueBefore, now)
+		if err != nil {
+			return tasks.Filter{}, err
+		}
+		filter.DueBefore = &d
+	}
+	if f.priority != "" {
+		p, err := tasks.ParsePriority(f.priority)
+		if err != nil {
+			return tasks.Filter{}, err
+		}
+		filter.Priority = &p
+	}
+	if f.done != f.open {
+		done := f.done
+		filter.Done = &done
+	}
+	return filter, nil
+}
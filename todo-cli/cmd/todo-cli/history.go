@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/journal"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// mutateFunc applies one change to the list and returns the new list with a
+// short summary for the history.
+type mutateFunc func(list []tasks.Task) ([]tasks.Task, string, error)
+
+// mutate runs fn on the stored list under the exclusive lock, saves the result
+// and records the change in the undo journal next to the tasks file. A change
+// that leaves the list as it was is neither saved nor recorded, and one that
+// cannot be recorded is rolled back.
+func mutate(g globals, jsonPath string, store storage.Store, op string, fn mutateFunc) error {
+	lock, err := storage.AcquireLock(jsonPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	before, err := store.Load()
+	if err != nil {
+		return err
+	}
+	after, summary, err := fn(tasks.CloneList(before))
+	if err != nil {
+		return err
+	}
+	if err := tasks.CheckGraph(after); err != nil {
+		return err
+	}
+	if reflect.DeepEqual(before, after) {
+		// Nothing changed (e.g. done on a finished task): keep the
+		// journal free of no-op entries.
+		return nil
+	}
+	if err := store.Save(after); err != nil {
+		return err
+	}
+	if err := record(g, jsonPath, op, summary, before, after); err != nil {
+		// A change missing from the journal could not be undone.
+		return rollback(store, before, err)
+	}
+	return nil
+}
+
+// rollback restores list after the journal could not be written, so the
+// list and its history stay in step. It returns err, along with any error
+// from the restore.
+func rollback(store storage.Store, list []tasks.Task, err error) error {
+	if rerr := store.Save(list); rerr != nil {
+		return errors.Join(err, fmt.Errorf("restoring the list: %w", rerr))
+	}
+	return err
+}
+
+// record appends a change to the undo journal of the list at jsonPath and,
+// when the storage directory is synced, commits it. The caller must hold
+// that list's exclusive lock.
+func record(g globals, jsonPath, op, summary string, before, after []tasks.Task) error {
+	jpath := journal.PathFor(jsonPath)
+	j, err := journal.Load(jpath)
+	if err != nil {
+		return err
+	}
+	j.Record(op, summary, before, after, now())
+	if err := j.Save(jpath); err != nil {
+		return err
+	}
+	commitChange(g, jsonPath, summary)
+	return nil
+}
+
+// failure reports err on stderr and maps it to an exit code: missing tasks,
+// dependency cycles and refused completions are usage errors, anything else
+// is a runtime failure.
+func failure(err error) int {
+	var missing *tasks.MissingError
+	var openChildren *tasks.OpenChildrenError
+	switch {
+	case err == tasks.ErrNotFound, errors.As(err, &missing):
+		fmt.Fprintln(os.Stderr, "no such task")
+		return 2
+	case errors.Is(err, tasks.ErrNotFound), errors.Is(err, tasks.ErrCycle):
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	case errors.As(err, &openChildren):
+		fmt.Fprintf(os.Stderr, "%v (use --force to complete it anyway)\n", err)
+		return 2
+	}
+	fmt.Fprintln(os.Stderr, err)
+	return 1
+}
+
+// runUndoRedo handles `undo [n]` and `redo [n]`.
+func runUndoRedo(g globals, jsonPath string, store storage.Store, cmd string, args []string) int {
+	n := 1
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "usage: todo-cli %s [n]\n", cmd)
+		return 2
+	}
+	if len(args) == 1 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v < 1 {
+			fmt.Fprintln(os.Stderr, "invalid count")
+			return 2
+		}
+		n = v
+	}
+
+	lock, err := storage.AcquireLock(jsonPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer lock.Release()
+
+	current, err := store.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	jpath := journal.PathFor(jsonPath)
+	j, err := journal.Load(jpath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	step, verb := j.Undo, "undid"
+	if cmd == "redo" {
+		step, verb = j.Redo, "redid"
+	}
+	list, entries, err := step(n, current)
+	if err != nil {
+		if errors.Is(err, journal.ErrNothingToUndo) || errors.Is(err, journal.ErrNothingToRedo) {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := store.Save(list); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := j.Save(jpath); err != nil {
+		fmt.Fprintln(os.Stderr, rollback(store, current, err))
+		return 1
+	}
+	for _, e := range entries {
+		fmt.Fprintf(os.Stdout, "%s #%d: %s\n", verb, e.Seq, e.Summary)
+	}
+	if len(entries) == 1 {
+		commitChange(g, jsonPath, fmt.Sprintf("%s #%d: %s", verb, entries[0].Seq, entries[0].Summary))
+	} else {
+		commitChange(g, jsonPath, fmt.Sprintf("%s %d changes", verb, len(entries)))
+	}
+	return 0
+}
+
+// historyCommand prints the journal newest first, marking undone entries.
+func historyCommand(fs *flag.FlagSet) runFunc {
+	limit := fs.Int("limit", 20, "show at most this many entries (0 for all)")
+	return func(e *env, args []string) int {
+		if len(args) != 0 {
+			fmt.Fprintln(os.Stderr, "invalid flags")
+			return 2
+		}
+
+		lock, err := storage.AcquireSharedLock(e.jsonPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		j, err := journal.Load(journal.PathFor(e.jsonPath))
+		lock.Release()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(j.Entries) == 0 {
+			fmt.Fprintln(os.Stdout, "No history.")
+			return 0
+		}
+		shown := 0
+		for i := len(j.Entries) - 1; i >= 0; i-- {
+			if *limit > 0 && shown == *limit {
+				break
+			}
+			e := j.Entries[i]
+			state := ""
+			switch {
+			case i >= j.Cursor:
+				state = " (undone)"
+			case i < j.Floor:
+				state = " (before sync)"
+			}
+			fmt.Fprintf(os.Stdout, "#%d %s %-6s %s%s\n", e.Seq, e.At.Local().Format("2006-01-02 15:04"), e.Op, e.Summary, state)
+			shown++
+		}
+		return 0
+	}
+}
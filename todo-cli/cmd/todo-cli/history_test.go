@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUndoRedoAndHistory(t *testing.T) {
+	t.Setenv("TODO_CLI_PATH", filepath.Join(t.TempDir(), "tasks.json"))
+
+	run := func(args ...string) string {
+		t.Helper()
+		stdout, stderr, exit := runMenuHarness(t, args, "")
+		if exit != 0 {
+			t.Fatalf("%v: exit %d, stderr=%q", args, exit, stderr)
+		}
+		return stdout
+	}
+
+	run("add", "Alpha")
+	run("add", "Beta")
+	run("done", "1")
+	run("clear")
+
+	if out := run("list"); strings.TrimSpace(out) != "" {
+		t.Fatalf("expected empty list after clear, got %q", out)
+	}
+
+	out := run("undo")
+	requireContainsAll(t, out, []string{"undid #4: cleared 2 tasks"})
+	requireContainsAll(t, run("list"), []string{"[ ] #2 Beta", "[x] #1 Alpha"})
+
+	out = run("undo", "2")
+	requireContainsAll(t, out, []string{"undid #3: done #1", "undid #2: added #2 Beta"})
+	list := run("list")
+	requireContainsAll(t, list, []string{"[ ] #1 Alpha"})
+	if strings.Contains(list, "Beta") {
+		t.Fatalf("expected Beta to be undone, got %q", list)
+	}
+
+	run("redo")
+	requireContainsAll(t, run("list"), []string{"[ ] #2 Beta"})
+
+	history := run("history")
+	requireContainsInOrder(t, history, []string{
+		"#4", "clear", "(undone)",
+		"#3", "done", "(undone)",
+		"#2", "add", "added #2 Beta",
+		"#1", "add", "added #1 Alpha",
+	})
+	if strings.Contains(strings.Split(history, "\n")[2], "(undone)") {
+		t.Fatalf("redone entry should not be marked undone: %q", history)
+	}
+
+	run("undo", "5")
+	if _, stderr, exit := runMenuHarness(t, []string{"undo"}, ""); exit != 2 || !strings.Contains(stderr, "nothing to undo") {
+		t.Fatalf("expected nothing to undo, got exit %d stderr %q", exit, stderr)
+	}
+}
+
+func TestChangeIsRolledBackWhenJournalFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	t.Setenv("TODO_CLI_PATH", path)
+
+	if _, stderr, exit := runMenuHarness(t, []string{"add", "Alpha"}, ""); exit != 0 {
+		t.Fatalf("add: exit %d, stderr=%q", exit, stderr)
+	}
+	// A directory in place of the journal makes recording fail.
+	jpath := path + ".journal"
+	if err := os.Remove(jpath); err != nil {
+		t.Fatalf("remove journal: %v", err)
+	}
+	if err := os.Mkdir(jpath, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	if _, _, exit := runMenuHarness(t, []string{"add", "Beta"}, ""); exit != 1 {
+		t.Fatalf("expected add to fail without a journal, got exit %d", exit)
+	}
+	stdout, _, _ := runMenuHarness(t, []string{"list"}, "")
+	requireContainsAll(t, stdout, []string{"#1 Alpha"})
+	if strings.Contains(stdout, "Beta") {
+		t.Fatalf("expected the unrecorded add to be rolled back, got %q", stdout)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/interchange"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/render"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// exportCommand is `export [--format f] [--output file] [filters]`.
+func exportCommand(fs *flag.FlagSet) runFunc {
+	formatName := fs.String("format", "", "todotxt, markdown or json (default: from --output extension, else todotxt)")
+	output := fs.String("output", "", "write to this file instead of stdout")
+	var ff filterFlags
+	ff.register(fs)
+	return func(e *env, args []string) int {
+		if len(args) != 0 {
+			fmt.Fprintln(os.Stderr, "invalid flags")
+			return 2
+		}
+		format, err := interchangeFormat(*formatName, *output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		current := now()
+		filter, err := ff.build(current)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+
+		lock, err := storage.AcquireSharedLock(e.jsonPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		list, err := e.store.Load()
+		lock.Release()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		list = tasks.SortOldestFirst(filter.Apply(list, current))
+
+		var w io.Writer = os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := interchange.Encode(w, format, list); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if *output != "" {
+			fmt.Fprintf(os.Stdout, "exported %s to %s\n", countTasks(len(list)), *output)
+		}
+		return 0
+	}
+}
+
+// importCommand is `import [--format f] [--dry-run] [--allow-duplicates] <file|->`.
+func importCommand(fs *flag.FlagSet) runFunc {
+	formatName := fs.String("format", "", "todotxt, markdown or json (default: from file extension)")
+	dryRun := fs.Bool("dry-run", false, "show what would be imported without saving")
+	allowDup := fs.Bool("allow-duplicates", false, "import tasks whose text matches an existing task")
+	return func(e *env, files []string) int {
+		if len(files) != 1 {
+			fmt.Fprintln(os.Stderr, "usage: todo-cli import [--format todotxt|markdown|json] [--dry-run] [--allow-duplicates] <file|->")
+			return 2
+		}
+		src := files[0]
+		if *formatName == "" && src == "-" {
+			fmt.Fprintln(os.Stderr, "import from stdin requires --format")
+			return 2
+		}
+		inFormat, err := interchangeFormat(*formatName, src)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+
+		var r io.Reader = os.Stdin
+		if src != "-" {
+			f, err := os.Open(src)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			defer f.Close()
+			r = f
+		}
+		incoming, err := interchange.Decode(r, inFormat)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", src, err)
+			return 1
+		}
+
+		var plan interchange.Plan
+		apply := func(list []tasks.Task) ([]tasks.Task, string, error) {
+			plan = interchange.Merge(list, incoming, *allowDup, now())
+			return append(list, plan.New...), fmt.Sprintf("imported %s from %s", countTasks(len(plan.New)), src), nil
+		}
+		if *dryRun {
+			lock, err := storage.AcquireSharedLock(e.jsonPath)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			list, err := e.store.Load()
+			lock.Release()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			_, _, _ = apply(list)
+		} else if err := mutate(e.globals, e.jsonPath, e.store, "import", apply); err != nil {
+			return failure(err)
+		}
+
+		current := now()
+		verb := "imported"
+		if *dryRun {
+			verb = "would import"
+			for _, t := range plan.New {
+				fmt.Fprintln(os.Stdout, "+ "+render.Line(t, current))
+			}
+			for _, t := range plan.Duplicates {
+				fmt.Fprintf(os.Stdout, "= duplicate: %s\n", t.Text)
+			}
+		}
+		fmt.Fprintf(os.Stdout, "%s %s", verb, countTasks(len(plan.New)))
+		if n := len(plan.Duplicates); n > 0 {
+			fmt.Fprintf(os.Stdout, ", skipped %d duplicate", n)
+			if n > 1 {
+				fmt.Fprint(os.Stdout, "s")
+			}
+		}
+		fmt.Fprintln(os.Stdout)
+		return 0
+	}
+}
+
+// interchangeFormat resolves an explicit --format or infers one from path.
+func interchangeFormat(name, path string) (string, error) {
+	if name != "" {
+		return interchange.ParseFormat(name)
+	}
+	if path == "" {
+		return interchange.FormatTodoTxt, nil
+	}
+	if f, ok := interchange.FormatFromPath(path); ok {
+		return f, nil
+	}
+	return "", fmt.Errorf("cannot tell the format of %s; pass --format", path)
+}
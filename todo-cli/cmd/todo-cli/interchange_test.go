@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportExportRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TODO_CLI_PATH", filepath.Join(dir, "tasks.json"))
+
+	run := func(wantExit int, args ...string) (string, string) {
+		t.Helper()
+		stdout, stderr, exit := runMenuHarness(t, args, "")
+		if exit != wantExit {
+			t.Fatalf("%v: expected exit %d, got %d (stdout=%q stderr=%q)", args, wantExit, exit, stdout, stderr)
+		}
+		return stdout, stderr
+	}
+
+	run(0, "add", "Buy milk")
+	src := filepath.Join(dir, "todo.txt")
+	content := "(A) Call mom +family due:2030-01-02\nbuy milk\nx 2024-03-02 Old chore\n"
+	if err := os.WriteFile(src, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, _ := run(0, "import", "--dry-run", src)
+	requireContainsAll(t, out, []string{"+ [ ] #2 Call mom", "+ [x] #3 Old chore", "= duplicate: buy milk", "would import 2 tasks, skipped 1 duplicate"})
+	if list, _ := run(0, "list"); strings.Contains(list, "Call mom") {
+		t.Fatalf("dry run must not save, got %q", list)
+	}
+
+	out, _ = run(0, "import", src)
+	requireContainsAll(t, out, []string{"imported 2 tasks, skipped 1 duplicate"})
+	list, _ := run(0, "list", "--tag", "family")
+	requireContainsAll(t, list, []string{"[ ] #2 Call mom (due 2030-01-02, priority high, tags family)"})
+
+	md := filepath.Join(dir, "out.md")
+	out, _ = run(0, "export", "--output", md, "--open")
+	requireContainsAll(t, out, []string{"exported 2 tasks to " + md})
+	b, err := os.ReadFile(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireContainsInOrder(t, string(b), []string{"- [ ] Buy milk\n", "- [ ] Call mom #family due:2030-01-02 !high\n"})
+
+	out, _ = run(0, "export", "--format", "todotxt", "--done")
+	requireContainsInOrder(t, out, []string{"x 2024-03-02 ", "Old chore"})
+
+	run(0, "undo")
+	if list, _ := run(0, "list"); strings.Contains(list, "Call mom") {
+		t.Fatalf("undo should revert the import, got %q", list)
+	}
+
+	_, stderr := run(2, "import", filepath.Join(dir, "tasks.csv"))
+	requireContainsAll(t, stderr, []string{"pass --format"})
+	run(2, "import", "-")
+	run(1, "import", filepath.Join(dir, "missing.json"))
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/render"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// openList resolves the path of the named list and opens it with the
+// backend selected by $TODO_CLI_BACKEND. The path is also what callers lock.
+func openList(g globals, name string) (string, storage.Store, error) {
+	base, err := basePath(g)
+	if err != nil {
+		return "", nil, err
+	}
+	jsonPath, err := storage.ListPath(base, name)
+	if err != nil {
+		return "", nil, err
+	}
+	store, err := storage.Open(storage.DefaultBackend(), jsonPath)
+	if err != nil {
+		return "", nil, err
+	}
+	return jsonPath, store, nil
+}
+
+// runLists handles `lists`: every list with its open and total task counts.
+func runLists(e *env, args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: todo-cli lists")
+		return 2
+	}
+	base, err := basePath(e.globals)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	names, err := storage.ListNames(base)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if !contains(names, e.list) {
+		names = append(names, e.list)
+	}
+	for _, name := range names {
+		jsonPath, store, err := openList(e.globals, name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		lock, err := storage.AcquireSharedLock(jsonPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		list, err := store.Load()
+		lock.Release()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			return 1
+		}
+		open := 0
+		for _, t := range list {
+			if !t.Done {
+				open++
+			}
+		}
+		marker := " "
+		if name == e.list {
+			marker = "*"
+		}
+		fmt.Fprintf(os.Stdout, "%s %s (%d open, %s)\n", marker, name, open, countTasks(len(list)))
+	}
+	return 0
+}
+
+// moveCommand is `mv <sel...> --to <list>`. Moved tasks get fresh IDs in
+// the target list. Both lists are locked, in path order so two opposite
+// moves cannot deadlock, and each records the move in its own journal.
+func moveCommand(fs *flag.FlagSet) runFunc {
+	to := fs.String("to", "", "list to move the tasks to")
+	var ff filterFlags
+	ff.register(fs)
+	format := resultFormatFlag(fs)
+	return func(e *env, words []string) int {
+		if err := render.CheckFormat(*format, resultFormats); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if *to == "" {
+			fmt.Fprintln(os.Stderr, "usage: todo-cli mv <id|range...> --to <list>")
+			return 2
+		}
+		sel, err := tasks.ParseSelection(words)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid ID")
+			return 2
+		}
+		if ff.set() {
+			filter, err := ff.build(now())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+			sel.Filter = &filter
+		}
+		if sel.Empty() {
+			fmt.Fprintln(os.Stderr, "mv requires an ID, range or filter")
+			return 2
+		}
+
+		srcPath, src, err := openList(e.globals, e.list)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		dstPath, dst, err := openList(e.globals, *to)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if srcPath == dstPath {
+			fmt.Fprintf(os.Stderr, "tasks are already in list %s\n", *to)
+			return 2
+		}
+
+		var moved, added []int
+		err = withLocks([]string{srcPath, dstPath}, func() error {
+			srcBefore, err := src.Load()
+			if err != nil {
+				return err
+			}
+			dstBefore, err := dst.Load()
+			if err != nil {
+				return err
+			}
+			moved, err = sel.Resolve(srcBefore, now())
+			if err != nil {
+				return err
+			}
+			dstAfter, ids := tasks.Adopt(tasks.CloneList(dstBefore), pick(srcBefore, moved))
+			added = ids
+			srcAfter := tasks.RemoveAll(tasks.CloneList(srcBefore), moved)
+
+			// Write the target first: if saving the source then fails the
+			// tasks exist twice rather than not at all.
+			if err := dst.Save(dstAfter); err != nil {
+				return err
+			}
+			if err := src.Save(srcAfter); err != nil {
+				return err
+			}
+			summary := fmt.Sprintf("moved %s from %s to %s as %s", tasks.FormatIDs(moved), e.list, *to, tasks.FormatIDs(added))
+			if err := record(e.globals, dstPath, "mv", summary, dstBefore, dstAfter); err != nil {
+				return err
+			}
+			return record(e.globals, srcPath, "mv", summary, srcBefore, srcAfter)
+		})
+		if err != nil {
+			return selectionFailure(err)
+		}
+
+		if *format == render.FormatJSON {
+			return writeResult(render.Result{Op: "mv", IDs: added})
+		}
+		fmt.Fprintf(os.Stdout, "moved %s to %s as %s\n", tasks.FormatIDs(moved), *to, tasks.FormatIDs(added))
+		return 0
+	}
+}
+
+// withLocks holds the exclusive lock guarding every path while fn runs.
+// Locks are taken in lock-file order, once each: lists in the same directory
+// share a lock file.
+func withLocks(paths []string, fn func() error) error {
+	byLock := make(map[string]string, len(paths))
+	for _, p := range paths {
+		byLock[storage.LockPath(p)] = p
+	}
+	order := make([]string, 0, len(byLock))
+	for l := range byLock {
+		order = append(order, l)
+	}
+	sort.Strings(order)
+	for _, l := range order {
+		lock, err := storage.AcquireLock(byLock[l])
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+	}
+	return fn()
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNamedListsAndMove(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TODO_CLI_PATH", filepath.Join(dir, "tasks.json"))
+
+	run := func(wantExit int, args ...string) (string, string) {
+		t.Helper()
+		stdout, stderr, exit := runMenuHarness(t, args, "")
+		if exit != wantExit {
+			t.Fatalf("%v: expected exit %d, got %d (stdout=%q stderr=%q)", args, wantExit, exit, stdout, stderr)
+		}
+		return stdout, stderr
+	}
+
+	run(0, "add", "Water plants")
+	run(0, "add", "Book dentist")
+	run(0, "--list", "work", "add", "Write report")
+	out, _ := run(0, "--list=work", "add", "Review PR")
+	requireContainsAll(t, out, []string{"added #2"})
+	if _, err := os.Stat(filepath.Join(dir, "lists", "work.json")); err != nil {
+		t.Fatalf("expected work list file: %v", err)
+	}
+
+	out, _ = run(0, "list", "--reverse")
+	requireContainsInOrder(t, out, []string{"#1 Water plants", "#2 Book dentist"})
+	if strings.Contains(out, "Write report") {
+		t.Fatalf("default list must not show work tasks, got %q", out)
+	}
+
+	run(0, "done", "2")
+	out, _ = run(0, "mv", "1-2", "--to", "work")
+	requireContainsAll(t, out, []string{"moved #1, #2 to work as #3, #4"})
+	out, _ = run(0, "--list", "work", "list", "--reverse")
+	requireContainsInOrder(t, out, []string{"[ ] #1 Write report", "[ ] #2 Review PR", "[ ] #3 Water plants", "[x] #4 Book dentist"})
+	if out, _ := run(0, "list"); out != "" {
+		t.Fatalf("expected default list to be empty, got %q", out)
+	}
+
+	t.Setenv("TODO_CLI_LIST", "work")
+	out, _ = run(0, "lists")
+	requireContainsInOrder(t, out, []string{"  default (0 open, 0 tasks)", "* work (3 open, 4 tasks)"})
+
+	run(0, "--list", "work", "undo")
+	out, _ = run(0, "list")
+	if strings.Contains(out, "Water plants") {
+		t.Fatalf("undo in work should drop the moved tasks, got %q", out)
+	}
+	run(0, "--list", "default", "undo")
+	out, _ = run(0, "--list", "default", "list")
+	requireContainsAll(t, out, []string{"#1 Water plants", "#2 Book dentist"})
+
+	_, stderr := run(2, "mv", "1", "--to", "work")
+	requireContainsAll(t, stderr, []string{"already in list work"})
+	run(2, "mv", "9", "--to", "home")
+	run(2, "mv", "1")
+	_, stderr = run(2, "--list", "../etc", "list")
+	requireContainsAll(t, stderr, []string{"invalid list name"})
+	run(2, "--list")
+}
@@ -9,8 +9,10 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/render"
 	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
 	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
 	"github.com/pekomon/go-sandbox/todo-cli/internal/ui"
@@ -26,240 +28,339 @@ type Command struct {
 }
 
 // ParseCommand inspects the provided CLI arguments (including program name) and
-// returns the parsed command. It keeps support for the legacy command parsing
-// API used in older tests.
+// returns the parsed command. It parses with the command registry, like Run,
+// and keeps the shapes of the legacy parsing API used in older tests: add
+// joins its text into one argument, and --storage is handed back in Args.
 func ParseCommand(args []string) (Command, error) {
 	if len(args) < 2 {
 		return Command{}, ErrUnknownCommand
 	}
-	cmd := Command{Name: args[1]}
-	rest := args[2:]
-	var storagePath string
-	filtered := make([]string, 0, len(rest))
-	for i := 0; i < len(rest); i++ {
-		s := rest[i]
-		if s == "--storage" {
-			if i+1 >= len(rest) {
-				return Command{}, ErrUnknownCommand
-			}
-			storagePath = rest[i+1]
-			i++
-			continue
-		}
-		filtered = append(filtered, s)
+	g, rest, err := splitGlobals(args[1:])
+	if err != nil || len(rest) == 0 {
+		return Command{}, ErrUnknownCommand
 	}
+	inv, err := parseCommand(rest, g)
+	if err != nil || inv.cmd.Hidden {
+		return Command{}, ErrUnknownCommand
+	}
+	cmd := Command{Name: inv.cmd.Name}
+	storagePath := inv.globals.storage
 	switch cmd.Name {
 	case "add":
-		if len(filtered) == 0 {
+		if len(inv.args) == 0 {
 			return Command{}, ErrUnknownCommand
 		}
-		cmd.Args = []string{strings.Join(filtered, " ")}
+		cmd.Args = []string{strings.Join(inv.args, " ")}
 	case "list":
 		if storagePath != "" {
 			cmd.Args = append(cmd.Args, storagePath)
 		}
-		cmd.Args = append(cmd.Args, filtered...)
+		cmd.Args = append(cmd.Args, inv.args...)
 	case "done", "rm":
-		if len(filtered) != 1 {
+		if len(inv.args) != 1 {
 			return Command{}, ErrUnknownCommand
 		}
-		cmd.Args = filtered
+		cmd.Args = inv.args
 		if storagePath != "" {
 			cmd.Args = append(cmd.Args, storagePath)
 		}
 	case "clear":
-		if len(filtered) != 0 {
+		if len(inv.args) != 0 {
 			return Command{}, ErrUnknownCommand
 		}
 		if storagePath != "" {
 			cmd.Args = []string{storagePath}
 		}
 	default:
-		return Command{}, ErrUnknownCommand
+		cmd.Args = inv.args
 	}
 	return cmd, nil
 }
 
 // Run is separated for testability. Returns exit code.
 func Run(args []string) int {
+	g, args, err := splitGlobals(args)
+	if errors.Is(err, flag.ErrHelp) {
+		writeUsage(os.Stdout)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if c, ok := lookup(firstArg(args)); !ok || !c.Early {
+		if err := loadSettings(); err != nil {
+			fmt.Fprintln(os.Stderr, "error reading config:", err)
+			return 1
+		}
+		args = expandAlias(args)
+	}
 	if len(args) == 0 {
-		if os.Getenv("TODO_CLI_MENU") == "1" {
-			return runMenu()
+		if os.Getenv("TODO_CLI_MENU") == "1" || settings.Menu {
+			return runMenu(g)
 		}
-		fmt.Fprintln(os.Stderr, "usage: todo-cli <add|list|done|rm|clear> [args]")
+		writeUsage(os.Stderr)
 		return 2
 	}
 
-	if args[0] == "menu" {
-		return runMenu()
-	}
-
-	// Resolve storage path
-	jsonPath, err := storage.DefaultPath()
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "error resolving storage path:", err)
-		return 1
+	inv, err := parseCommand(args, g)
+	switch {
+	case errors.Is(err, ErrUnknownCommand):
+		fmt.Fprintln(os.Stderr, "unknown command")
+		return 2
+	case errors.Is(err, flag.ErrHelp):
+		writeHelp(os.Stdout, inv.cmd)
+		return 0
+	case err != nil:
+		fmt.Fprintln(os.Stderr, err)
+		return 2
 	}
-
-	// Commands
-	switch args[0] {
-	case "add":
-		text := strings.TrimSpace(strings.Join(args[1:], " "))
-		if text == "" {
-			fmt.Fprintln(os.Stderr, "add requires task text")
+	e := &env{globals: inv.globals}
+	if !inv.cmd.Early {
+		if e.list, err = resolveList(inv.globals); err != nil {
+			fmt.Fprintln(os.Stderr, err)
 			return 2
 		}
-		lock, lerr := storage.AcquireLock(jsonPath)
-		if lerr != nil {
-			fmt.Fprintln(os.Stderr, lerr)
+	}
+	if inv.cmd.Store {
+		if e.jsonPath, e.store, err = openList(inv.globals, e.list); err != nil {
+			fmt.Fprintln(os.Stderr, "error opening storage:", err)
 			return 1
 		}
-		defer lock.Release()
+	}
+	return inv.run(e, inv.args)
+}
 
-		list, err := storage.LoadTasks(jsonPath)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			return 1
-		}
-		list = tasks.Add(list, text)
-		if err := storage.SaveTasks(jsonPath, list); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			return 1
-		}
-		fmt.Fprintf(os.Stdout, "added #%d\n", list[len(list)-1].ID)
-		return 0
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
 
-	case "list":
-		fs := flag.NewFlagSet("list", flag.ContinueOnError)
-		reverse := fs.Bool("reverse", false, "reverse order (oldest-first)")
-		// prevent flag package from writing to stderr on parse error
-		fs.SetOutput(new(nopWriter))
-		if err := fs.Parse(args[1:]); err != nil {
-			fmt.Fprintln(os.Stderr, "invalid flags")
-			return 2
-		}
-		list, err := storage.LoadTasks(jsonPath)
-		if err != nil {
+// addCommand is `add <text...>`.
+func addCommand(fs *flag.FlagSet) runFunc {
+	due := fs.String("due", "", "due date (2006-01-02, today, tomorrow, +3d, ...)")
+	remind := fs.String("remind", "", "reminder time, absolute or before the due date (-30min, -1d)")
+	priority := fs.String("priority", "", "priority: low, medium or high")
+	var tags stringList
+	fs.Var(&tags, "tag", "tag to attach (repeatable, comma-separated)")
+	recur := fs.String("recur", "", "repeat rule: daily, weekly[:mon,...], monthly, every N days|weeks|months")
+	parent := fs.Int("parent", 0, "make the task a subtask of this ID")
+	blockedBy := fs.String("blocked-by", "", "IDs of tasks that must be done first")
+	format := resultFormatFlag(fs)
+	return func(e *env, words []string) int {
+		if err := render.CheckFormat(*format, resultFormats); err != nil {
 			fmt.Fprintln(os.Stderr, err)
-			return 1
-		}
-		if *reverse {
-			list = tasks.SortOldestFirst(list)
-		} else {
-			list = tasks.SortNewestFirst(list)
-		}
-		for _, t := range list {
-			state := " "
-			if t.Done {
-				state = "x"
-			}
-			fmt.Fprintf(os.Stdout, "[%s] #%d %s\n", state, t.ID, t.Text)
-		}
-		return 0
-
-	case "done":
-		if len(args) < 2 {
-			fmt.Fprintln(os.Stderr, "done requires an ID")
 			return 2
 		}
-		id, convErr := strconv.Atoi(args[1])
-		if convErr != nil {
-			fmt.Fprintln(os.Stderr, "invalid ID")
+		text := strings.TrimSpace(strings.Join(words, " "))
+		if text == "" {
+			fmt.Fprintln(os.Stderr, "add requires task text")
 			return 2
 		}
-		lock, lerr := storage.AcquireLock(jsonPath)
-		if lerr != nil {
-			fmt.Fprintln(os.Stderr, lerr)
-			return 1
+		created := now()
+		task := tasks.Task{Text: text, Tags: tasks.NormalizeTags(tags), CreatedAt: &created}
+		if *due != "" {
+			d, err := tasks.ParseDue(*due, created)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+			task.Due = &d
 		}
-		defer lock.Release()
-
-		list, err := storage.LoadTasks(jsonPath)
-		if err != nil {
+		var err error
+		if task.Priority, err = tasks.ParsePriority(*priority); err != nil {
 			fmt.Fprintln(os.Stderr, err)
-			return 1
+			return 2
 		}
-		list, err = tasks.MarkDone(list, id)
-		if err != nil {
-			if errors.Is(err, tasks.ErrNotFound) {
-				fmt.Fprintln(os.Stderr, "no such task")
+		if *recur != "" {
+			rule, err := tasks.ParseRecurrence(*recur)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
 				return 2
 			}
-			fmt.Fprintln(os.Stderr, err)
-			return 1
-		}
-		if err := storage.SaveTasks(jsonPath, list); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			return 1
+			task.Recur = &rule
+			if task.Due == nil {
+				// A recurring task needs a due date to advance from.
+				first := rule.First(tasks.StartOfDay(created))
+				task.Due = &first
+			}
 		}
-		fmt.Fprintf(os.Stdout, "done #%d\n", id)
-		return 0
-
-	case "rm":
-		if len(args) < 2 {
-			fmt.Fprintln(os.Stderr, "rm requires an ID")
-			return 2
+		if *remind != "" {
+			r, err := tasks.ParseRemind(*remind, task.Due, created)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+			task.RemindAt = &r
 		}
-		id, convErr := strconv.Atoi(args[1])
-		if convErr != nil {
-			fmt.Fprintln(os.Stderr, "invalid ID")
+		if *parent < 0 {
+			fmt.Fprintln(os.Stderr, "invalid parent ID")
 			return 2
 		}
-		lock, lerr := storage.AcquireLock(jsonPath)
-		if lerr != nil {
-			fmt.Fprintln(os.Stderr, lerr)
-			return 1
-		}
-		defer lock.Release()
-
-		list, err := storage.LoadTasks(jsonPath)
-		if err != nil {
+		task.Parent = *parent
+		if task.BlockedBy, err = parseIDs(*blockedBy); err != nil {
 			fmt.Fprintln(os.Stderr, err)
-			return 1
+			return 2
 		}
-		list, err = tasks.Remove(list, id)
+		var added tasks.Task
+		err = mutate(e.globals, e.jsonPath, e.store, "add", func(list []tasks.Task) ([]tasks.Task, string, error) {
+			list = tasks.AddTask(list, task)
+			added = list[len(list)-1]
+			return list, fmt.Sprintf("added #%d %s", added.ID, added.Text), nil
+		})
 		if err != nil {
-			if errors.Is(err, tasks.ErrNotFound) {
-				fmt.Fprintln(os.Stderr, "no such task")
-				return 2
-			}
-			fmt.Fprintln(os.Stderr, err)
-			return 1
+			return failure(err)
 		}
-		if err := storage.SaveTasks(jsonPath, list); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			return 1
+		if *format == render.FormatJSON {
+			return writeResult(render.Result{Op: "add", IDs: []int{added.ID}, Tasks: []tasks.Task{added}})
 		}
-		fmt.Fprintf(os.Stdout, "removed #%d\n", id)
+		fmt.Fprintf(os.Stdout, "added #%d\n", added.ID)
 		return 0
+	}
+}
 
-	case "clear":
-		lock, lerr := storage.AcquireLock(jsonPath)
-		if lerr != nil {
-			fmt.Fprintln(os.Stderr, lerr)
-			return 1
-		}
-		defer lock.Release()
+// listCommand is `list`, or `ready` for the open tasks nothing holds up.
+func listCommand(cmd string) func(*flag.FlagSet) runFunc {
+	return func(fs *flag.FlagSet) runFunc {
+		reverse := fs.Bool("reverse", false, "reverse the configured order (newest first by default)")
+		var ff filterFlags
+		ff.register(fs)
+		format := fs.String("format", defaultFormat(), "output format: text, json, jsonl, csv, tsv or template")
+		tmpl := fs.String("template", "", "Go text/template applied to each task (implies --format template)")
+		return func(e *env, args []string) int {
+			if len(args) != 0 {
+				fmt.Fprintf(os.Stderr, "usage: todo-cli %s [flags]\n", cmd)
+				return 2
+			}
+			current := now()
+			filter, err := ff.build(current)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+			opts := render.Options{Format: *format, Template: *tmpl, Now: current}
+			if *tmpl != "" {
+				opts.Format = render.FormatTemplate
+			}
+			if err := render.CheckFormat(opts.Format, render.ListFormats); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+			if opts.Format == render.FormatTemplate {
+				// Surface template syntax errors as usage errors before locking.
+				if _, err := render.ParseTemplate(opts.Template, current); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					return 2
+				}
+			}
+			lock, lerr := storage.AcquireSharedLock(e.jsonPath)
+			if lerr != nil {
+				fmt.Fprintln(os.Stderr, lerr)
+				return 1
+			}
+			defer lock.Release()
 
-		// Clear regardless of existing content
-		if err := storage.SaveTasks(jsonPath, tasks.Clear(nil)); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			return 1
+			list, err := e.store.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			opts.All = list
+			if cmd == "ready" {
+				list = tasks.Ready(list)
+			}
+			list = tasks.Sort(filter.Apply(list, current), oldestFirst(*reverse))
+			if err := render.WriteList(os.Stdout, list, opts); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			return 0
 		}
-		fmt.Fprintln(os.Stdout, "cleared")
-		return 0
+	}
+}
 
-	default:
-		fmt.Fprintln(os.Stderr, "unknown command")
+// runClear handles `clear`, regardless of existing content.
+func runClear(e *env, args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: todo-cli clear")
 		return 2
 	}
+	err := mutate(e.globals, e.jsonPath, e.store, "clear", func(list []tasks.Task) ([]tasks.Task, string, error) {
+		return tasks.Clear(list), fmt.Sprintf("cleared %d tasks", len(list)), nil
+	})
+	if err != nil {
+		return failure(err)
+	}
+	fmt.Fprintln(os.Stdout, "cleared")
+	return 0
 }
 
 type nopWriter struct{}
 
 func (*nopWriter) Write(p []byte) (int, error) { return len(p), nil }
 
-func runMenu() int {
+// resultFormats are the --format values accepted by mutating commands.
+var resultFormats = []string{render.FormatText, render.FormatJSON}
+
+// resultFormatFlag registers --format on a mutating command.
+func resultFormatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", render.FormatText, "result format: text or json")
+}
+
+// writeResult prints the JSON result of a mutation.
+func writeResult(r render.Result) int {
+	if err := render.WriteResult(os.Stdout, r); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// now is the clock used for due dates; tests may replace it.
+var now = time.Now
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// parseInterspersed parses fs over args while allowing flags to appear after
+// positional words, e.g. `add buy milk --due tomorrow`. It returns the
+// positional words in order. Everything after "--" is a word, even when it
+// starts with a dash.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var words []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(words, rest...), nil
+		}
+		args = rest
+		if len(args) == 0 {
+			return words, nil
+		}
+		words = append(words, args[0])
+		args = args[1:]
+	}
+}
+
+// menuRun runs a command from the menu with g, the global options the menu
+// was started with.
+func menuRun(g globals, args ...string) int {
+	return Run(append(g.args(), args...))
+}
+
+func runMenu(g globals) int {
 	if menuUI == nil {
 		menuUI = ui.SurveyUI{}
 	}
@@ -282,7 +383,7 @@ func runMenu() int {
 				fmt.Fprintln(os.Stdout)
 				return 0
 			}
-			return runTextMenu(reader)
+			return runTextMenu(reader, g)
 		}
 
 		switch idx {
@@ -300,11 +401,11 @@ func runMenu() int {
 				fmt.Fprintln(os.Stdout, "no text entered")
 				continue
 			}
-			if exit := Run([]string{"add", text}); exit == 1 {
+			if exit := menuRun(g, "add", "--", text); exit == 1 {
 				return 1
 			}
 		case 1:
-			if exit := menuList(); exit != -1 {
+			if exit := menuList(g); exit != -1 {
 				return exit
 			}
 		case 2:
@@ -315,7 +416,7 @@ func runMenu() int {
 			if !ok {
 				continue
 			}
-			if exit := Run([]string{"done", id}); exit == 1 {
+			if exit := menuRun(g, "done", id); exit == 1 {
 				return 1
 			}
 		case 3:
@@ -326,11 +427,11 @@ func runMenu() int {
 			if !ok {
 				continue
 			}
-			if exit := Run([]string{"rm", id}); exit == 1 {
+			if exit := menuRun(g, "rm", id); exit == 1 {
 				return 1
 			}
 		case 4:
-			if exit := Run([]string{"clear"}); exit == 1 {
+			if exit := menuRun(g, "clear"); exit == 1 {
 				return 1
 			}
 		case 5:
@@ -342,7 +443,7 @@ func runMenu() int {
 	}
 }
 
-func runTextMenu(reader *bufio.Reader) int {
+func runTextMenu(reader *bufio.Reader, g globals) int {
 	if reader == nil {
 		reader = bufio.NewReader(os.Stdin)
 	}
@@ -384,11 +485,11 @@ func runTextMenu(reader *bufio.Reader) int {
 				fmt.Fprintln(os.Stdout, "no text entered")
 				continue
 			}
-			if exit := Run([]string{"add", text}); exit == 1 {
+			if exit := menuRun(g, "add", "--", text); exit == 1 {
 				return 1
 			}
 		case "2":
-			if exit := menuList(); exit != -1 {
+			if exit := menuList(g); exit != -1 {
 				return exit
 			}
 		case "3":
@@ -399,7 +500,7 @@ func runTextMenu(reader *bufio.Reader) int {
 			if !ok {
 				continue
 			}
-			if exit := Run([]string{"done", id}); exit == 1 {
+			if exit := menuRun(g, "done", id); exit == 1 {
 				return 1
 			}
 		case "4":
@@ -410,11 +511,11 @@ func runTextMenu(reader *bufio.Reader) int {
 			if !ok {
 				continue
 			}
-			if exit := Run([]string{"rm", id}); exit == 1 {
+			if exit := menuRun(g, "rm", id); exit == 1 {
 				return 1
 			}
 		case "5":
-			if exit := Run([]string{"clear"}); exit == 1 {
+			if exit := menuRun(g, "clear"); exit == 1 {
 				return 1
 			}
 		case "0":
@@ -457,13 +558,24 @@ func promptID(reader *bufio.Reader, action string) (string, bool, int) {
 	return line, true, -1
 }
 
-func menuList() int {
-	jsonPath, err := storage.DefaultPath()
+func menuList(g globals) int {
+	name, err := resolveList(g)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "error resolving storage path:", err)
+		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
-	list, err := storage.LoadTasks(jsonPath)
+	jsonPath, store, err := openList(g, name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error opening storage:", err)
+		return 1
+	}
+	lock, err := storage.AcquireSharedLock(jsonPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	list, err := store.Load()
+	lock.Release()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
@@ -473,12 +585,9 @@ func menuList() int {
 		return -1
 	}
 	list = tasks.SortNewestFirst(list)
+	current := now()
 	for _, t := range list {
-		state := " "
-		if t.Done {
-			state = "x"
-		}
-		fmt.Fprintf(os.Stdout, "[%s] #%d %s\n", state, t.ID, t.Text)
+		fmt.Fprintln(os.Stdout, render.Line(t, current))
 	}
 	return -1
 }
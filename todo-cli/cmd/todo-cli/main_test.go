@@ -1,11 +1,14 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 type commandTestCase struct {
@@ -89,3 +92,160 @@ func TestParseCommand(t *testing.T) {
 		t.Fatalf("expected storage path in args, got %v", cmd.Args)
 	}
 }
+
+func TestRunAddWithAttributesAndListFilters(t *testing.T) {
+	t.Setenv("TODO_CLI_PATH", filepath.Join(t.TempDir(), "tasks.json"))
+	fixed := time.Date(2024, time.March, 13, 12, 0, 0, 0, time.Local)
+	previous := now
+	now = func() time.Time { return fixed }
+	defer func() { now = previous }()
+
+	steps := [][]string{
+		{"add", "--due", "yesterday", "--priority", "high", "--tag", "home", "file taxes"},
+		{"add", "review", "PR", "--tag", "work,code", "--due", "+2d", "--priority", "m"},
+		{"add", "plan offsite", "--tag", "work"},
+	}
+	for _, args := range steps {
+		if _, stderr, exit := runMenuHarness(t, args, ""); exit != 0 {
+			t.Fatalf("%v: exit %d, stderr=%q", args, exit, stderr)
+		}
+	}
+
+	cases := []struct {
+		name    string
+		args    []string
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "all tasks show details",
+			args: []string{"list"},
+			want: []string{
+				"[ ] #1 file taxes (due 2024-03-12, overdue, priority high, tags home)",
+				"[ ] #2 review PR (due 2024-03-15, priority medium, tags work,code)",
+				"[ ] #3 plan offsite (tags work)",
+			},
+		},
+		{name: "tag", args: []string{"list", "--tag", "WORK"}, want: []string{"#2", "#3"}, notWant: []string{"#1"}},
+		{name: "priority", args: []string{"list", "--priority", "high"}, want: []string{"#1"}, notWant: []string{"#2", "#3"}},
+		{name: "due before", args: []string{"list", "--due-before", "+3d"}, want: []string{"#1", "#2"}, notWant: []string{"#3"}},
+		{name: "overdue", args: []string{"list", "--overdue"}, want: []string{"#1"}, notWant: []string{"#2", "#3"}},
+	}
+	for _, tc := range cases {
+		stdout, stderr, exit := runMenuHarness(t, tc.args, "")
+		if exit != 0 {
+			t.Fatalf("%s: exit %d, stderr=%q", tc.name, exit, stderr)
+		}
+		requireContainsAll(t, stdout, tc.want)
+		for _, nw := range tc.notWant {
+			if strings.Contains(stdout, nw) {
+				t.Fatalf("%s: expected %q to be filtered out, got %q", tc.name, nw, stdout)
+			}
+		}
+	}
+
+	if _, _, exit := runMenuHarness(t, []string{"add", "--due", "someday", "x"}, ""); exit != 2 {
+		t.Fatalf("expected usage error for invalid due date, got %d", exit)
+	}
+	if _, _, exit := runMenuHarness(t, []string{"list", "--priority", "urgent"}, ""); exit != 2 {
+		t.Fatalf("expected usage error for invalid priority, got %d", exit)
+	}
+}
+
+func TestRunUsesBackendFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TODO_CLI_PATH", filepath.Join(dir, "tasks.json"))
+	t.Setenv("TODO_CLI_BACKEND", "jsonl")
+
+	for _, args := range [][]string{{"add", "log me"}, {"done", "1"}} {
+		if _, stderr, exit := runMenuHarness(t, args, ""); exit != 0 {
+			t.Fatalf("%v: exit %d, stderr=%q", args, exit, stderr)
+		}
+	}
+	stdout, _, exit := runMenuHarness(t, []string{"list"}, "")
+	if exit != 0 || !strings.Contains(stdout, "[x] #1 log me") {
+		t.Fatalf("unexpected list output (exit %d): %q", exit, stdout)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tasks.jsonl")); err != nil {
+		t.Fatalf("expected event log next to tasks.json: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tasks.json")); err == nil {
+		t.Fatalf("json backend file should not be written when jsonl is selected")
+	}
+
+	t.Setenv("TODO_CLI_BACKEND", "bogus")
+	if _, _, exit := runMenuHarness(t, []string{"list"}, ""); exit != 1 {
+		t.Fatalf("expected exit 1 for unknown backend, got %d", exit)
+	}
+}
+
+func TestRunMachineReadableOutput(t *testing.T) {
+	t.Setenv("TODO_CLI_PATH", filepath.Join(t.TempDir(), "tasks.json"))
+
+	stdout, stderr, exit := runMenuHarness(t, []string{"add", "--format", "json", "--tag", "ops", "rotate keys"}, "")
+	if exit != 0 {
+		t.Fatalf("add: exit %d, stderr=%q", exit, stderr)
+	}
+	var added struct {
+		Op    string `json:"op"`
+		IDs   []int  `json:"ids"`
+		Tasks []struct {
+			Text string   `json:"text"`
+			Tags []string `json:"tags"`
+		} `json:"tasks"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &added); err != nil {
+		t.Fatalf("add output is not JSON: %v\n%s", err, stdout)
+	}
+	if added.Op != "add" || len(added.IDs) != 1 || added.IDs[0] != 1 || added.Tasks[0].Text != "rotate keys" {
+		t.Fatalf("unexpected add result %+v", added)
+	}
+
+	runMenuHarness(t, []string{"add", "second"}, "")
+	stdout, _, _ = runMenuHarness(t, []string{"done", "1-2", "--format", "json"}, "")
+	if strings.TrimSpace(stdout) == "" || !strings.Contains(stdout, `"op":"done","ids":[1,2]`) {
+		t.Fatalf("unexpected done result %q", stdout)
+	}
+
+	stdout, _, exit = runMenuHarness(t, []string{"list", "--format", "csv", "--reverse"}, "")
+	if exit != 0 || !strings.HasPrefix(stdout, "id,done,text,due,priority,tags\n1,true,rotate keys,,,ops\n") {
+		t.Fatalf("unexpected csv output (exit %d): %q", exit, stdout)
+	}
+
+	stdout, _, exit = runMenuHarness(t, []string{"list", "--template", "{{.ID}} {{.Text}}"}, "")
+	if exit != 0 || stdout != "2 second\n1 rotate keys\n" {
+		t.Fatalf("unexpected template output (exit %d): %q", exit, stdout)
+	}
+
+	for _, args := range [][]string{
+		{"list", "--format", "xml"},
+		{"list", "--format", "template"},
+		{"list", "--template", "{{.ID"},
+		{"add", "--format", "csv", "x"},
+	} {
+		if _, _, exit := runMenuHarness(t, args, ""); exit != 2 {
+			t.Fatalf("%v: expected usage error, got %d", args, exit)
+		}
+	}
+}
+
+func TestRunAddTextStartingWithDash(t *testing.T) {
+	t.Setenv("TODO_CLI_PATH", filepath.Join(t.TempDir(), "tasks.json"))
+
+	if _, _, exit := runMenuHarness(t, []string{"add", "-5 degrees"}, ""); exit != 2 {
+		t.Fatalf("expected usage error for dash-prefixed text without --, got %d", exit)
+	}
+	for _, args := range [][]string{
+		{"add", "--", "-5 degrees, wear a coat"},
+		{"add", "--tag", "home", "--", "buy", "-x"},
+	} {
+		if _, stderr, exit := runMenuHarness(t, args, ""); exit != 0 {
+			t.Fatalf("%v: exit %d, stderr=%q", args, exit, stderr)
+		}
+	}
+	stdout, _, exit := runMenuHarness(t, []string{"list"}, "")
+	if exit != 0 {
+		t.Fatalf("list: exit %d", exit)
+	}
+	requireContainsAll(t, stdout, []string{"#1 -5 degrees, wear a coat", "#2 buy -x (tags home)"})
+}
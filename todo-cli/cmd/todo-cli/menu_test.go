@@ -109,6 +109,15 @@ func TestMenuScenarios(t *testing.T) {
 				"invalid selection",
 			},
 		},
+		{
+			name:     "7 add text starting with a dash",
+			script:   "1\n-5 degrees, wear a coat\n2\n0\n",
+			wantExit: 0,
+			wantStdout: []string{
+				"added #1",
+				"[ ] #1 -5 degrees, wear a coat",
+			},
+		},
 	}
 
 	entrypoints := []struct {
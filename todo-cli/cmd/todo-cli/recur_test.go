@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecurringTasks(t *testing.T) {
+	t.Setenv("TODO_CLI_PATH", filepath.Join(t.TempDir(), "tasks.json"))
+	previous := now
+	// 2024-03-05 is a Tuesday.
+	now = func() time.Time { return time.Date(2024, time.March, 5, 10, 0, 0, 0, time.Local) }
+	defer func() { now = previous }()
+
+	run := func(wantExit int, args ...string) (string, string) {
+		t.Helper()
+		stdout, stderr, exit := runMenuHarness(t, args, "")
+		if exit != wantExit {
+			t.Fatalf("%v: expected exit %d, got %d (stdout=%q stderr=%q)", args, wantExit, exit, stdout, stderr)
+		}
+		return stdout, stderr
+	}
+
+	run(0, "add", "Gym", "--recur", "weekly:mon,thu")
+	run(0, "add", "Water plants", "--recur", "every 3 days", "--due", "2024-03-01")
+	run(0, "add", "Pay rent", "--recur", "monthly", "--due", "2024-03-31")
+
+	out, _ := run(0, "list", "--reverse")
+	requireContainsInOrder(t, out, []string{
+		"#1 Gym (due 2024-03-07, repeats weekly:mon,thu)",
+		"#2 Water plants (due 2024-03-01, overdue, repeats every 3 days)",
+		"#3 Pay rent (due 2024-03-31, repeats monthly)",
+	})
+
+	out, _ = run(0, "done", "1")
+	requireContainsAll(t, out, []string{"done #1", "next: [ ] #4 Gym (due 2024-03-11, repeats weekly:mon,thu)"})
+
+	out, _ = run(0, "done", "2-3")
+	requireContainsAll(t, out, []string{
+		"done #2, #3 (2 tasks)",
+		"next: [ ] #5 Water plants (due 2024-03-07, repeats every 3 days)",
+		"next: [ ] #6 Pay rent (due 2024-04-30, repeats monthly:31)",
+	})
+
+	// Reopening and completing again must not spawn a second copy.
+	run(0, "undone", "1")
+	out, _ = run(0, "done", "1")
+	if out != "done #1\n" {
+		t.Fatalf("expected no new occurrence, got %q", out)
+	}
+
+	out, _ = run(0, "done", "--format", "json", "4")
+	requireContainsAll(t, out, []string{`"spawned":[7]`})
+
+	_, stderr := run(2, "add", "Bad", "--recur", "hourly")
+	requireContainsAll(t, stderr, []string{"invalid recurrence"})
+}
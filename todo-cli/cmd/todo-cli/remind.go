@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/remind"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// remindCommand is `remind`: print what needs attention now, or with
+// --watch keep running and notify as reminders and due dates come up.
+func remindCommand(fs *flag.FlagSet) runFunc {
+	watch := fs.Bool("watch", false, "keep running and notify at reminder and due times")
+	poll := fs.Duration("poll", 5*time.Second, "how often --watch checks the tasks file for changes")
+	quiet := fs.Bool("quiet", false, "do not print notices to stdout")
+	desktop := fs.Bool("desktop", false, "send desktop notifications (notify-send, or osascript on macOS)")
+	desktopCmd := fs.String("desktop-cmd", "", "desktop notification command; title and message are appended")
+	var hooks stringList
+	fs.Var(&hooks, "exec", "shell command to run for each notice (repeatable)")
+	return func(e *env, args []string) int {
+		if len(args) != 0 || *poll <= 0 {
+			fmt.Fprintln(os.Stderr, "invalid flags")
+			return 2
+		}
+
+		var notifiers []remind.Notifier
+		if !*quiet {
+			notifiers = append(notifiers, remind.Writer{W: os.Stdout, Now: now})
+		}
+		if *desktop || *desktopCmd != "" {
+			notifiers = append(notifiers, remind.Desktop{Command: strings.Fields(*desktopCmd)})
+		}
+		for _, h := range hooks {
+			notifiers = append(notifiers, remind.Exec{Command: h})
+		}
+		if len(notifiers) == 0 {
+			fmt.Fprintln(os.Stderr, "remind --quiet needs --desktop, --desktop-cmd or --exec")
+			return 2
+		}
+
+		w := &remind.Watcher{
+			Load: func() ([]tasks.Task, error) {
+				lock, err := storage.AcquireSharedLock(e.jsonPath)
+				if err != nil {
+					return nil, err
+				}
+				defer lock.Release()
+				return e.store.Load()
+			},
+			// The JSON file or, with the jsonl backend, its event log.
+			Paths:     []string{e.jsonPath, storage.EventLogPath(e.jsonPath)},
+			Notifiers: notifiers,
+			Now:       now,
+			Poll:      *poll,
+			Errors:    os.Stderr,
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if !*watch {
+			if !w.Check(ctx) {
+				// The watcher already reported why the tasks could not be read.
+				return 1
+			}
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "watching %s for reminders (Ctrl+C to stop)\n", e.jsonPath)
+		if err := w.Run(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRemindAtTimesAndOneShotRemind(t *testing.T) {
+	t.Setenv("TODO_CLI_PATH", filepath.Join(t.TempDir(), "tasks.json"))
+	originalNow := now
+	now = func() time.Time { return time.Date(2024, time.March, 5, 8, 0, 0, 0, time.Local) }
+	defer func() { now = originalNow }()
+
+	run := func(wantExit int, args ...string) string {
+		t.Helper()
+		stdout, stderr, exit := runMenuHarness(t, args, "")
+		if exit != wantExit {
+			t.Fatalf("%v: expected exit %d, got %d (stdout=%q stderr=%q)", args, wantExit, exit, stdout, stderr)
+		}
+		return stdout + stderr
+	}
+
+	run(0, "add", "Dentist", "--due", "2024-03-05 10:00", "--remind", "-2h")
+	run(0, "add", "Pay rent", "--due", "today")
+	run(0, "add", "Call bank", "--remind", "2024-03-05 09:00")
+	run(2, "add", "Nope", "--remind", "-1h")
+	out := run(0, "list", "--reverse")
+	requireContainsInOrder(t, out, []string{
+		"#1 Dentist (due 2024-03-05 10:00, remind 2024-03-05 08:00)",
+		"#2 Pay rent (due 2024-03-05)",
+		"#3 Call bank (remind 2024-03-05 09:00)",
+	})
+
+	out = run(0, "remind")
+	requireContainsInOrder(t, out, []string{
+		"2024-03-05 08:00 due      #2 Pay rent is due today",
+		"2024-03-05 08:00 reminder #1 Dentist (due 2024-03-05 10:00)",
+	})
+	if strings.Contains(out, "Call bank") {
+		t.Fatalf("reminder for #3 fired early: %q", out)
+	}
+
+	run(0, "edit", "3", "--due", "2024-03-06 12:00", "--remind", "-1d")
+	run(0, "edit", "1", "--remind", "none")
+	out = run(0, "list", "--reverse")
+	requireContainsAll(t, out, []string{
+		"#1 Dentist (due 2024-03-05 10:00)\n",
+		"#3 Call bank (due 2024-03-06 12:00, remind 2024-03-05 12:00)",
+	})
+	run(2, "edit", "3", "--remind", "-soon")
+	run(2, "remind", "--quiet")
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	hookOut := filepath.Join(t.TempDir(), "hook.txt")
+	run(0, "remind", "--quiet", "--exec", `echo "$TODO_ID $TODO_KIND" >> `+hookOut)
+	got, err := os.ReadFile(hookOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "2 due\n" {
+		t.Fatalf("hook output %q", got)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/render"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/search"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// ANSI markers used to highlight matches.
+const (
+	highlightOn  = "\x1b[1;33m"
+	highlightOff = "\x1b[0m"
+)
+
+// searchCommand is `search [--regex|--fuzzy] [--limit n] [filters] <query...>`.
+func searchCommand(fs *flag.FlagSet) runFunc {
+	regex := fs.Bool("regex", false, "treat the query as a regular expression")
+	fuzzy := fs.Bool("fuzzy", false, "match query characters in order, allowing gaps")
+	limit := fs.Int("limit", 0, "show at most n results (0 shows all)")
+	color := fs.String("color", "auto", "highlight matches: auto, always or never")
+	var ff filterFlags
+	ff.register(fs)
+	format := fs.String("format", defaultFormat(), "output format: text, json, jsonl, csv, tsv or template")
+	tmpl := fs.String("template", "", "Go text/template applied to each task (implies --format template)")
+	return func(e *env, words []string) int {
+		if *limit < 0 || (*regex && *fuzzy) {
+			fmt.Fprintln(os.Stderr, "invalid flags")
+			return 2
+		}
+		query := strings.Join(words, " ")
+		if strings.TrimSpace(query) == "" {
+			fmt.Fprintln(os.Stderr, "usage: todo-cli search [--regex|--fuzzy] <query...>")
+			return 2
+		}
+		mode := search.Substring
+		switch {
+		case *regex:
+			mode = search.Regex
+		case *fuzzy:
+			mode = search.Fuzzy
+		}
+		matcher, err := search.Compile(query, mode)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		highlight, err := useColor(*color)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		current := now()
+		filter, err := ff.build(current)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		opts := render.Options{Format: *format, Template: *tmpl, Now: current}
+		if *tmpl != "" {
+			opts.Format = render.FormatTemplate
+		}
+		if err := render.CheckFormat(opts.Format, render.ListFormats); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if opts.Format == render.FormatTemplate {
+			if _, err := render.ParseTemplate(opts.Template, current); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+		}
+
+		lock, err := storage.AcquireSharedLock(e.jsonPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		list, err := e.store.Load()
+		lock.Release()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		hits := search.Search(filter.Apply(list, current), matcher)
+		if *limit > 0 && len(hits) > *limit {
+			hits = hits[:*limit]
+		}
+		if opts.Format != render.FormatText {
+			ranked := make([]tasks.Task, len(hits))
+			for i, h := range hits {
+				ranked[i] = h.Task
+			}
+			if err := render.WriteList(os.Stdout, ranked, opts); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			return 0
+		}
+		if len(hits) == 0 {
+			fmt.Fprintf(os.Stdout, "no tasks match %q\n", query)
+			return 0
+		}
+		for _, h := range hits {
+			t := h.Task
+			if highlight {
+				t = highlighted(h)
+			}
+			fmt.Fprintln(os.Stdout, render.Line(t, current))
+		}
+		return 0
+	}
+}
+
+// highlighted returns a copy of the hit's task with matched spans marked.
+func highlighted(h search.Hit) tasks.Task {
+	t := h.Task.Clone()
+	t.Text = search.Highlight(t.Text, h.Spans, highlightOn, highlightOff)
+	for i := range t.Tags {
+		if i < len(h.TagSpans) {
+			t.Tags[i] = search.Highlight(t.Tags[i], h.TagSpans[i], highlightOn, highlightOff)
+		}
+	}
+	return t
+}
+
+// useColor resolves --color: auto highlights only when stdout is a terminal
+// and $NO_COLOR is unset.
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		info, err := os.Stdout.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("invalid color mode %q (want auto, always or never)", mode)
+}
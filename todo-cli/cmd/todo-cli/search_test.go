@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSearchCommand(t *testing.T) {
+	t.Setenv("TODO_CLI_PATH", filepath.Join(t.TempDir(), "tasks.json"))
+
+	run := func(wantExit int, args ...string) (string, string) {
+		t.Helper()
+		stdout, stderr, exit := runMenuHarness(t, args, "")
+		if exit != wantExit {
+			t.Fatalf("%v: expected exit %d, got %d (stdout=%q stderr=%q)", args, wantExit, exit, stdout, stderr)
+		}
+		return stdout, stderr
+	}
+
+	run(0, "add", "Call the plumber")
+	run(0, "add", "Plumber invoice", "--tag", "bills")
+	run(0, "add", "Pay rent", "--tag", "plumbing")
+	run(0, "add", "Renew passport")
+	run(0, "done", "2")
+
+	out, _ := run(0, "search", "plumb")
+	requireContainsInOrder(t, out, []string{"[x] #2 Plumber invoice", "[ ] #1 Call the plumber", "[ ] #3 Pay rent"})
+	if strings.Contains(out, "\x1b[") {
+		t.Fatalf("expected no color when stdout is not a terminal, got %q", out)
+	}
+
+	out, _ = run(0, "search", "--color", "always", "--open", "plumb")
+	requireContainsAll(t, out, []string{
+		"[ ] #1 Call the " + highlightOn + "plumb" + highlightOff + "er",
+		"tags " + highlightOn + "plumb" + highlightOff + "ing",
+	})
+	if strings.Contains(out, "#2") {
+		t.Fatalf("--open should drop completed tasks, got %q", out)
+	}
+
+	out, _ = run(0, "search", "--fuzzy", "rnw", "pspt")
+	requireContainsAll(t, out, []string{"#4 Renew passport"})
+
+	out, _ = run(0, "search", "--regex", `^(pay|call)\b`, "--format", "jsonl")
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 2 {
+		t.Fatalf("expected two jsonl rows, got %q", out)
+	}
+
+	out, _ = run(0, "search", "--limit", "1", "plumb")
+	if strings.Count(out, "\n") != 1 {
+		t.Fatalf("--limit 1 should print one line, got %q", out)
+	}
+
+	out, _ = run(0, "search", "dentist")
+	requireContainsAll(t, out, []string{`no tasks match "dentist"`})
+
+	run(2, "search")
+	run(2, "search", "--regex", "--fuzzy", "x")
+	_, stderr := run(2, "search", "--regex", "(")
+	requireContainsAll(t, stderr, []string{"invalid regex"})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/server"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// defaultAddr keeps the API on the loopback interface unless asked otherwise.
+const defaultAddr = "127.0.0.1:8765"
+
+// serveCommand is `serve [--addr host:port]`. It serves until interrupted.
+func serveCommand(fs *flag.FlagSet) runFunc {
+	addr := fs.String("addr", defaultAddr, "address to listen on")
+	return func(e *env, args []string) int {
+		if len(args) != 0 {
+			fmt.Fprintln(os.Stderr, "invalid flags")
+			return 2
+		}
+		ln, err := net.Listen("tcp", *addr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := serve(ctx, ln, apiStore{globals: e.globals, jsonPath: e.jsonPath, store: e.store}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+}
+
+// serve runs the API on ln until ctx is done, then lets in-flight requests
+// finish.
+func serve(ctx context.Context, ln net.Listener, store server.Store) error {
+	srv := &http.Server{
+		Handler:           server.New(store, now),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Fprintf(os.Stdout, "serving tasks on http://%s/tasks\n", ln.Addr())
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdown); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// apiStore gives the server the same locking and undo journal as the
+// command line.
+type apiStore struct {
+	globals  globals
+	jsonPath string
+	store    storage.Store
+}
+
+func (s apiStore) Load() ([]tasks.Task, error) {
+	lock, err := storage.AcquireSharedLock(s.jsonPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+	return s.store.Load()
+}
+
+func (s apiStore) Update(op string, fn func([]tasks.Task) ([]tasks.Task, string, error)) error {
+	return mutate(s.globals, s.jsonPath, s.store, op, fn)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServeSharesStorageLockingAndJournal(t *testing.T) {
+	t.Setenv("TODO_CLI_PATH", filepath.Join(t.TempDir(), "tasks.json"))
+	jsonPath, store, err := openList(globals{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, ln, apiStore{jsonPath: jsonPath, store: store}) }()
+	base := "http://" + ln.Addr().String()
+
+	request := func(method, path, body, ifMatch string) (int, string, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, base+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, resp.Header.Get("ETag"), string(b)
+	}
+
+	status, etag, body := request("POST", "/tasks", `{"text":"Review PR","priority":"high"}`, "")
+	if status != http.StatusCreated {
+		t.Fatalf("POST: %d %s", status, body)
+	}
+	out, _, _ := runMenuHarness(t, []string{"list"}, "")
+	requireContainsAll(t, out, []string{"#1 Review PR"})
+
+	// A CLI edit changes the task under the API client's feet.
+	if _, stderr, exit := runMenuHarness(t, []string{"edit", "1", "Review PR #42"}, ""); exit != 0 {
+		t.Fatalf("edit exited %d: %s", exit, stderr)
+	}
+	if status, _, _ := request("PATCH", "/tasks/1", `{"done":true}`, etag); status != http.StatusPreconditionFailed {
+		t.Fatalf("stale PATCH: expected 412, got %d", status)
+	}
+	_, etag, _ = request("GET", "/tasks/1", "", "")
+	if status, _, body := request("PATCH", "/tasks/1", `{"done":true}`, etag); status != http.StatusOK {
+		t.Fatalf("PATCH: %d %s", status, body)
+	}
+
+	out, _, _ = runMenuHarness(t, []string{"history"}, "")
+	requireContainsInOrder(t, out, []string{"updated #1", "edited #1", "added #1 Review PR"})
+	runMenuHarness(t, []string{"undo"}, "")
+	_, _, body = request("GET", "/tasks/1", "", "")
+	requireContainsAll(t, body, []string{`"done":false`, "Review PR #42"})
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+}
+
+func TestServeRejectsBadAddress(t *testing.T) {
+	t.Setenv("TODO_CLI_PATH", filepath.Join(t.TempDir(), "tasks.json"))
+	if _, _, exit := runMenuHarness(t, []string{"serve", "--addr", "not-an-address"}, ""); exit != 1 {
+		t.Fatalf("expected exit 1, got %d", exit)
+	}
+	if _, _, exit := runMenuHarness(t, []string{"serve", "extra"}, ""); exit != 2 {
+		t.Fatalf("expected exit 2, got %d", exit)
+	}
+}
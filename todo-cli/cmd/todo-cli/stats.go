@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/render"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/stats"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// statsCommand is `stats`: throughput per day and week, time to complete,
+// the open backlog and the completion streak.
+func statsCommand(fs *flag.FlagSet) runFunc {
+	days := fs.Int("days", 14, "number of days in the daily view")
+	weeks := fs.Int("weeks", 8, "number of weeks in the weekly view")
+	format := fs.String("format", render.FormatText, "output format: text or json")
+	return func(e *env, args []string) int {
+		if len(args) != 0 || *days < 1 || *weeks < 1 {
+			fmt.Fprintln(os.Stderr, "invalid flags")
+			return 2
+		}
+		if err := render.CheckFormat(*format, []string{render.FormatText, render.FormatJSON}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+
+		lock, err := storage.AcquireSharedLock(e.jsonPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		list, err := e.store.Load()
+		lock.Release()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		report := stats.Compute(list, now(), *days, *weeks)
+		if *format == render.FormatJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			return 0
+		}
+		writeStats(os.Stdout, report)
+		return 0
+	}
+}
+
+func writeStats(w io.Writer, r stats.Report) {
+	first, last := r.Days[0].Start, r.Days[len(r.Days)-1].Start
+	fmt.Fprintf(w, "Last %d days (%s to %s)\n", len(r.Days), first.Format(tasks.DateLayout), last.Format(tasks.DateLayout))
+	fmt.Fprintf(w, "  created    %s  %d\n", stats.Sparkline(stats.Counts(r.Days, false)), r.Created)
+	fmt.Fprintf(w, "  completed  %s  %d\n", stats.Sparkline(stats.Counts(r.Days, true)), r.Completed)
+
+	fmt.Fprintf(w, "\nLast %d weeks\n", len(r.Weeks))
+	fmt.Fprintln(w, "  week of     created  completed")
+	for _, b := range r.Weeks {
+		fmt.Fprintf(w, "  %s  %7d  %9d\n", b.Start.Format(tasks.DateLayout), b.Created, b.Completed)
+	}
+	fmt.Fprintf(w, "  created    %s\n", stats.Sparkline(stats.Counts(r.Weeks, false)))
+	fmt.Fprintf(w, "  completed  %s\n", stats.Sparkline(stats.Counts(r.Weeks, true)))
+
+	fmt.Fprintln(w)
+	if r.Timed > 0 {
+		fmt.Fprintf(w, "Average time to complete: %s (%d tasks)\n", stats.FormatDuration(r.AvgToComplete), r.Timed)
+	} else {
+		fmt.Fprintln(w, "Average time to complete: n/a")
+	}
+	fmt.Fprintf(w, "Open backlog: %d (%d overdue)\n", r.Open, r.Overdue)
+	fmt.Fprintf(w, "Completion streak: %s (longest %s)\n", plural(r.Streak, "day"), plural(r.LongestStreak, "day"))
+	if r.Untracked > 0 {
+		fmt.Fprintf(w, "%s without timestamps from older versions are not counted per day\n", plural(r.Untracked, "task"))
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStatsReport(t *testing.T) {
+	t.Setenv("TODO_CLI_PATH", filepath.Join(t.TempDir(), "tasks.json"))
+	originalNow := now
+	defer func() { now = originalNow }()
+	setNow := func(day, hour int) {
+		now = func() time.Time { return time.Date(2024, time.March, day, hour, 0, 0, 0, time.Local) }
+	}
+	run := func(args ...string) string {
+		t.Helper()
+		stdout, stderr, exit := runMenuHarness(t, args, "")
+		if exit != 0 {
+			t.Fatalf("%v: exit %d: %s", args, exit, stderr)
+		}
+		return stdout
+	}
+
+	setNow(4, 9)
+	run("add", "Write report")
+	run("add", "Pay rent", "--due", "2024-03-05")
+	setNow(5, 9)
+	run("add", "Call bank")
+	setNow(5, 15)
+	run("done", "1")
+	setNow(6, 10)
+	run("done", "3")
+
+	out := run("stats", "--days", "3", "--weeks", "2")
+	requireContainsInOrder(t, out, []string{
+		"Last 3 days (2024-03-04 to 2024-03-06)\n",
+		"  created    @+_  3\n",
+		"  completed  _@@  2\n",
+		"Last 2 weeks\n",
+		"  2024-02-26        0          0\n",
+		"  2024-03-04        3          2\n",
+		"Average time to complete: 1d 3h (2 tasks)\n",
+		"Open backlog: 1 (1 overdue)\n",
+		"Completion streak: 2 days (longest 2 days)\n",
+	})
+
+	out = run("stats", "--format", "json")
+	var report struct {
+		Created int `json:"created"`
+		Streak  int `json:"streak"`
+		Days    []struct {
+			Completed int `json:"completed"`
+		} `json:"days"`
+	}
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	if report.Created != 3 || report.Streak != 2 || len(report.Days) != 14 {
+		t.Fatalf("unexpected JSON report %+v", report)
+	}
+
+	if _, _, exit := runMenuHarness(t, []string{"stats", "--days", "0"}, ""); exit != 2 {
+		t.Fatalf("expected exit 2 for --days 0, got %d", exit)
+	}
+}
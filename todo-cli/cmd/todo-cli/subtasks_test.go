@@ -0,0 +1,66 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSubtasksDependenciesAndReady(t *testing.T) {
+	t.Setenv("TODO_CLI_PATH", filepath.Join(t.TempDir(), "tasks.json"))
+
+	run := func(wantExit int, args ...string) (string, string) {
+		t.Helper()
+		stdout, stderr, exit := runMenuHarness(t, args, "")
+		if exit != wantExit {
+			t.Fatalf("%v: expected exit %d, got %d (stdout=%q stderr=%q)", args, wantExit, exit, stdout, stderr)
+		}
+		return stdout, stderr
+	}
+
+	run(0, "add", "Plan trip")
+	run(0, "add", "Book flights", "--parent", "1")
+	run(0, "add", "Book hotel", "--parent", "1", "--blocked-by", "2")
+	run(0, "add", "Pick seats", "--parent", "2")
+	run(0, "add", "Pack")
+	run(0, "edit", "5", "--block", "3")
+
+	out, _ := run(0, "list", "--reverse")
+	want := "[ ] #1 Plan trip\n" +
+		"├─ [ ] #2 Book flights\n" +
+		"│  └─ [ ] #4 Pick seats\n" +
+		"└─ [ ] #3 Book hotel (blocked by #2)\n" +
+		"[ ] #5 Pack (blocked by #3)\n"
+	if out != want {
+		t.Fatalf("unexpected tree:\n%s\nwant:\n%s", out, want)
+	}
+
+	out, _ = run(0, "ready")
+	if out != "[ ] #4 Pick seats (subtask of #2)\n" {
+		t.Fatalf("unexpected ready view %q", out)
+	}
+
+	_, stderr := run(2, "done", "2")
+	requireContainsAll(t, stderr, []string{"#2 has open subtasks: #4", "--force"})
+	run(0, "done", "2", "4")
+	out, _ = run(0, "list", "--reverse")
+	requireContainsAll(t, out, []string{"└─ [ ] #3 Book hotel\n"})
+	out, _ = run(0, "ready", "--reverse")
+	requireContainsInOrder(t, out, []string{"#3 Book hotel"})
+	if strings.Contains(out, "Pack") {
+		t.Fatalf("Pack is still blocked by #3, got %q", out)
+	}
+
+	_, stderr = run(2, "edit", "2", "--block", "5")
+	requireContainsAll(t, stderr, []string{"dependency cycle: #"})
+	_, stderr = run(2, "edit", "1", "--parent", "3")
+	requireContainsAll(t, stderr, []string{"dependency cycle"})
+	_, stderr = run(2, "add", "Orphan", "--parent", "42")
+	requireContainsAll(t, stderr, []string{"parent #42"})
+
+	run(0, "done", "1", "--force")
+	run(0, "edit", "5", "--unblock", "3")
+	run(0, "rm", "1")
+	out, _ = run(0, "list", "--reverse")
+	requireContainsInOrder(t, out, []string{"[x] #2 Book flights\n", "└─ [x] #4 Pick seats\n", "[ ] #3 Book hotel\n", "[ ] #5 Pack\n"})
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/gitsync"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/journal"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+)
+
+// runSync handles `sync` and `sync init [url]`. It holds the lock of every
+// list while git rewrites the task files.
+func runSync(g globals, args []string) int {
+	usage := "usage: todo-cli sync [init [url]]"
+	setup := len(args) > 0 && args[0] == "init"
+	if (setup && len(args) > 2) || (!setup && len(args) != 0) {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	if b := strings.ToLower(storage.DefaultBackend()); b != "" && b != storage.BackendJSON {
+		fmt.Fprintf(os.Stderr, "sync needs the %s backend\n", storage.BackendJSON)
+		return 2
+	}
+	base, err := basePath(g)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	dir := filepath.Dir(base)
+	names, err := storage.ListNames(base)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		p, err := storage.ListPath(base, name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		paths = append(paths, p)
+	}
+
+	var res gitsync.Result
+	err = withLocks(paths, func() error {
+		repo, ok := gitsync.Open(base)
+		if setup {
+			url := ""
+			if len(args) == 2 {
+				url = args[1]
+			}
+			r, err := gitsync.Init(base, url)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "syncing %s with git\n", dir)
+			if url == "" {
+				return nil
+			}
+			repo = r
+		} else if !ok {
+			return fmt.Errorf("%s is not synced (use sync init <url>)", dir)
+		}
+		before := snapshot(paths)
+		var err error
+		res, err = repo.Sync()
+		// A failed sync may still have fast-forwarded some lists.
+		return errors.Join(err, fenceChanged(paths, before))
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, gitsync.ErrNoRemote) {
+			return 2
+		}
+		return 1
+	}
+	if setup && len(args) < 2 {
+		return 0
+	}
+	writeSyncResult(res)
+	return 0
+}
+
+func writeSyncResult(res gitsync.Result) {
+	switch {
+	case res.Pulled && res.Pushed:
+		fmt.Fprintln(os.Stdout, "pulled and pushed")
+	case res.Pulled:
+		fmt.Fprintln(os.Stdout, "pulled")
+	case res.Pushed:
+		fmt.Fprintln(os.Stdout, "pushed")
+	default:
+		fmt.Fprintln(os.Stdout, "already up to date")
+	}
+	for _, f := range res.Merged {
+		fmt.Fprintf(os.Stdout, "merged %s\n", f)
+	}
+	for _, r := range res.Renumbered {
+		fmt.Fprintf(os.Stdout, "renumbered %s\n", r)
+	}
+	for _, c := range res.Conflicts {
+		fmt.Fprintf(os.Stdout, "conflict: %s\n", c)
+	}
+	for _, f := range res.Dropped {
+		fmt.Fprintf(os.Stdout, "conflict: %s changed on both sides; kept the local version\n", f)
+	}
+}
+
+// snapshot reads the lists at paths, for fenceChanged to compare against.
+func snapshot(paths []string) map[string][]byte {
+	lists := make(map[string][]byte, len(paths))
+	for _, p := range paths {
+		list, _ := storage.LoadTasks(p)
+		lists[p], _ = json.Marshal(list)
+	}
+	return lists
+}
+
+// fenceChanged fences the undo journal of every list that changed since the
+// snapshot was taken: undoing past the sync would revert the remote changes.
+func fenceChanged(paths []string, before map[string][]byte) error {
+	after := snapshot(paths)
+	for _, p := range paths {
+		if bytes.Equal(before[p], after[p]) {
+			continue
+		}
+		jpath := journal.PathFor(p)
+		j, err := journal.Load(jpath)
+		if err != nil {
+			return err
+		}
+		if len(j.Entries) == 0 {
+			continue
+		}
+		j.Fence()
+		if err := j.Save(jpath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commitChange commits a saved change when the storage directory is synced
+// with git. A failed commit is only reported: the change itself is saved,
+// and the next commit or sync picks it up.
+func commitChange(g globals, jsonPath, summary string) {
+	base, err := basePath(g)
+	if err != nil {
+		return
+	}
+	repo, ok := gitsync.Open(base)
+	if !ok {
+		return
+	}
+	if rel, err := filepath.Rel(repo.Dir, jsonPath); err == nil {
+		summary = filepath.ToSlash(rel) + ": " + summary
+	}
+	if _, err := repo.Commit(summary); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: git commit failed:", err)
+	}
+}
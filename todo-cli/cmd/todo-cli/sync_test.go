@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSyncBetweenTwoStorageDirs(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	remote := filepath.Join(t.TempDir(), "tasks.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare: %v: %s", err, out)
+	}
+	laptop := filepath.Join(t.TempDir(), "tasks.json")
+	desktop := filepath.Join(t.TempDir(), "tasks.json")
+
+	run := func(path string, wantExit int, args ...string) string {
+		t.Helper()
+		t.Setenv("TODO_CLI_PATH", path)
+		stdout, stderr, exit := runMenuHarness(t, args, "")
+		if exit != wantExit {
+			t.Fatalf("%v: expected exit %d, got %d (stdout=%q stderr=%q)", args, wantExit, exit, stdout, stderr)
+		}
+		return stdout + stderr
+	}
+
+	run(laptop, 1, "sync")
+	run(laptop, 0, "add", "Buy milk")
+	out := run(laptop, 0, "sync", "init", remote)
+	requireContainsAll(t, out, []string{"syncing ", "pushed"})
+	run(desktop, 0, "sync", "init", remote)
+	out = run(desktop, 0, "list")
+	requireContainsAll(t, out, []string{"#1 Buy milk"})
+
+	// Each mutation is committed, so sync has nothing left to commit.
+	run(laptop, 0, "done", "1")
+	log, err := exec.Command("git", "-C", filepath.Dir(laptop), "log", "--format=%s", "-1").Output()
+	if err != nil || !strings.Contains(string(log), "tasks.json: ") {
+		t.Fatalf("last commit = %q, %v", log, err)
+	}
+	run(laptop, 0, "add", "Laptop task")
+	run(desktop, 0, "edit", "1", "Buy oat milk")
+	run(desktop, 0, "add", "Desktop task")
+	run(laptop, 0, "sync")
+	out = run(desktop, 0, "sync")
+	requireContainsAll(t, out, []string{"pulled and pushed", "merged tasks.json", `renumbered tasks.json #2 "Laptop task" is now #3`})
+
+	out = run(laptop, 0, "sync")
+	requireContainsAll(t, out, []string{"pulled"})
+	out = run(laptop, 0, "list", "--reverse")
+	requireContainsInOrder(t, out, []string{"[x] #1 Buy oat milk", "[ ] #2 Desktop task", "[ ] #3 Laptop task"})
+	out = run(laptop, 0, "sync")
+	requireContainsAll(t, out, []string{"already up to date"})
+
+	// Undo stops at the synced list instead of reverting the remote changes.
+	run(laptop, 0, "add", "After sync")
+	run(laptop, 0, "undo")
+	out = run(laptop, 2, "undo")
+	requireContainsAll(t, out, []string{"nothing to undo"})
+	out = run(laptop, 0, "list")
+	requireContainsAll(t, out, []string{"#1 Buy oat milk", "#2 Desktop task", "#3 Laptop task"})
+	requireContainsAll(t, run(laptop, 0, "history"), []string{"added #2 Laptop task (before sync)"})
+
+	t.Setenv("TODO_CLI_BACKEND", "jsonl")
+	run(laptop, 2, "sync")
+}
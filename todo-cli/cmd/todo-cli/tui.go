@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tui"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/ui"
+)
+
+// openScreen opens the terminal for the TUI; tests replace it with a
+// ui.FakeScreen.
+var openScreen = func() (ui.Screen, error) { return ui.OpenTerminal() }
+
+// runTUI handles `tui`, the full-screen list view.
+func runTUI(g globals, jsonPath string, store storage.Store, args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: todo-cli tui")
+		return 2
+	}
+	screen, err := openScreen()
+	if err != nil {
+		if errors.Is(err, ui.ErrNotTerminal) {
+			fmt.Fprintln(os.Stderr, "tui needs an interactive terminal; use list or menu instead")
+			return 2
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	err = tui.New(tuiStore{globals: g, jsonPath: jsonPath, store: store}, screen, now).Run()
+	if cerr := screen.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// tuiStore runs each TUI action as a locked, journaled mutation, so TUI
+// changes can be undone from the command line.
+type tuiStore struct {
+	globals  globals
+	jsonPath string
+	store    storage.Store
+}
+
+func (s tuiStore) Load() ([]tasks.Task, error) {
+	lock, err := storage.AcquireSharedLock(s.jsonPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+	return s.store.Load()
+}
+
+func (s tuiStore) Add(text string) (tasks.Task, error) {
+	var added tasks.Task
+	err := mutate(s.globals, s.jsonPath, s.store, "add", func(list []tasks.Task) ([]tasks.Task, string, error) {
+		created := now()
+		list = tasks.AddTask(list, tasks.Task{Text: text, CreatedAt: &created})
+		added = list[len(list)-1]
+		return list, fmt.Sprintf("added #%d %s", added.ID, added.Text), nil
+	})
+	return added, err
+}
+
+func (s tuiStore) Edit(id int, text string) error {
+	return mutate(s.globals, s.jsonPath, s.store, "edit", func(list []tasks.Task) ([]tasks.Task, string, error) {
+		list, err := tasks.Edit(list, id, text)
+		return list, fmt.Sprintf("edited #%d %s", id, text), err
+	})
+}
+
+func (s tuiStore) SetDone(id int, done bool) error {
+	op := "done"
+	if !done {
+		op = "undone"
+	}
+	return mutate(s.globals, s.jsonPath, s.store, op, func(list []tasks.Task) ([]tasks.Task, string, error) {
+		current := now()
+		if _, err := (tasks.Selection{IDs: []int{id}}).Resolve(list, current); err != nil {
+			return nil, "", err
+		}
+		list, changed := tasks.SetDone(list, []int{id}, done, current)
+		if done {
+			if err := tasks.CheckChildrenDone(list, changed); err != nil {
+				return nil, "", err
+			}
+			list, _ = tasks.Recur(list, changed, current)
+		}
+		return list, fmt.Sprintf("%s #%d", pastTense(op), id), nil
+	})
+}
+
+func (s tuiStore) Remove(id int) error {
+	return mutate(s.globals, s.jsonPath, s.store, "rm", func(list []tasks.Task) ([]tasks.Task, string, error) {
+		if _, err := (tasks.Selection{IDs: []int{id}}).Resolve(list, now()); err != nil {
+			return nil, "", err
+		}
+		return tasks.RemoveAll(list, []int{id}), fmt.Sprintf("removed #%d", id), nil
+	})
+}
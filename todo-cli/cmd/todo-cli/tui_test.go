@@ -0,0 +1,54 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/ui"
+)
+
+func TestTUIChangesArePersistedAndUndoable(t *testing.T) {
+	t.Setenv("TODO_CLI_PATH", filepath.Join(t.TempDir(), "tasks.json"))
+
+	var keys []ui.Key
+	keys = append(keys, ui.Type("aBuy milk")...)
+	keys = append(keys, ui.Key{Code: ui.KeyEnter})
+	keys = append(keys, ui.Type("aCall mum")...)
+	keys = append(keys, ui.Key{Code: ui.KeyEnter})
+	keys = append(keys, ui.Type("jxq")...)
+	screen := &ui.FakeScreen{Width: 80, Height: 12, Keys: keys}
+
+	original := openScreen
+	openScreen = func() (ui.Screen, error) { return screen, nil }
+	defer func() { openScreen = original }()
+
+	if _, stderr, exit := runMenuHarness(t, []string{"tui"}, ""); exit != 0 {
+		t.Fatalf("tui exited %d: %s", exit, stderr)
+	}
+	if !screen.Closed {
+		t.Fatal("expected the screen to be closed")
+	}
+
+	out, _, _ := runMenuHarness(t, []string{"list", "--reverse"}, "")
+	requireContainsInOrder(t, out, []string{"[x] #1 Buy milk", "[ ] #2 Call mum"})
+
+	if _, stderr, exit := runMenuHarness(t, []string{"undo"}, ""); exit != 0 {
+		t.Fatalf("undo exited %d: %s", exit, stderr)
+	}
+	out, _, _ = runMenuHarness(t, []string{"list", "--reverse"}, "")
+	requireContainsInOrder(t, out, []string{"[ ] #1 Buy milk", "[ ] #2 Call mum"})
+}
+
+func TestTUIRequiresATerminal(t *testing.T) {
+	t.Setenv("TODO_CLI_PATH", filepath.Join(t.TempDir(), "tasks.json"))
+
+	original := openScreen
+	openScreen = func() (ui.Screen, error) { return nil, ui.ErrNotTerminal }
+	defer func() { openScreen = original }()
+
+	_, stderr, exit := runMenuHarness(t, []string{"tui"}, "")
+	if exit != 2 {
+		t.Fatalf("expected exit 2, got %d", exit)
+	}
+	requireContainsAll(t, stderr, []string{"interactive terminal"})
+}
@@ -0,0 +1,322 @@
+// Package config reads and writes the todo-cli settings file, a small JSON
+// document holding defaults and command aliases. Environment variables and
+// flags still take precedence over anything set here.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/render"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+)
+
+// EnvPath names the environment variable that overrides the config file
+// location.
+const EnvPath = "TODO_CLI_CONFIG"
+
+const defaultRel = ".todo-cli/config.json"
+
+// Sort orders accepted by the sort setting.
+const (
+	SortNewest = "newest"
+	SortOldest = "oldest"
+)
+
+// Config is the settings file. Empty fields leave the built-in default in
+// place.
+type Config struct {
+	// Path is the tasks file of the default list; "~/" expands to the home
+	// directory.
+	Path string `json:"path,omitempty"`
+	// List is the list commands use without --list.
+	List string `json:"list,omitempty"`
+	// Sort is the default order of list and ready: newest or oldest first.
+	Sort string `json:"sort,omitempty"`
+	// Format is the default --format of list, ready and search.
+	Format string `json:"format,omitempty"`
+	// Menu starts the interactive menu when todo-cli runs without arguments.
+	Menu bool `json:"menu,omitempty"`
+	// Aliases maps a command name to the arguments it stands for.
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+// Keys are the settings Get, Set and Unset understand besides alias.<name>.
+var Keys = []string{"path", "list", "sort", "format", "menu"}
+
+// Formats are the list formats that can be a default. Templates need
+// --template, so they are left out.
+var Formats = []string{render.FormatText, render.FormatJSON, render.FormatJSONL, render.FormatCSV, render.FormatTSV}
+
+// aliasPrefix starts the keys of command aliases.
+const aliasPrefix = "alias."
+
+// Path returns the config file location: $TODO_CLI_CONFIG or
+// $HOME/.todo-cli/config.json.
+func Path() (string, error) {
+	if p := os.Getenv(EnvPath); p != "" {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, defaultRel), nil
+}
+
+// Load reads the config file at path. A missing file is an empty Config.
+func Load(path string) (Config, error) {
+	var c Config
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Save writes c to path, replacing the file atomically.
+func Save(path string, c Config) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Get returns the value of key and whether it is set.
+func (c Config) Get(key string) (string, bool) {
+	if name, ok := strings.CutPrefix(key, aliasPrefix); ok {
+		v, ok := c.Aliases[name]
+		return v, ok
+	}
+	switch key {
+	case "path":
+		return c.Path, c.Path != ""
+	case "list":
+		return c.List, c.List != ""
+	case "sort":
+		return c.Sort, c.Sort != ""
+	case "format":
+		return c.Format, c.Format != ""
+	case "menu":
+		return strconv.FormatBool(c.Menu), c.Menu
+	}
+	return "", false
+}
+
+// Set validates value and stores it under key.
+func (c *Config) Set(key, value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fmt.Errorf("empty value for %s (use unset to remove it)", key)
+	}
+	if name, ok := strings.CutPrefix(key, aliasPrefix); ok {
+		if err := checkAliasName(name); err != nil {
+			return err
+		}
+		if _, err := SplitArgs(value); err != nil {
+			return err
+		}
+		if c.Aliases == nil {
+			c.Aliases = make(map[string]string)
+		}
+		c.Aliases[name] = value
+		return nil
+	}
+	switch key {
+	case "path":
+		c.Path = value
+	case "list":
+		if value != storage.DefaultListName {
+			if err := storage.CheckListName(value); err != nil {
+				return err
+			}
+		}
+		c.List = value
+	case "sort":
+		if value != SortNewest && value != SortOldest {
+			return fmt.Errorf("invalid sort %q (want %s or %s)", value, SortNewest, SortOldest)
+		}
+		c.Sort = value
+	case "format":
+		if err := render.CheckFormat(value, Formats); err != nil {
+			return err
+		}
+		c.Format = value
+	case "menu":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid menu value %q (want true or false)", value)
+		}
+		c.Menu = b
+	default:
+		return unknownKey(key)
+	}
+	return nil
+}
+
+// Unset removes key.
+func (c *Config) Unset(key string) error {
+	if name, ok := strings.CutPrefix(key, aliasPrefix); ok {
+		if _, ok := c.Aliases[name]; !ok {
+			return fmt.Errorf("no alias %q", name)
+		}
+		delete(c.Aliases, name)
+		if len(c.Aliases) == 0 {
+			c.Aliases = nil
+		}
+		return nil
+	}
+	switch key {
+	case "path":
+		c.Path = ""
+	case "list":
+		c.List = ""
+	case "sort":
+		c.Sort = ""
+	case "format":
+		c.Format = ""
+	case "menu":
+		c.Menu = false
+	default:
+		return unknownKey(key)
+	}
+	return nil
+}
+
+// SetKeys returns the keys that hold a value: settings first, then aliases
+// in name order.
+func (c Config) SetKeys() []string {
+	var out []string
+	for _, k := range Keys {
+		if _, ok := c.Get(k); ok {
+			out = append(out, k)
+		}
+	}
+	names := make([]string, 0, len(c.Aliases))
+	for name := range c.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out = append(out, aliasPrefix+name)
+	}
+	return out
+}
+
+// Alias returns the arguments name expands to.
+func (c Config) Alias(name string) ([]string, bool) {
+	v, ok := c.Aliases[name]
+	if !ok {
+		return nil, false
+	}
+	args, err := SplitArgs(v)
+	if err != nil || len(args) == 0 {
+		return nil, false
+	}
+	return args, true
+}
+
+// ExpandPath replaces a leading "~/" in p with the home directory.
+func ExpandPath(p string) (string, error) {
+	rest, ok := strings.CutPrefix(p, "~/")
+	if !ok {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, rest), nil
+}
+
+func unknownKey(key string) error {
+	return fmt.Errorf("unknown config key %q (want %s or alias.<name>)", key, strings.Join(Keys, ", "))
+}
+
+func checkAliasName(name string) error {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, " \t\n\"'") {
+		return fmt.Errorf("invalid alias name %q", name)
+	}
+	return nil
+}
+
+// SplitArgs splits s into words like a shell would for simple cases:
+// whitespace separates words, and single or double quotes group them.
+func SplitArgs(s string) ([]string, error) {
+	var out []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				out = append(out, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inWord {
+		out = append(out, word.String())
+	}
+	return out, nil
+}
+
+// JoinArgs is the inverse of SplitArgs: it joins args into one string,
+// quoting those that are empty or contain whitespace or quotes.
+func JoinArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = quoteArg(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func quoteArg(a string) string {
+	switch {
+	case a != "" && !strings.ContainsAny(a, " \t\n\"'"):
+		return a
+	case !strings.Contains(a, `"`):
+		return `"` + a + `"`
+	case !strings.Contains(a, "'"):
+		return "'" + a + "'"
+	}
+	// Both kinds of quotes: single-quote each double quote. SplitArgs joins
+	// adjacent quoted parts into one word.
+	return `"` + strings.ReplaceAll(a, `"`, `"'"'"`) + `"`
+}
@@ -0,0 +1,94 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/config"
+)
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "config.json")
+	c, err := config.Load(path)
+	if err != nil || !reflect.DeepEqual(c, config.Config{}) {
+		t.Fatalf("missing file should load as empty config, got %+v (%v)", c, err)
+	}
+	for key, value := range map[string]string{
+		"path": "~/todo/tasks.json", "list": "work", "sort": "oldest",
+		"format": "json", "menu": "true", "alias.t": "add --tag today",
+	} {
+		if err := c.Set(key, value); err != nil {
+			t.Fatalf("Set(%s): %v", key, err)
+		}
+	}
+	if err := config.Save(path, c); err != nil {
+		t.Fatal(err)
+	}
+	got, err := config.Load(path)
+	if err != nil || !reflect.DeepEqual(got, c) {
+		t.Fatalf("round trip: %+v (%v), want %+v", got, err, c)
+	}
+	want := []string{"path", "list", "sort", "format", "menu", "alias.t"}
+	if keys := got.SetKeys(); !reflect.DeepEqual(keys, want) {
+		t.Fatalf("SetKeys = %v", keys)
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Load(path); err == nil {
+		t.Fatal("expected an error for a corrupt config file")
+	}
+}
+
+func TestSetValidatesAndUnsetClears(t *testing.T) {
+	var c config.Config
+	for key, value := range map[string]string{
+		"sort": "random", "format": "template", "menu": "maybe",
+		"list": "../etc", "alias.-x": "list", "alias.y": `add "open`, "nope": "1",
+	} {
+		if err := c.Set(key, value); err == nil {
+			t.Fatalf("Set(%s, %q) should fail", key, value)
+		}
+	}
+	if err := c.Set("alias.t", `add --tag today "two words"`); err != nil {
+		t.Fatal(err)
+	}
+	args, ok := c.Alias("t")
+	if !ok || !reflect.DeepEqual(args, []string{"add", "--tag", "today", "two words"}) {
+		t.Fatalf("Alias = %q, %v", args, ok)
+	}
+	if err := c.Unset("alias.t"); err != nil || c.Aliases != nil {
+		t.Fatalf("Unset left %v (%v)", c.Aliases, err)
+	}
+	if err := c.Unset("nope"); err == nil {
+		t.Fatal("unknown key should fail")
+	}
+}
+
+func TestJoinArgsRoundTrips(t *testing.T) {
+	args := []string{"add", "--tag", "two words", "", `say "hi"`, "it's", `both " and '`}
+	got, err := config.SplitArgs(config.JoinArgs(args))
+	if err != nil || !reflect.DeepEqual(got, args) {
+		t.Fatalf("SplitArgs(JoinArgs) = %q (%v), want %q", got, err, args)
+	}
+	if s := config.JoinArgs([]string{"list", "--tag", "today"}); s != "list --tag today" {
+		t.Fatalf("plain words should not be quoted, got %q", s)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	got, err := config.ExpandPath("~/todo/tasks.json")
+	if err != nil || got != filepath.Join(home, "todo", "tasks.json") {
+		t.Fatalf("ExpandPath = %q (%v)", got, err)
+	}
+	if got, _ := config.ExpandPath("/tmp/tasks.json"); got != "/tmp/tasks.json" {
+		t.Fatalf("absolute path changed to %q", got)
+	}
+}
@@ -0,0 +1,408 @@
+// Package gitsync keeps the storage directory in a git repository so task
+// lists can be shared between machines. Every change is committed locally;
+// Sync exchanges commits with the remote and merges diverged task files task
+// by task instead of line by line.
+package gitsync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// Remote is the name Init gives the remote repository.
+const Remote = "origin"
+
+// ErrNoRemote is returned by Sync when the repository has no remote.
+var ErrNoRemote = errors.New("no remote configured (use sync init <url>)")
+
+// ignore keeps per-machine files out of the repository: locks, temporary
+// files, the undo journal, schema backups and the config file.
+const ignore = `*.lock
+*.tmp
+*.journal
+*.bak
+*.bak.*
+config.json
+`
+
+// Repo is the git repository at the root of a storage directory.
+type Repo struct {
+	Dir string
+	// base is the file name of the default list in Dir.
+	base string
+}
+
+// Open returns the repository holding base, the default tasks file, if its
+// directory is the root of one.
+func Open(base string) (*Repo, bool) {
+	dir := filepath.Dir(base)
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return nil, false
+	}
+	return &Repo{Dir: dir, base: filepath.Base(base)}, true
+}
+
+// Init turns the directory of base, the default tasks file, into a repository
+// with the current files committed and, if url is not empty, sets it as the
+// remote. Running it again on a repository only updates the remote.
+func Init(base, url string) (*Repo, error) {
+	dir := filepath.Dir(base)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	r, ok := Open(base)
+	if !ok {
+		r = &Repo{Dir: dir, base: filepath.Base(base)}
+		if _, err := r.git("init", "--quiet"); err != nil {
+			return nil, err
+		}
+		if _, err := r.git("symbolic-ref", "HEAD", "refs/heads/main"); err != nil {
+			return nil, err
+		}
+		gi := filepath.Join(dir, ".gitignore")
+		if _, err := os.Stat(gi); os.IsNotExist(err) {
+			if err := os.WriteFile(gi, []byte(ignore), 0o644); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := r.Commit("todo-cli: start sync"); err != nil {
+			return nil, err
+		}
+	}
+	if url == "" {
+		return r, nil
+	}
+	if _, err := r.git("remote", "get-url", Remote); err == nil {
+		_, err = r.git("remote", "set-url", Remote, url)
+		return r, err
+	}
+	_, err := r.git("remote", "add", Remote, url)
+	return r, err
+}
+
+// Commit records every change in the directory. It reports whether there
+// was anything to commit.
+func (r *Repo) Commit(message string) (bool, error) {
+	if _, err := r.git("add", "--all"); err != nil {
+		return false, err
+	}
+	if _, err := r.git("diff", "--cached", "--quiet"); err == nil {
+		return false, nil
+	}
+	_, err := r.git(r.identity("commit", "--quiet", "--no-verify", "-m", message)...)
+	return err == nil, err
+}
+
+// Result describes what Sync did.
+type Result struct {
+	// Pulled and Pushed report whether commits came in or went out.
+	Pulled, Pushed bool
+	// Merged lists the task files merged task by task.
+	Merged     []string
+	Conflicts  []Conflict
+	Renumbered []Renumbered
+	// Dropped lists other files changed differently on both sides; the
+	// local version is kept and the remote change is lost.
+	Dropped []string
+}
+
+// Sync commits pending changes, fetches the remote and brings both sides
+// together: a fast-forward when only one side moved, otherwise a merge
+// commit whose task files are merged by Merge. The result is pushed.
+// The caller must hold the locks of every list in the directory.
+func (r *Repo) Sync() (Result, error) {
+	var res Result
+	if _, err := r.git("remote", "get-url", Remote); err != nil {
+		return res, ErrNoRemote
+	}
+	if _, err := r.Commit("todo-cli: sync"); err != nil {
+		return res, err
+	}
+	branch, err := r.git("symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return res, err
+	}
+	if !r.has("HEAD") {
+		// Nothing committed yet: start from an empty commit so there is
+		// something to merge into.
+		if _, err := r.git(r.identity("commit", "--quiet", "--allow-empty", "-m", "todo-cli: start sync")...); err != nil {
+			return res, err
+		}
+	}
+	if _, err := r.git("fetch", "--quiet", Remote); err != nil {
+		return res, err
+	}
+	upstream := "refs/remotes/" + Remote + "/" + branch
+	if !r.has(upstream) {
+		return res, r.push(branch, &res)
+	}
+	local, _ := r.git("rev-parse", "HEAD")
+	remote, _ := r.git("rev-parse", upstream)
+	switch {
+	case local == remote:
+		return res, nil
+	case r.ancestor(remote, local):
+		return res, r.push(branch, &res)
+	case r.ancestor(local, remote):
+		res.Pulled = true
+		_, err := r.git("merge", "--quiet", "--ff-only", upstream)
+		return res, err
+	}
+
+	res.Pulled = true
+	if err := r.merge(upstream, &res); err != nil {
+		return res, err
+	}
+	return res, r.push(branch, &res)
+}
+
+// merge creates a merge commit with upstream. Git records the history only
+// (-s ours); the task files are then replaced by the task-level merge of
+// both sides, and other files changed only remotely are taken from upstream.
+func (r *Repo) merge(upstream string, res *Result) error {
+	base, _ := r.git("merge-base", "HEAD", upstream)
+	files, err := r.taskFiles("HEAD", upstream)
+	if err != nil {
+		return err
+	}
+	others, err := r.otherChanges(base, upstream, res)
+	if err != nil {
+		return err
+	}
+	type result struct {
+		file string
+		list []tasks.Task
+	}
+	var merged []result
+	for _, f := range files {
+		b, err := r.load(base, f)
+		if err != nil {
+			return err
+		}
+		o, err := r.load("HEAD", f)
+		if err != nil {
+			return err
+		}
+		t, err := r.load(upstream, f)
+		if err != nil {
+			return err
+		}
+		list, conflicts, renumbered, err := Merge(f, b, o, t)
+		if err != nil {
+			return err
+		}
+		merged = append(merged, result{f, list})
+		res.Merged = append(res.Merged, f)
+		res.Conflicts = append(res.Conflicts, conflicts...)
+		res.Renumbered = append(res.Renumbered, renumbered...)
+	}
+
+	if _, err := r.git(r.identity("merge", "--quiet", "--no-ff", "--no-commit", "--allow-unrelated-histories", "-s", "ours", upstream)...); err != nil {
+		return err
+	}
+	for _, f := range others {
+		args := []string{"checkout", "--quiet", upstream, "--", f.name}
+		if !f.exists {
+			args = []string{"rm", "--quiet", "--", f.name}
+		}
+		if _, err := r.git(args...); err != nil {
+			r.git("merge", "--abort")
+			return err
+		}
+	}
+	for _, m := range merged {
+		if err := storage.SaveTasks(filepath.Join(r.Dir, filepath.FromSlash(m.file)), m.list); err != nil {
+			r.git("merge", "--abort")
+			return err
+		}
+	}
+	msg := "todo-cli: merge " + upstream
+	if len(res.Conflicts) > 0 {
+		msg += fmt.Sprintf(" (%d conflicts)", len(res.Conflicts))
+	}
+	if _, err := r.git("add", "--all"); err != nil {
+		r.git("merge", "--abort")
+		return err
+	}
+	if _, err := r.git(r.identity("commit", "--quiet", "--no-verify", "-m", msg)...); err != nil {
+		r.git("merge", "--abort")
+		return err
+	}
+	return nil
+}
+
+// taskFiles lists the task lists tracked in any of revs.
+func (r *Repo) taskFiles(revs ...string) ([]string, error) {
+	seen := make(map[string]bool)
+	var out []string
+	for _, rev := range revs {
+		names, err := r.git("ls-tree", "-r", "--name-only", rev)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range strings.Split(names, "\n") {
+			if r.isTaskFile(name) && !seen[name] {
+				seen[name] = true
+				out = append(out, name)
+			}
+		}
+	}
+	return out, nil
+}
+
+// isTaskFile reports whether name, relative to Dir, is one of the lists that
+// storage.ListNames finds: the default list or lists/<name>.json. Other JSON
+// files are merged like any other file.
+func (r *Repo) isTaskFile(name string) bool {
+	if name == r.base {
+		return true
+	}
+	dir, file := path.Split(name)
+	list, ok := strings.CutSuffix(file, ".json")
+	return ok && dir == "lists/" && list != storage.DefaultListName && storage.CheckListName(list) == nil
+}
+
+// otherChange is a file other than a task list that only upstream changed.
+type otherChange struct {
+	name   string
+	exists bool // false when upstream deleted it
+}
+
+// otherChanges compares the files that are not task lists at base, HEAD and
+// upstream. Files only upstream changed are returned to be taken over; files
+// both sides changed differently are added to res.Dropped.
+func (r *Repo) otherChanges(base, upstream string, res *Result) ([]otherChange, error) {
+	trees := make([]map[string]string, 3)
+	for i, rev := range []string{base, "HEAD", upstream} {
+		t, err := r.tree(rev)
+		if err != nil {
+			return nil, err
+		}
+		trees[i] = t
+	}
+	b, o, t := trees[0], trees[1], trees[2]
+	names := make(map[string]bool)
+	for _, tree := range trees {
+		for name := range tree {
+			if !r.isTaskFile(name) {
+				names[name] = true
+			}
+		}
+	}
+	var out []otherChange
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		switch {
+		case t[name] == b[name] || t[name] == o[name]:
+			// Unchanged upstream, or changed the same way.
+		case o[name] == b[name]:
+			_, exists := t[name]
+			out = append(out, otherChange{name, exists})
+		default:
+			res.Dropped = append(res.Dropped, name)
+		}
+	}
+	return out, nil
+}
+
+// tree maps the files tracked at rev to their mode and object. An empty rev
+// is an empty tree.
+func (r *Repo) tree(rev string) (map[string]string, error) {
+	files := make(map[string]string)
+	if rev == "" {
+		return files, nil
+	}
+	out, err := r.gitBytes("ls-tree", "-r", "-z", rev)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range strings.Split(string(out), "\x00") {
+		// "<mode> <type> <object>\t<name>"
+		if meta, name, ok := strings.Cut(entry, "\t"); ok {
+			files[name] = meta
+		}
+	}
+	return files, nil
+}
+
+// load reads file as of rev. A missing revision or file is an empty list.
+func (r *Repo) load(rev, file string) ([]tasks.Task, error) {
+	if rev == "" {
+		return nil, nil
+	}
+	if _, err := r.git("cat-file", "-e", rev+":"+file); err != nil {
+		return nil, nil
+	}
+	b, err := r.gitBytes("show", rev+":"+file)
+	if err != nil {
+		return nil, err
+	}
+	list, err := storage.ParseTasks(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s at %s: %w", file, rev, err)
+	}
+	return list, nil
+}
+
+func (r *Repo) push(branch string, res *Result) error {
+	if _, err := r.git("push", "--quiet", "--set-upstream", Remote, "HEAD:refs/heads/"+branch); err != nil {
+		return err
+	}
+	res.Pushed = true
+	return nil
+}
+
+// has reports whether rev names a commit.
+func (r *Repo) has(rev string) bool {
+	_, err := r.git("rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	return err == nil
+}
+
+// ancestor reports whether a is an ancestor of b.
+func (r *Repo) ancestor(a, b string) bool {
+	_, err := r.git("merge-base", "--is-ancestor", a, b)
+	return err == nil
+}
+
+// identity prefixes a committing git command with a fallback author for
+// machines where none is configured.
+func (r *Repo) identity(args ...string) []string {
+	if name, _ := r.git("config", "user.name"); name == "" {
+		args = append([]string{"-c", "user.name=todo-cli"}, args...)
+	}
+	if email, _ := r.git("config", "user.email"); email == "" {
+		args = append([]string{"-c", "user.email=todo-cli@localhost"}, args...)
+	}
+	return append([]string{"-c", "commit.gpgsign=false"}, args...)
+}
+
+// git runs a git command in the repository and returns its trimmed output.
+func (r *Repo) git(args ...string) (string, error) {
+	out, err := r.gitBytes(args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+func (r *Repo) gitBytes(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", r.Dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "LC_ALL=C")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return nil, fmt.Errorf("git %s: %w", args[0], err)
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return stdout.Bytes(), nil
+}
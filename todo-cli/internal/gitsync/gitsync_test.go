@@ -0,0 +1,285 @@
+package gitsync_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/gitsync"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+func describe(list []tasks.Task) string {
+	var lines []string
+	for _, t := range list {
+		s := fmt.Sprintf("#%d %s", t.ID, t.Text)
+		if t.Done {
+			s += " [done]"
+		}
+		if t.Priority != tasks.PriorityNone {
+			s += " !" + t.Priority.String()
+		}
+		if t.Parent != 0 {
+			s += fmt.Sprintf(" ^%d", t.Parent)
+		}
+		lines = append(lines, s)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func requireList(t *testing.T, got []tasks.Task, want ...string) {
+	t.Helper()
+	if describe(got) != strings.Join(want, "\n") {
+		t.Fatalf("list:\n%s\nwant:\n%s", describe(got), strings.Join(want, "\n"))
+	}
+}
+
+func requireStrings[T interface{ String() string }](t *testing.T, got []T, want ...string) {
+	t.Helper()
+	var lines []string
+	for _, v := range got {
+		lines = append(lines, v.String())
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestMergeCombinesChangesByTask(t *testing.T) {
+	base := []tasks.Task{
+		{ID: 1, Text: "Buy milk"},
+		{ID: 2, Text: "Call mum"},
+		{ID: 3, Text: "Pay rent"},
+		{ID: 4, Text: "Water plants"},
+		{ID: 5, Text: "Old idea"},
+	}
+	ours := []tasks.Task{
+		{ID: 1, Text: "Buy oat milk"},
+		{ID: 2, Text: "Call mum", Done: true},
+		{ID: 3, Text: "Pay rent", Priority: tasks.PriorityHigh},
+		{ID: 5, Text: "Old idea, refined"},
+		{ID: 6, Text: "Local task"},
+		{ID: 7, Text: "Same on both"},
+	}
+	theirs := []tasks.Task{
+		{ID: 1, Text: "Buy milk", Priority: tasks.PriorityLow},
+		{ID: 2, Text: "Call mum"},
+		{ID: 3, Text: "Pay rent", Priority: tasks.PriorityLow},
+		{ID: 4, Text: "Water plants"},
+		{ID: 6, Text: "Remote task"},
+		{ID: 7, Text: "Same on both"},
+		{ID: 8, Text: "Remote subtask", Parent: 6},
+	}
+
+	got, conflicts, renumbered, err := gitsync.Merge("tasks.json", base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireList(t, got,
+		"#1 Buy oat milk !low",
+		"#2 Call mum [done]",
+		"#3 Pay rent !high",
+		"#5 Old idea, refined",
+		"#6 Local task",
+		"#7 Same on both",
+		"#8 Remote subtask ^9",
+		"#9 Remote task",
+	)
+	requireStrings(t, conflicts,
+		`tasks.json #3 priority: kept "high", dropped "low"`,
+		"tasks.json #5: deleted remotely but changed here; kept it",
+	)
+	requireStrings(t, renumbered, `tasks.json #6 "Remote task" is now #9`)
+}
+
+func TestMergeUnlinksDeletedParents(t *testing.T) {
+	base := []tasks.Task{{ID: 1, Text: "Project"}}
+	ours := []tasks.Task{{ID: 1, Text: "Project"}, {ID: 2, Text: "Step", Parent: 1}}
+	var theirs []tasks.Task
+
+	got, conflicts, _, err := gitsync.Merge("lists/work.json", base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireList(t, got, "#2 Step")
+	requireStrings(t, conflicts, "lists/work.json #2 parent: parent #1 was deleted; unlinked")
+}
+
+func TestMergeUnlinksRemoteLinksThatCloseACycle(t *testing.T) {
+	base := []tasks.Task{{ID: 1, Text: "Design"}, {ID: 2, Text: "Build"}, {ID: 3, Text: "Epic"}, {ID: 4, Text: "Story"}}
+	ours := []tasks.Task{{ID: 1, Text: "Design", BlockedBy: []int{2}}, {ID: 2, Text: "Build"}, {ID: 3, Text: "Epic"}, {ID: 4, Text: "Story", Parent: 3}}
+	theirs := []tasks.Task{{ID: 1, Text: "Design"}, {ID: 2, Text: "Build", BlockedBy: []int{1}}, {ID: 3, Text: "Epic", Parent: 4}, {ID: 4, Text: "Story"}}
+
+	got, conflicts, _, err := gitsync.Merge("tasks.json", base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireList(t, got, "#1 Design", "#2 Build", "#3 Epic", "#4 Story ^3")
+	if !slices.Equal(got[0].BlockedBy, []int{2}) || len(got[1].BlockedBy) != 0 {
+		t.Fatalf("expected the local blocker to stay and the remote one to go: %v, %v", got[0].BlockedBy, got[1].BlockedBy)
+	}
+	requireStrings(t, conflicts,
+		"tasks.json #2 blocked_by: blocker #1 would close a dependency cycle; unlinked",
+		"tasks.json #3 parent: parent #4 would close a dependency cycle; unlinked",
+	)
+}
+
+// machine is one clone of the shared task repository.
+type machine struct {
+	t    *testing.T
+	repo *gitsync.Repo
+	path string
+}
+
+func newMachine(t *testing.T, remote string) *machine {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	repo, err := gitsync.Init(path, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &machine{t: t, repo: repo, path: path}
+}
+
+func (m *machine) save(list ...tasks.Task) {
+	m.t.Helper()
+	if err := storage.SaveTasks(m.path, list); err != nil {
+		m.t.Fatal(err)
+	}
+	if _, err := m.repo.Commit("test"); err != nil {
+		m.t.Fatal(err)
+	}
+}
+
+func (m *machine) load() []tasks.Task {
+	m.t.Helper()
+	list, err := storage.LoadTasks(m.path)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return list
+}
+
+func (m *machine) sync() gitsync.Result {
+	m.t.Helper()
+	res, err := m.repo.Sync()
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return res
+}
+
+func bareRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := filepath.Join(t.TempDir(), "tasks.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare: %v: %s", err, out)
+	}
+	return dir
+}
+
+func TestSyncThroughABareRepository(t *testing.T) {
+	remote := bareRepo(t)
+	laptop, desktop := newMachine(t, remote), newMachine(t, remote)
+
+	laptop.save(tasks.Task{ID: 1, Text: "Buy milk"}, tasks.Task{ID: 2, Text: "Call mum"})
+	if res := laptop.sync(); !res.Pushed || res.Pulled {
+		t.Fatalf("first sync = %+v", res)
+	}
+	if res := desktop.sync(); !res.Pulled {
+		t.Fatalf("desktop sync = %+v", res)
+	}
+	requireList(t, desktop.load(), "#1 Buy milk", "#2 Call mum")
+
+	// Both machines change the list before syncing again.
+	laptop.save(tasks.Task{ID: 1, Text: "Buy milk", Done: true}, tasks.Task{ID: 2, Text: "Call mum"}, tasks.Task{ID: 3, Text: "Laptop task"})
+	desktop.save(tasks.Task{ID: 1, Text: "Buy milk"}, tasks.Task{ID: 2, Text: "Call mum tonight"}, tasks.Task{ID: 3, Text: "Desktop task"})
+	laptop.sync()
+	res := desktop.sync()
+	if !res.Pulled || !res.Pushed || len(res.Merged) != 1 {
+		t.Fatalf("merge sync = %+v", res)
+	}
+	requireStrings(t, res.Renumbered, `tasks.json #3 "Laptop task" is now #4`)
+	want := []string{"#1 Buy milk [done]", "#2 Call mum tonight", "#3 Desktop task", "#4 Laptop task"}
+	requireList(t, desktop.load(), want...)
+
+	laptop.sync()
+	requireList(t, laptop.load(), want...)
+	if res := laptop.sync(); res.Pulled || res.Pushed {
+		t.Fatalf("sync without changes = %+v", res)
+	}
+}
+
+func TestSyncMergeKeepsOtherFiles(t *testing.T) {
+	remote := bareRepo(t)
+	laptop, desktop := newMachine(t, remote), newMachine(t, remote)
+	write := func(m *machine, name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(m.repo.Dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(m *machine, name string) string {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(m.repo.Dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	write(laptop, "notes.md", "shared\n")
+	laptop.save(tasks.Task{ID: 1, Text: "Buy milk"})
+	laptop.sync()
+	desktop.sync()
+
+	// The laptop changes the ignore rules and the notes, the desktop only
+	// the notes; both change the list, so the sync merges.
+	write(laptop, ".gitignore", read(laptop, ".gitignore")+"*.swp\n")
+	write(laptop, "notes.md", "from the laptop\n")
+	write(laptop, "settings.json", `{"theme": "dark"}`)
+	laptop.save(tasks.Task{ID: 1, Text: "Buy milk", Done: true})
+	laptop.sync()
+	write(desktop, "notes.md", "from the desktop\n")
+	desktop.save(tasks.Task{ID: 1, Text: "Buy milk"}, tasks.Task{ID: 2, Text: "Call mum"})
+	res := desktop.sync()
+
+	requireList(t, desktop.load(), "#1 Buy milk [done]", "#2 Call mum")
+	if !strings.Contains(read(desktop, ".gitignore"), "*.swp") {
+		t.Fatalf("remote .gitignore change lost: %q", read(desktop, ".gitignore"))
+	}
+	// JSON files other than the lists are not task lists.
+	if got := read(desktop, "settings.json"); got != `{"theme": "dark"}` || strings.Join(res.Merged, ",") != "tasks.json" {
+		t.Fatalf("settings.json = %q, merged = %v", got, res.Merged)
+	}
+	if got := read(desktop, "notes.md"); got != "from the desktop\n" {
+		t.Fatalf("notes.md = %q, want the local version", got)
+	}
+	if strings.Join(res.Dropped, ",") != "notes.md" {
+		t.Fatalf("dropped = %v, want notes.md", res.Dropped)
+	}
+}
+
+func TestSyncWithoutRemote(t *testing.T) {
+	bareRepo(t) // skips without git
+	dir := t.TempDir()
+	repo, err := gitsync.Init(filepath.Join(dir, "tasks.json"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Sync(); err != gitsync.ErrNoRemote {
+		t.Fatalf("err = %v", err)
+	}
+	ignore, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil || !strings.Contains(string(ignore), "*.journal") {
+		t.Fatalf(".gitignore = %q, %v", ignore, err)
+	}
+}
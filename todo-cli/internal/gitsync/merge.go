@@ -0,0 +1,324 @@
+package gitsync
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// Conflict is a change made on both sides that could not be combined. The
+// merge keeps one version and reports the other.
+type Conflict struct {
+	File string
+	ID   int
+	// Field is the JSON name of the field both sides changed, or empty when
+	// one side deleted a task the other changed.
+	Field  string
+	Kept   string
+	Lost   string
+	Reason string
+}
+
+func (c Conflict) String() string {
+	s := fmt.Sprintf("%s #%d", c.File, c.ID)
+	if c.Field != "" {
+		s += " " + c.Field
+	}
+	if c.Reason != "" {
+		return s + ": " + c.Reason
+	}
+	return fmt.Sprintf("%s: kept %s, dropped %s", s, c.Kept, c.Lost)
+}
+
+// Renumbered is a task added remotely under an ID that was also used by a
+// different local task, and so given a new ID.
+type Renumbered struct {
+	File     string
+	From, To int
+	Text     string
+}
+
+func (r Renumbered) String() string {
+	return fmt.Sprintf("%s #%d %q is now #%d", r.File, r.From, r.Text, r.To)
+}
+
+// Merge combines two versions of a task list that both descend from base,
+// matching tasks by ID. Fields changed on one side only are taken from that
+// side; a field changed differently on both sides keeps ours and is
+// reported. A task deleted on one side and changed on the other is kept. A
+// task added on both sides under the same ID keeps its ID locally and the
+// remote one is renumbered. Links left pointing at deleted tasks are dropped,
+// and so are remote links that close a dependency cycle with local ones.
+func Merge(file string, base, ours, theirs []tasks.Task) ([]tasks.Task, []Conflict, []Renumbered, error) {
+	baseByID, oursByID := byID(base), byID(ours)
+	theirs, renumbered := renumber(file, baseByID, oursByID, theirs, maxID(base, ours, theirs))
+	theirsByID := byID(theirs)
+
+	ids := make(map[int]bool)
+	for _, list := range [][]tasks.Task{base, ours, theirs} {
+		for _, t := range list {
+			ids[t.ID] = true
+		}
+	}
+	order := make([]int, 0, len(ids))
+	for id := range ids {
+		order = append(order, id)
+	}
+	sort.Ints(order)
+
+	var merged []tasks.Task
+	var conflicts []Conflict
+	for _, id := range order {
+		b, inBase := baseByID[id]
+		o, inOurs := oursByID[id]
+		t, inTheirs := theirsByID[id]
+		switch {
+		case inOurs && inTheirs:
+			if !inBase {
+				// Added on both sides with the same content.
+				merged = append(merged, o)
+				continue
+			}
+			m, cs, err := mergeTask(file, b, o, t)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			merged = append(merged, m)
+			conflicts = append(conflicts, cs...)
+		case inOurs:
+			if !inBase {
+				merged = append(merged, o)
+			} else if !sameTask(b, o) {
+				merged = append(merged, o)
+				conflicts = append(conflicts, Conflict{File: file, ID: id, Reason: "deleted remotely but changed here; kept it"})
+			}
+		case inTheirs:
+			if !inBase {
+				merged = append(merged, t)
+			} else if !sameTask(b, t) {
+				merged = append(merged, t)
+				conflicts = append(conflicts, Conflict{File: file, ID: id, Reason: "deleted here but changed remotely; kept it"})
+			}
+		}
+	}
+	conflicts = append(conflicts, dropDangling(file, merged)...)
+	cycles, err := breakCycles(file, oursByID, merged)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: merged list is invalid: %w", file, err)
+	}
+	conflicts = append(conflicts, cycles...)
+	return merged, conflicts, renumbered, nil
+}
+
+// renumber gives every task added remotely under an ID that a different
+// local addition also uses a fresh ID above max, rewriting the remote
+// links to it.
+func renumber(file string, base, ours map[int]tasks.Task, theirs []tasks.Task, max int) ([]tasks.Task, []Renumbered) {
+	moves := make(map[int]int)
+	var out []Renumbered
+	for _, t := range theirs {
+		o, clash := ours[t.ID]
+		if _, inBase := base[t.ID]; inBase || !clash || sameTask(o, t) {
+			continue
+		}
+		max++
+		moves[t.ID] = max
+		out = append(out, Renumbered{File: file, From: t.ID, To: max, Text: t.Text})
+	}
+	if len(moves) == 0 {
+		return theirs, nil
+	}
+	theirs = tasks.CloneList(theirs)
+	move := func(id int) int {
+		if to, ok := moves[id]; ok {
+			return to
+		}
+		return id
+	}
+	for i := range theirs {
+		t := &theirs[i]
+		t.ID = move(t.ID)
+		t.Parent = move(t.Parent)
+		for j, b := range t.BlockedBy {
+			t.BlockedBy[j] = move(b)
+		}
+	}
+	return theirs, out
+}
+
+// mergeTask merges one task field by field through its JSON form, so new
+// fields take part without changes here.
+func mergeTask(file string, base, ours, theirs tasks.Task) (tasks.Task, []Conflict, error) {
+	b, err := fields(base)
+	if err != nil {
+		return tasks.Task{}, nil, err
+	}
+	o, err := fields(ours)
+	if err != nil {
+		return tasks.Task{}, nil, err
+	}
+	t, err := fields(theirs)
+	if err != nil {
+		return tasks.Task{}, nil, err
+	}
+	names := make(map[string]bool)
+	for _, m := range []map[string]json.RawMessage{b, o, t} {
+		for k := range m {
+			names[k] = true
+		}
+	}
+	keys := make([]string, 0, len(names))
+	for k := range names {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	merged := make(map[string]json.RawMessage, len(keys))
+	var conflicts []Conflict
+	for _, k := range keys {
+		bv, ov, tv := b[k], o[k], t[k]
+		v := ov
+		switch {
+		case bytes.Equal(ov, tv), bytes.Equal(tv, bv):
+		case bytes.Equal(ov, bv):
+			v = tv
+		default:
+			conflicts = append(conflicts, Conflict{File: file, ID: ours.ID, Field: k, Kept: show(ov), Lost: show(tv)})
+		}
+		if v != nil {
+			merged[k] = v
+		}
+	}
+	raw, err := json.Marshal(merged)
+	if err != nil {
+		return tasks.Task{}, nil, err
+	}
+	var out tasks.Task
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return tasks.Task{}, nil, err
+	}
+	return out, conflicts, nil
+}
+
+func fields(t tasks.Task) (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]json.RawMessage
+	err = json.Unmarshal(raw, &m)
+	return m, err
+}
+
+// show renders a field value for a conflict report; a missing field is
+// "(unset)".
+func show(v json.RawMessage) string {
+	if v == nil {
+		return "(unset)"
+	}
+	return strings.TrimSpace(string(v))
+}
+
+// dropDangling removes parent and blocker links to tasks that are gone,
+// which happens when one side deleted a task the other side linked to.
+func dropDangling(file string, list []tasks.Task) []Conflict {
+	ids := byID(list)
+	var out []Conflict
+	for i := range list {
+		t := &list[i]
+		if _, ok := ids[t.Parent]; t.Parent != 0 && !ok {
+			out = append(out, Conflict{File: file, ID: t.ID, Field: "parent", Reason: fmt.Sprintf("parent #%d was deleted; unlinked", t.Parent)})
+			t.Parent = 0
+		}
+		var kept []int
+		for _, b := range t.BlockedBy {
+			if _, ok := ids[b]; ok {
+				kept = append(kept, b)
+				continue
+			}
+			out = append(out, Conflict{File: file, ID: t.ID, Field: "blocked_by", Reason: fmt.Sprintf("blocker #%d was deleted; unlinked", b)})
+		}
+		t.BlockedBy = kept
+	}
+	return out
+}
+
+// breakCycles unlinks remote links until list has no dependency cycle, which
+// happens when the two sides linked the same tasks in opposite directions
+// (#1 blocked by #2 here, #2 blocked by #1 there). Local links are kept.
+func breakCycles(file string, ours map[int]tasks.Task, list []tasks.Task) ([]Conflict, error) {
+	var out []Conflict
+	for {
+		err := tasks.CheckGraph(list)
+		var cycle *tasks.CycleError
+		if !errors.As(err, &cycle) {
+			return out, err
+		}
+		c, ok := unlinkRemote(file, ours, list, cycle.Path)
+		if !ok {
+			return out, err
+		}
+		out = append(out, c)
+	}
+}
+
+// unlinkRemote removes the first link along path that ours does not have.
+// Each step of path waits for the next: as its blocker or as its subtask.
+func unlinkRemote(file string, ours map[int]tasks.Task, list []tasks.Task, path []int) (Conflict, bool) {
+	at := make(map[int]int, len(list))
+	for i, t := range list {
+		at[t.ID] = i
+	}
+	for i := 0; i+1 < len(path); i++ {
+		from, to := path[i], path[i+1]
+		t := &list[at[from]]
+		if slices.Contains(t.BlockedBy, to) && !slices.Contains(ours[from].BlockedBy, to) {
+			var kept []int
+			for _, b := range t.BlockedBy {
+				if b != to {
+					kept = append(kept, b)
+				}
+			}
+			t.BlockedBy = kept
+			return Conflict{File: file, ID: from, Field: "blocked_by", Reason: fmt.Sprintf("blocker #%d would close a dependency cycle; unlinked", to)}, true
+		}
+		child := &list[at[to]]
+		if child.Parent == from && ours[to].Parent != from {
+			child.Parent = 0
+			return Conflict{File: file, ID: to, Field: "parent", Reason: fmt.Sprintf("parent #%d would close a dependency cycle; unlinked", from)}, true
+		}
+	}
+	return Conflict{}, false
+}
+
+func byID(list []tasks.Task) map[int]tasks.Task {
+	m := make(map[int]tasks.Task, len(list))
+	for _, t := range list {
+		m[t.ID] = t
+	}
+	return m
+}
+
+func maxID(lists ...[]tasks.Task) int {
+	max := 0
+	for _, list := range lists {
+		for _, t := range list {
+			if t.ID > max {
+				max = t.ID
+			}
+		}
+	}
+	return max
+}
+
+func sameTask(a, b tasks.Task) bool {
+	x, errX := json.Marshal(a)
+	y, errY := json.Marshal(b)
+	return errX == nil && errY == nil && bytes.Equal(x, y)
+}
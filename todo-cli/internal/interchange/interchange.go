@@ -0,0 +1,122 @@
+package interchange
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// Format names accepted by import and export.
+const (
+	FormatTodoTxt  = "todotxt"
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+)
+
+// Formats lists every supported format.
+var Formats = []string{FormatTodoTxt, FormatMarkdown, FormatJSON}
+
+// Encode writes list to w in format.
+func Encode(w io.Writer, format string, list []tasks.Task) error {
+	switch format {
+	case FormatTodoTxt:
+		return encodeTodoTxt(w, list)
+	case FormatMarkdown:
+		return encodeMarkdown(w, list)
+	case FormatJSON:
+		return encodeJSON(w, list)
+	}
+	return unknownFormat(format)
+}
+
+// Decode reads tasks in format from r. IDs in the result are only meaningful
+// for JSON input; callers assign fresh IDs when merging into a list.
+func Decode(r io.Reader, format string) ([]tasks.Task, error) {
+	switch format {
+	case FormatTodoTxt:
+		return decodeTodoTxt(r)
+	case FormatMarkdown:
+		return decodeMarkdown(r)
+	case FormatJSON:
+		return decodeJSON(r)
+	}
+	return nil, unknownFormat(format)
+}
+
+// FormatFromPath guesses the format from a file extension: .txt is todo.txt,
+// .md/.markdown is Markdown and .json is JSON.
+func FormatFromPath(path string) (string, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".txt":
+		return FormatTodoTxt, true
+	case ".md", ".markdown":
+		return FormatMarkdown, true
+	case ".json":
+		return FormatJSON, true
+	}
+	return "", false
+}
+
+// ParseFormat normalizes a user-supplied format name.
+func ParseFormat(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "todotxt", "todo.txt", "txt":
+		return FormatTodoTxt, nil
+	case "markdown", "md":
+		return FormatMarkdown, nil
+	case "json":
+		return FormatJSON, nil
+	}
+	return "", unknownFormat(s)
+}
+
+func unknownFormat(format string) error {
+	return fmt.Errorf("unknown format %q (want %s)", format, strings.Join(Formats, ", "))
+}
+
+// Plan describes how an import merges into an existing list.
+type Plan struct {
+	New        []tasks.Task // tasks to add, with IDs assigned
+	Duplicates []tasks.Task // incoming tasks matching an existing or earlier incoming task
+}
+
+// Merge plans adding incoming to existing. An incoming task whose normalized
+// text matches an existing task, or one earlier in the same import, counts as
+// a duplicate and is skipped unless allowDuplicates is set. New tasks get
+// sequential IDs after the existing ones and, when the source had no
+// creation date, are stamped as created at now. Subtask and blocked-by links
+// between imported tasks follow the new IDs.
+func Merge(existing, incoming []tasks.Task, allowDuplicates bool, now time.Time) Plan {
+	seen := make(map[string]bool, len(existing)+len(incoming))
+	for _, t := range existing {
+		seen[dedupeKey(t.Text)] = true
+	}
+	var plan Plan
+	var accepted []tasks.Task
+	for _, t := range incoming {
+		key := dedupeKey(t.Text)
+		if seen[key] && !allowDuplicates {
+			plan.Duplicates = append(plan.Duplicates, t)
+			continue
+		}
+		seen[key] = true
+		t = t.Clone()
+		if t.CreatedAt == nil {
+			created := now
+			t.CreatedAt = &created
+		}
+		accepted = append(accepted, t)
+	}
+	list, _ := tasks.Adopt(tasks.CloneList(existing), accepted)
+	plan.New = list[len(existing):]
+	return plan
+}
+
+// dedupeKey folds case and whitespace so "Buy  milk" matches "buy milk".
+func dedupeKey(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
@@ -0,0 +1,156 @@
+package interchange_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/interchange"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+func day(y int, m time.Month, d int) *time.Time {
+	t := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	return &t
+}
+
+func sample() []tasks.Task {
+	return []tasks.Task{
+		{ID: 1, Text: "Call mom", Tags: []string{"@phone", "family"}, Due: day(2024, time.March, 5), Priority: tasks.PriorityHigh, CreatedAt: day(2024, time.March, 1)},
+		{ID: 2, Text: "Fix #12 crash", Done: true, Priority: tasks.PriorityMedium, CreatedAt: day(2024, time.February, 1), CompletedAt: day(2024, time.March, 2)},
+		{ID: 3, Text: "Water plants"},
+	}
+}
+
+func TestRoundTripEveryFormat(t *testing.T) {
+	for _, format := range interchange.Formats {
+		var buf bytes.Buffer
+		if err := interchange.Encode(&buf, format, sample()); err != nil {
+			t.Fatalf("%s encode: %v", format, err)
+		}
+		got, err := interchange.Decode(&buf, format)
+		if err != nil {
+			t.Fatalf("%s decode: %v", format, err)
+		}
+		if len(got) != 3 {
+			t.Fatalf("%s: expected 3 tasks, got %d", format, len(got))
+		}
+		for i, want := range sample() {
+			g := got[i]
+			if g.Text != want.Text || g.Done != want.Done || g.Priority != want.Priority || !reflect.DeepEqual(g.Tags, want.Tags) {
+				t.Fatalf("%s task %d: got %+v, want %+v", format, i, g, want)
+			}
+			if (g.Due == nil) != (want.Due == nil) || (g.Due != nil && !g.Due.Equal(*want.Due)) {
+				t.Fatalf("%s task %d: due %v, want %v", format, i, g.Due, want.Due)
+			}
+		}
+	}
+}
+
+func TestTodoTxtLayout(t *testing.T) {
+	var buf bytes.Buffer
+	if err := interchange.Encode(&buf, interchange.FormatTodoTxt, sample()); err != nil {
+		t.Fatal(err)
+	}
+	want := "(A) 2024-03-01 Call mom @phone +family due:2024-03-05\n" +
+		"x 2024-03-02 2024-02-01 Fix #12 crash pri:B\n" +
+		"Water plants\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected todo.txt output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	undated := []tasks.Task{{ID: 1, Text: "Old chore", Done: true, CreatedAt: day(2024, time.January, 5)}}
+	if err := interchange.Encode(&buf, interchange.FormatTodoTxt, undated); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "x Old chore\n" {
+		t.Fatalf("expected no dates without a completion date, got %q", buf.String())
+	}
+
+	got, err := interchange.Decode(strings.NewReader("(D) 2024-01-09 Renew passport +errands\n\n"), interchange.FormatTodoTxt)
+	if err != nil || len(got) != 1 {
+		t.Fatalf("decode: %v %+v", err, got)
+	}
+	if got[0].Priority != tasks.PriorityLow || got[0].CreatedAt == nil || got[0].Tags[0] != "errands" {
+		t.Fatalf("unexpected task %+v", got[0])
+	}
+}
+
+func TestDecodeMarkdownIgnoresProse(t *testing.T) {
+	src := "# Notes\n\nSome prose.\n- plain bullet\n  * [X] Ship it #work !low\n- [ ] Fix #12\n"
+	got, err := interchange.Decode(strings.NewReader(src), interchange.FormatMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !got[0].Done || got[0].Text != "Ship it" || got[0].Priority != tasks.PriorityLow || got[1].Text != "Fix #12" {
+		t.Fatalf("unexpected tasks %+v", got)
+	}
+	if _, err := interchange.Decode(strings.NewReader("- [ ] #work !high\n"), interchange.FormatMarkdown); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Fatalf("expected line-numbered error, got %v", err)
+	}
+}
+
+func TestDecodeKeepsUnparsedAttributesAsText(t *testing.T) {
+	got, err := interchange.Decode(strings.NewReader("- [ ] Pay rent due:tomorrow\n- [ ] Call Bob !now #home\n"), interchange.FormatMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Text != "Pay rent due:tomorrow" || got[0].Due != nil ||
+		got[1].Text != "Call Bob !now" || got[1].Priority != tasks.PriorityNone || !reflect.DeepEqual(got[1].Tags, []string{"home"}) {
+		t.Fatalf("unexpected markdown tasks %+v", got)
+	}
+
+	got, err = interchange.Decode(strings.NewReader("Renew passport due:soon +admin\n"), interchange.FormatTodoTxt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Text != "Renew passport due:soon" || got[0].Due != nil {
+		t.Fatalf("unexpected todo.txt tasks %+v", got)
+	}
+}
+
+func TestDecodeJSONAcceptsEnvelope(t *testing.T) {
+	src := `{"version":2,"tasks":[{"id":9,"text":"From backup","done":false}]}`
+	got, err := interchange.Decode(strings.NewReader(src), interchange.FormatJSON)
+	if err != nil || len(got) != 1 || got[0].Text != "From backup" {
+		t.Fatalf("decode envelope: %v %+v", err, got)
+	}
+}
+
+func TestMergeSkipsDuplicates(t *testing.T) {
+	now := time.Date(2024, time.April, 1, 9, 0, 0, 0, time.UTC)
+	existing := []tasks.Task{{ID: 4, Text: "Buy milk"}}
+	incoming := []tasks.Task{
+		{Text: "buy   MILK"},
+		{Text: "Walk dog", Done: true},
+		{Text: "walk dog"},
+	}
+	plan := interchange.Merge(existing, incoming, false, now)
+	if len(plan.New) != 1 || len(plan.Duplicates) != 2 {
+		t.Fatalf("expected 1 new and 2 duplicates, got %+v", plan)
+	}
+	added := plan.New[0]
+	if added.ID != 5 || !added.Done || added.CreatedAt == nil || !added.CreatedAt.Equal(now) {
+		t.Fatalf("unexpected new task %+v", added)
+	}
+
+	plan = interchange.Merge(existing, incoming, true, now)
+	if len(plan.New) != 3 || plan.New[2].ID != 7 {
+		t.Fatalf("allowDuplicates should import everything, got %+v", plan)
+	}
+}
+
+func TestFormatDetection(t *testing.T) {
+	if f, ok := interchange.FormatFromPath("Notes.MD"); !ok || f != interchange.FormatMarkdown {
+		t.Fatalf("got %q %v", f, ok)
+	}
+	if _, ok := interchange.FormatFromPath("tasks.csv"); ok {
+		t.Fatal("csv should not be detected")
+	}
+	if _, err := interchange.ParseFormat("yaml"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
@@ -0,0 +1,41 @@
+package interchange
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+func encodeJSON(w io.Writer, list []tasks.Task) error {
+	if list == nil {
+		list = []tasks.Task{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(list)
+}
+
+// decodeJSON accepts a bare array of tasks (as written by export or
+// `list --format json`) or a whole tasks.json envelope.
+func decodeJSON(r io.Reader) ([]tasks.Task, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil, nil
+	}
+	var list []tasks.Task
+	if b[0] == '[' {
+		err = json.Unmarshal(b, &list)
+		return list, err
+	}
+	var doc struct {
+		Tasks []tasks.Task `json:"tasks"`
+	}
+	err = json.Unmarshal(b, &doc)
+	return doc.Tasks, err
+}
@@ -0,0 +1,122 @@
+package interchange
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// encodeMarkdown writes a GitHub-style checklist. Attributes follow the text
+// as trailing tokens so they survive a round trip, e.g.
+// "- [ ] Renew passport #errands due:2024-05-01 !high".
+func encodeMarkdown(w io.Writer, list []tasks.Task) error {
+	bw := bufio.NewWriter(w)
+	for _, t := range list {
+		box := " "
+		if t.Done {
+			box = "x"
+		}
+		parts := []string{"- [" + box + "]", t.Text}
+		for _, tag := range t.Tags {
+			parts = append(parts, "#"+tag)
+		}
+		if t.Due != nil {
+			parts = append(parts, "due:"+t.Due.Local().Format(tasks.DateLayout))
+		}
+		if t.Priority != tasks.PriorityNone {
+			parts = append(parts, "!"+t.Priority.String())
+		}
+		if _, err := fmt.Fprintln(bw, strings.Join(parts, " ")); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// decodeMarkdown reads checklist items ("- [ ]", "* [x]", "+ [X]", optionally
+// indented) and ignores every other line, so whole notes files can be
+// imported.
+func decodeMarkdown(r io.Reader) ([]tasks.Task, error) {
+	var list []tasks.Task
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		item, done, ok := checklistItem(sc.Text())
+		if !ok {
+			continue
+		}
+		t, err := parseMarkdownItem(item)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		t.Done = done
+		list = append(list, t)
+	}
+	return list, sc.Err()
+}
+
+func checklistItem(line string) (string, bool, bool) {
+	s := strings.TrimSpace(line)
+	if len(s) < 5 || !strings.ContainsRune("-*+", rune(s[0])) || s[1] != ' ' {
+		return "", false, false
+	}
+	s = s[2:]
+	switch {
+	case strings.HasPrefix(s, "[ ]"):
+		return strings.TrimSpace(s[3:]), false, true
+	case strings.HasPrefix(s, "[x]"), strings.HasPrefix(s, "[X]"):
+		return strings.TrimSpace(s[3:]), true, true
+	}
+	return "", false, false
+}
+
+// parseMarkdownItem peels trailing #tag, due: and !priority tokens off the
+// item; hashes inside the text (e.g. "fix #12 crash") stay part of it, and so
+// does everything from the last token that is not a valid attribute
+// (e.g. "call Bob !now").
+func parseMarkdownItem(item string) (tasks.Task, error) {
+	var t tasks.Task
+	fields := strings.Fields(item)
+	end := len(fields)
+peel:
+	for end > 0 {
+		f := fields[end-1]
+		switch {
+		case len(f) > 1 && f[0] == '#' && !isNumber(f[1:]):
+			t.Tags = append([]string{f[1:]}, t.Tags...)
+		case strings.HasPrefix(f, "due:"):
+			d, err := time.ParseInLocation(tasks.DateLayout, f[len("due:"):], time.Local)
+			if err != nil {
+				break peel
+			}
+			t.Due = &d
+		case len(f) > 1 && f[0] == '!':
+			p, err := tasks.ParsePriority(f[1:])
+			if err != nil {
+				break peel
+			}
+			t.Priority = p
+		default:
+			break peel
+		}
+		end--
+	}
+	if end == 0 {
+		return t, fmt.Errorf("task has no text")
+	}
+	t.Text = strings.Join(fields[:end], " ")
+	t.Tags = tasks.NormalizeTags(t.Tags)
+	return t, nil
+}
+
+func isNumber(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,151 @@
+package interchange
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// todo.txt priorities map onto ours: (A) high, (B) medium, (C) and below low.
+var todoTxtPriority = map[tasks.Priority]string{
+	tasks.PriorityHigh:   "A",
+	tasks.PriorityMedium: "B",
+	tasks.PriorityLow:    "C",
+}
+
+// encodeTodoTxt writes one todo.txt line per task:
+//
+//	x 2024-03-02 2024-03-01 Call mom +family @phone due:2024-03-05 pri:A
+//
+// Open tasks carry their priority as "(A) " in front. Completed tasks keep it
+// as a pri: tag, as the todo.txt convention suggests. Tags starting with @
+// are written as contexts, and all other tags become +projects.
+func encodeTodoTxt(w io.Writer, list []tasks.Task) error {
+	bw := bufio.NewWriter(w)
+	for _, t := range list {
+		var parts []string
+		pri := todoTxtPriority[t.Priority]
+		if t.Done {
+			parts = append(parts, "x")
+			if t.CompletedAt != nil {
+				parts = append(parts, t.CompletedAt.Local().Format(tasks.DateLayout))
+			}
+		} else if pri != "" {
+			parts = append(parts, "("+pri+")")
+		}
+		// todo.txt only allows a creation date after a completion date, so a
+		// task completed at an unknown time goes without both.
+		if t.CreatedAt != nil && (!t.Done || t.CompletedAt != nil) {
+			parts = append(parts, t.CreatedAt.Local().Format(tasks.DateLayout))
+		}
+		parts = append(parts, t.Text)
+		for _, tag := range t.Tags {
+			if strings.HasPrefix(tag, "@") || strings.HasPrefix(tag, "+") {
+				parts = append(parts, tag)
+			} else {
+				parts = append(parts, "+"+tag)
+			}
+		}
+		if t.Due != nil {
+			parts = append(parts, "due:"+t.Due.Local().Format(tasks.DateLayout))
+		}
+		if t.Done && pri != "" {
+			parts = append(parts, "pri:"+pri)
+		}
+		if _, err := fmt.Fprintln(bw, strings.Join(parts, " ")); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func decodeTodoTxt(r io.Reader) ([]tasks.Task, error) {
+	var list []tasks.Task
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		t, err := parseTodoTxtLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		list = append(list, t)
+	}
+	return list, sc.Err()
+}
+
+func parseTodoTxtLine(line string) (tasks.Task, error) {
+	var t tasks.Task
+	fields := strings.Fields(line)
+	if len(fields) > 0 && fields[0] == "x" {
+		t.Done = true
+		fields = fields[1:]
+		if d, ok := todoTxtDate(fields); ok {
+			t.CompletedAt = &d
+			fields = fields[1:]
+		}
+	} else if len(fields) > 0 && isTodoTxtPriority(fields[0]) {
+		t.Priority = priorityFromLetter(fields[0][1])
+		fields = fields[1:]
+	}
+	if d, ok := todoTxtDate(fields); ok {
+		t.CreatedAt = &d
+		fields = fields[1:]
+	}
+
+	var words []string
+	for _, f := range fields {
+		switch {
+		case len(f) > 1 && f[0] == '+':
+			t.Tags = append(t.Tags, f[1:])
+		case len(f) > 1 && f[0] == '@':
+			t.Tags = append(t.Tags, f)
+		case strings.HasPrefix(f, "due:"):
+			d, ok := todoTxtDate([]string{f[len("due:"):]})
+			if !ok {
+				// Not a date (e.g. "due:soon"): keep it as written.
+				words = append(words, f)
+				continue
+			}
+			t.Due = &d
+		case strings.HasPrefix(f, "pri:") && len(f) == len("pri:")+1:
+			t.Priority = priorityFromLetter(f[len(f)-1])
+		default:
+			words = append(words, f)
+		}
+	}
+	t.Text = strings.Join(words, " ")
+	if t.Text == "" {
+		return t, fmt.Errorf("task has no text")
+	}
+	t.Tags = tasks.NormalizeTags(t.Tags)
+	return t, nil
+}
+
+func todoTxtDate(fields []string) (time.Time, bool) {
+	if len(fields) == 0 {
+		return time.Time{}, false
+	}
+	d, err := time.ParseInLocation(tasks.DateLayout, fields[0], time.Local)
+	return d, err == nil
+}
+
+func isTodoTxtPriority(s string) bool {
+	return len(s) == 3 && s[0] == '(' && s[2] == ')' && s[1] >= 'A' && s[1] <= 'Z'
+}
+
+func priorityFromLetter(c byte) tasks.Priority {
+	switch c {
+	case 'A':
+		return tasks.PriorityHigh
+	case 'B':
+		return tasks.PriorityMedium
+	}
+	return tasks.PriorityLow
+}
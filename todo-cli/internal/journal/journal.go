@@ -0,0 +1,175 @@
+package journal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// MaxEntries bounds the journal; the oldest entries are dropped first.
+const MaxEntries = 100
+
+var (
+	// ErrNothingToUndo is returned when no applied entry is left to revert.
+	ErrNothingToUndo = errors.New("nothing to undo")
+	// ErrNothingToRedo is returned when no reverted entry is left to reapply.
+	ErrNothingToRedo = errors.New("nothing to redo")
+	// ErrDiverged is returned when the task list no longer matches what the
+	// journal last recorded, e.g. after the file was edited by hand.
+	ErrDiverged = errors.New("tasks changed outside the journal")
+)
+
+// Entry records one mutation as full before/after snapshots of the list.
+type Entry struct {
+	Seq     int          `json:"seq"`
+	At      time.Time    `json:"at"`
+	Op      string       `json:"op"`
+	Summary string       `json:"summary"`
+	Before  []tasks.Task `json:"before"`
+	After   []tasks.Task `json:"after"`
+}
+
+// Journal is the operation history kept alongside tasks.json. Entries before
+// Cursor are applied; entries from Cursor on were undone and can be redone.
+// Entries before Floor precede a change made outside the journal, such as a
+// sync, and can no longer be undone.
+type Journal struct {
+	Entries []Entry `json:"entries"`
+	Cursor  int     `json:"cursor"`
+	Floor   int     `json:"floor,omitempty"`
+}
+
+// PathFor returns the journal location for a tasks file.
+func PathFor(tasksPath string) string {
+	return tasksPath + ".journal"
+}
+
+// Load reads the journal at path. A missing file yields an empty journal.
+func Load(path string) (*Journal, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Journal{}, nil
+		}
+		return nil, err
+	}
+	var j Journal
+	if len(b) == 0 {
+		return &j, nil
+	}
+	if err := json.Unmarshal(b, &j); err != nil {
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+	if j.Cursor < 0 || j.Cursor > len(j.Entries) {
+		j.Cursor = len(j.Entries)
+	}
+	if j.Floor < 0 || j.Floor > j.Cursor {
+		j.Floor = j.Cursor
+	}
+	return &j, nil
+}
+
+// Save writes the journal to path via a temp file and rename.
+func (j *Journal) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Record appends a mutation. Any undone entries are discarded, as a new
+// change starts a new branch of history.
+func (j *Journal) Record(op, summary string, before, after []tasks.Task, at time.Time) {
+	seq := 1
+	if n := len(j.Entries); n > 0 {
+		seq = j.Entries[n-1].Seq + 1
+	}
+	j.Entries = append(j.Entries[:j.Cursor], Entry{
+		Seq:     seq,
+		At:      at,
+		Op:      op,
+		Summary: summary,
+		Before:  tasks.CloneList(before),
+		After:   tasks.CloneList(after),
+	})
+	if over := len(j.Entries) - MaxEntries; over > 0 {
+		j.Entries = append([]Entry(nil), j.Entries[over:]...)
+		j.Floor = max(j.Floor-over, 0)
+	}
+	j.Cursor = len(j.Entries)
+}
+
+// Fence marks the list as changed outside the journal, e.g. by a sync that
+// brought in remote changes. Undo stops at the current state, since going
+// further back would silently revert those changes, and undone entries can
+// no longer be redone. The entries stay in the history.
+func (j *Journal) Fence() {
+	j.Entries = j.Entries[:j.Cursor]
+	j.Floor = j.Cursor
+}
+
+// Undo reverts up to n applied entries, newest first, and returns the
+// resulting list with the entries it reverted. current must match the state
+// recorded by the newest applied entry.
+func (j *Journal) Undo(n int, current []tasks.Task) ([]tasks.Task, []Entry, error) {
+	if j.Cursor == j.Floor {
+		return nil, nil, ErrNothingToUndo
+	}
+	if !sameTasks(current, j.Entries[j.Cursor-1].After) {
+		return nil, nil, ErrDiverged
+	}
+	var undone []Entry
+	list := current
+	for ; n > 0 && j.Cursor > j.Floor; n-- {
+		j.Cursor--
+		e := j.Entries[j.Cursor]
+		list = tasks.CloneList(e.Before)
+		undone = append(undone, e)
+	}
+	return list, undone, nil
+}
+
+// Redo reapplies up to n undone entries, oldest first. current must match the
+// state before the first of them.
+func (j *Journal) Redo(n int, current []tasks.Task) ([]tasks.Task, []Entry, error) {
+	if j.Cursor == len(j.Entries) {
+		return nil, nil, ErrNothingToRedo
+	}
+	if !sameTasks(current, j.Entries[j.Cursor].Before) {
+		return nil, nil, ErrDiverged
+	}
+	var redone []Entry
+	list := current
+	for ; n > 0 && j.Cursor < len(j.Entries); n-- {
+		e := j.Entries[j.Cursor]
+		list = tasks.CloneList(e.After)
+		redone = append(redone, e)
+		j.Cursor++
+	}
+	return list, redone, nil
+}
+
+// sameTasks compares lists by their JSON form, which is how both sides were
+// persisted, so time zones and nil-vs-empty slices do not cause mismatches.
+func sameTasks(a, b []tasks.Task) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
@@ -0,0 +1,117 @@
+package journal_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/journal"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+func TestUndoRedoWalksSnapshots(t *testing.T) {
+	at := time.Date(2024, time.March, 13, 12, 0, 0, 0, time.UTC)
+	j := &journal.Journal{}
+
+	s0 := []tasks.Task(nil)
+	s1 := tasks.Add(s0, "one")
+	s2 := tasks.Add(tasks.CloneList(s1), "two")
+	s3 := tasks.Clear(s2)
+	j.Record("add", "added #1 one", s0, s1, at)
+	j.Record("add", "added #2 two", s1, s2, at)
+	j.Record("clear", "cleared 2 tasks", s2, s3, at)
+
+	list, undone, err := j.Undo(2, s3)
+	if err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if len(undone) != 2 || undone[0].Op != "clear" || len(list) != 1 || list[0].Text != "one" {
+		t.Fatalf("unexpected undo result: %+v / %+v", list, undone)
+	}
+
+	list, redone, err := j.Redo(1, list)
+	if err != nil {
+		t.Fatalf("Redo: %v", err)
+	}
+	if len(redone) != 1 || len(list) != 2 {
+		t.Fatalf("unexpected redo result: %+v", list)
+	}
+
+	// A fresh change drops the remaining redo branch.
+	s4 := tasks.Add(tasks.CloneList(list), "three")
+	j.Record("add", "added #3 three", list, s4, at)
+	if _, _, err := j.Redo(1, s4); !errors.Is(err, journal.ErrNothingToRedo) {
+		t.Fatalf("expected ErrNothingToRedo, got %v", err)
+	}
+	if len(j.Entries) != 3 {
+		t.Fatalf("expected redo branch to be discarded, got %d entries", len(j.Entries))
+	}
+}
+
+func TestUndoRefusesDivergedState(t *testing.T) {
+	j := &journal.Journal{}
+	after := tasks.Add(nil, "one")
+	j.Record("add", "added #1 one", nil, after, time.Now())
+
+	edited := tasks.Add(tasks.CloneList(after), "sneaked in")
+	if _, _, err := j.Undo(1, edited); !errors.Is(err, journal.ErrDiverged) {
+		t.Fatalf("expected ErrDiverged, got %v", err)
+	}
+	if _, _, err := (&journal.Journal{}).Undo(1, nil); !errors.Is(err, journal.ErrNothingToUndo) {
+		t.Fatalf("expected ErrNothingToUndo, got %v", err)
+	}
+}
+
+func TestFenceStopsUndoAtChangesFromElsewhere(t *testing.T) {
+	at := time.Date(2024, time.March, 13, 12, 0, 0, 0, time.UTC)
+	j := &journal.Journal{}
+	s1 := tasks.Add(nil, "one")
+	s2 := tasks.Add(tasks.CloneList(s1), "two")
+	j.Record("add", "added #1 one", nil, s1, at)
+	j.Record("add", "added #2 two", s1, s2, at)
+	if _, _, err := j.Undo(1, s2); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+
+	// A sync changes the list; a later change is recorded on top.
+	synced, _ := tasks.MarkDone(tasks.CloneList(s1), 1)
+	j.Fence()
+	s3 := tasks.Add(tasks.CloneList(synced), "three")
+	j.Record("add", "added #2 three", synced, s3, at)
+
+	list, undone, err := j.Undo(5, s3)
+	if err != nil || len(undone) != 1 || len(list) != 1 || !list[0].Done {
+		t.Fatalf("undo should stop at the synced list, got %+v / %+v (%v)", list, undone, err)
+	}
+	if _, _, err := j.Undo(1, list); !errors.Is(err, journal.ErrNothingToUndo) {
+		t.Fatalf("expected ErrNothingToUndo before the sync, got %v", err)
+	}
+	if len(j.Entries) != 2 || j.Floor != 1 {
+		t.Fatalf("expected the redo branch dropped and history kept, got %d entries, floor %d", len(j.Entries), j.Floor)
+	}
+}
+
+func TestJournalPersistsAndCaps(t *testing.T) {
+	path := journal.PathFor(filepath.Join(t.TempDir(), "tasks.json"))
+	j := &journal.Journal{}
+	var list []tasks.Task
+	for i := 0; i < journal.MaxEntries+5; i++ {
+		next := tasks.Add(tasks.CloneList(list), "task")
+		j.Record("add", "added", list, next, time.Now())
+		list = next
+	}
+	if err := j.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := journal.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded.Entries) != journal.MaxEntries || loaded.Cursor != journal.MaxEntries {
+		t.Fatalf("expected %d entries, got %d (cursor %d)", journal.MaxEntries, len(loaded.Entries), loaded.Cursor)
+	}
+	if loaded.Entries[0].Seq != 6 {
+		t.Fatalf("expected oldest entries dropped first, first seq %d", loaded.Entries[0].Seq)
+	}
+}
@@ -0,0 +1,94 @@
+package remind
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Notifier delivers notices.
+type Notifier interface {
+	Notify(ctx context.Context, n Notice) error
+}
+
+// Writer prints each notice as a line: time, kind and message.
+type Writer struct {
+	W   io.Writer
+	Now func() time.Time
+}
+
+func (w Writer) Notify(_ context.Context, n Notice) error {
+	_, err := fmt.Fprintf(w.W, "%s %-8s %s\n", w.Now().Format("2006-01-02 15:04"), n.Kind, n.Message())
+	return err
+}
+
+// Desktop runs a desktop notification command with the title and message as
+// its last two arguments, e.g. notify-send. An empty Command uses the
+// platform default.
+type Desktop struct {
+	Command []string
+}
+
+// DefaultDesktopCommand is notify-send, or osascript on macOS.
+func DefaultDesktopCommand() []string {
+	if runtime.GOOS == "darwin" {
+		return []string{"osascript", "-e", `on run argv
+display notification (item 2 of argv) with title (item 1 of argv)
+end run`}
+	}
+	return []string{"notify-send"}
+}
+
+func (d Desktop) Notify(ctx context.Context, n Notice) error {
+	argv := d.Command
+	if len(argv) == 0 {
+		argv = DefaultDesktopCommand()
+	}
+	args := append(append([]string(nil), argv[1:]...), n.Title(), n.Message())
+	return run(exec.CommandContext(ctx, argv[0], args...), n)
+}
+
+// Exec runs an arbitrary shell command for each notice. The notice is passed
+// in the environment: TODO_ID, TODO_TEXT, TODO_KIND, TODO_DUE (RFC 3339, or
+// empty), TODO_AT and TODO_MESSAGE.
+type Exec struct {
+	Command string
+}
+
+func (e Exec) Notify(ctx context.Context, n Notice) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", e.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", e.Command)
+	}
+	return run(cmd, n)
+}
+
+func run(cmd *exec.Cmd, n Notice) error {
+	cmd.Env = append(os.Environ(), env(n)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %v: %s", cmd.Path, err, out)
+	}
+	return nil
+}
+
+func env(n Notice) []string {
+	due := ""
+	if n.Task.Due != nil {
+		due = n.Task.Due.Format(time.RFC3339)
+	}
+	return []string{
+		"TODO_ID=" + strconv.Itoa(n.Task.ID),
+		"TODO_TEXT=" + n.Task.Text,
+		"TODO_KIND=" + n.Kind.String(),
+		"TODO_DUE=" + due,
+		"TODO_AT=" + n.At.Format(time.RFC3339),
+		"TODO_MESSAGE=" + n.Message(),
+	}
+}
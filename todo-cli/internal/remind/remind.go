@@ -0,0 +1,171 @@
+// Package remind works out when open tasks need attention and delivers the
+// resulting notices: at a task's remind-at time, when it falls due and, if
+// that moment has already passed, as overdue.
+package remind
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// Kind says why a notice fired.
+type Kind int
+
+const (
+	Reminder Kind = iota + 1
+	Due
+	Overdue
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Reminder:
+		return "reminder"
+	case Due:
+		return "due"
+	case Overdue:
+		return "overdue"
+	}
+	return "unknown"
+}
+
+// Notice is one notification about a task.
+type Notice struct {
+	Task tasks.Task
+	Kind Kind
+	// At is the moment the notice is for: the remind-at time or the due date.
+	At time.Time
+}
+
+// Title is a short heading for desktop notifications.
+func (n Notice) Title() string {
+	switch n.Kind {
+	case Due:
+		return fmt.Sprintf("Due: #%d", n.Task.ID)
+	case Overdue:
+		return fmt.Sprintf("Overdue: #%d", n.Task.ID)
+	}
+	return fmt.Sprintf("Reminder: #%d", n.Task.ID)
+}
+
+// Message is the one-line text of the notice.
+func (n Notice) Message() string {
+	msg := fmt.Sprintf("#%d %s", n.Task.ID, n.Task.Text)
+	if n.Task.Due == nil {
+		return msg
+	}
+	due := tasks.FormatDue(*n.Task.Due)
+	switch n.Kind {
+	case Due:
+		if tasks.IsDateOnly(*n.Task.Due) {
+			return msg + " is due today"
+		}
+		return msg + " is due now (" + due + ")"
+	case Overdue:
+		return msg + " is overdue (due " + due + ")"
+	}
+	return msg + " (due " + due + ")"
+}
+
+// Grace is how late a due notice may fire and still count as "due now"
+// rather than "overdue", so a poll interval or a laptop waking up a little
+// late does not change the wording.
+const Grace = time.Minute
+
+// trigger is one scheduled notice before its kind is settled.
+type trigger struct {
+	id   int
+	kind Kind
+	at   time.Time
+}
+
+// triggers lists the scheduled moments of the open tasks in list. A date-only
+// due date triggers at the start of that day and, once the day is over, again
+// as overdue.
+func triggers(list []tasks.Task) []trigger {
+	var out []trigger
+	for _, t := range list {
+		if t.Done {
+			continue
+		}
+		if t.RemindAt != nil {
+			out = append(out, trigger{t.ID, Reminder, *t.RemindAt})
+		}
+		if t.Due != nil {
+			out = append(out, trigger{t.ID, Due, *t.Due})
+			if tasks.IsDateOnly(*t.Due) {
+				out = append(out, trigger{t.ID, Overdue, t.Due.AddDate(0, 0, 1)})
+			}
+		}
+	}
+	return out
+}
+
+// key identifies a notice that has been delivered. It includes the time, so
+// moving a task's due date re-arms it.
+type key struct {
+	id   int
+	kind Kind
+	at   int64
+}
+
+// Schedule remembers which notices have fired. The zero value is ready to
+// use.
+type Schedule struct {
+	fired map[key]bool
+}
+
+// Pending returns the notices of list whose moment has come by now and that
+// have not fired yet, oldest first, and marks them fired. A reminder whose
+// due date has also passed is folded into the due or overdue notice.
+func (s *Schedule) Pending(list []tasks.Task, now time.Time) []Notice {
+	if s.fired == nil {
+		s.fired = make(map[key]bool)
+	}
+	byID := make(map[int]tasks.Task, len(list))
+	for _, t := range list {
+		byID[t.ID] = t
+	}
+	var out []Notice
+	seen := make(map[int]bool)
+	all := triggers(list)
+	// Latest first, so each task reports only its most urgent notice.
+	sort.SliceStable(all, func(i, j int) bool { return all[i].at.After(all[j].at) })
+	for _, tr := range all {
+		if tr.at.After(now) {
+			continue
+		}
+		k := key{tr.id, tr.kind, tr.at.UnixNano()}
+		if s.fired[k] {
+			seen[tr.id] = true
+			continue
+		}
+		s.fired[k] = true
+		if seen[tr.id] {
+			continue
+		}
+		seen[tr.id] = true
+		kind := tr.kind
+		if kind == Due && !tasks.IsDateOnly(tr.at) && now.Sub(tr.at) > Grace {
+			kind = Overdue
+		}
+		out = append(out, Notice{Task: byID[tr.id], Kind: kind, At: tr.at})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].At.Before(out[j].At) })
+	return out
+}
+
+// Next returns the earliest moment after now at which a notice of list will
+// fire.
+func Next(list []tasks.Task, now time.Time) (time.Time, bool) {
+	var next time.Time
+	for _, tr := range triggers(list) {
+		if tr.at.After(now) && (next.IsZero() || tr.at.Before(next)) {
+			next = tr.at
+		}
+	}
+	return next, !next.IsZero()
+}
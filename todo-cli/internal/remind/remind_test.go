@@ -0,0 +1,162 @@
+package remind_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/remind"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+func at(day, hour, min int) time.Time {
+	return time.Date(2024, time.March, day, hour, min, 0, 0, time.Local)
+}
+
+func ptr(t time.Time) *time.Time { return &t }
+
+func requireNotices(t *testing.T, got []remind.Notice, want ...string) {
+	t.Helper()
+	var lines []string
+	for _, n := range got {
+		lines = append(lines, n.Kind.String()+": "+n.Message())
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("notices:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestScheduleFiresEachNoticeOnce(t *testing.T) {
+	list := []tasks.Task{
+		{ID: 1, Text: "Standup", Due: ptr(at(5, 9, 30)), RemindAt: ptr(at(5, 9, 15))},
+		{ID: 2, Text: "Pay rent", Due: ptr(at(5, 0, 0))},
+		{ID: 3, Text: "Old report", Due: ptr(at(4, 17, 0)), RemindAt: ptr(at(4, 16, 0))},
+		{ID: 4, Text: "Done already", Done: true, Due: ptr(at(4, 0, 0))},
+		{ID: 5, Text: "Someday"},
+	}
+	var s remind.Schedule
+
+	requireNotices(t, s.Pending(list, at(5, 9, 0)),
+		"overdue: #3 Old report is overdue (due 2024-03-04 17:00)",
+		"due: #2 Pay rent is due today",
+	)
+	requireNotices(t, s.Pending(list, at(5, 9, 5)))
+	requireNotices(t, s.Pending(list, at(5, 9, 15)),
+		"reminder: #1 Standup (due 2024-03-05 09:30)",
+	)
+	requireNotices(t, s.Pending(list, at(5, 9, 30)),
+		"due: #1 Standup is due now (2024-03-05 09:30)",
+	)
+	requireNotices(t, s.Pending(list, at(6, 0, 0)),
+		"overdue: #2 Pay rent is overdue (due 2024-03-05)",
+	)
+
+	// Moving the due date re-arms the task.
+	list[0].Due = ptr(at(6, 10, 0))
+	requireNotices(t, s.Pending(list, at(6, 10, 5)),
+		"overdue: #1 Standup is overdue (due 2024-03-06 10:00)",
+	)
+
+	next, ok := remind.Next(list, at(5, 9, 0))
+	if !ok || !next.Equal(at(5, 9, 15)) {
+		t.Fatalf("next = %v, %v", next, ok)
+	}
+}
+
+type recorder struct{ notices []remind.Notice }
+
+func (r *recorder) Notify(_ context.Context, n remind.Notice) error {
+	r.notices = append(r.notices, n)
+	return nil
+}
+
+func TestWatcherReloadsWhenTheFileChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	if err := storage.SaveTasks(path, []tasks.Task{{ID: 1, Text: "Call mum", Due: ptr(at(5, 12, 0))}}); err != nil {
+		t.Fatal(err)
+	}
+	clock := at(5, 11, 0)
+	rec := &recorder{}
+	var errs bytes.Buffer
+	w := &remind.Watcher{
+		Load:      func() ([]tasks.Task, error) { return storage.LoadTasks(path) },
+		Paths:     []string{path},
+		Notifiers: []remind.Notifier{rec},
+		Now:       func() time.Time { return clock },
+		Errors:    &errs,
+	}
+	ctx := context.Background()
+	if !w.Check(ctx) {
+		t.Fatal("first check should load the file")
+	}
+	if w.Check(ctx) {
+		t.Fatal("unchanged file should not be reloaded")
+	}
+
+	if err := storage.SaveTasks(path, []tasks.Task{
+		{ID: 1, Text: "Call mum", Due: ptr(at(5, 12, 0))},
+		{ID: 2, Text: "Book dentist", RemindAt: ptr(at(5, 11, 30))},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Check(ctx) {
+		t.Fatal("changed file should be reloaded")
+	}
+	clock = at(5, 12, 0)
+	w.Check(ctx)
+	requireNotices(t, rec.notices,
+		"reminder: #2 Book dentist",
+		"due: #1 Call mum is due now (2024-03-05 12:00)",
+	)
+	if errs.Len() != 0 {
+		t.Fatalf("unexpected errors %q", errs.String())
+	}
+}
+
+func TestWriterAndExecNotifiers(t *testing.T) {
+	n := remind.Notice{Task: tasks.Task{ID: 7, Text: "Water plants", Due: ptr(at(5, 0, 0))}, Kind: remind.Due, At: at(5, 0, 0)}
+
+	var out bytes.Buffer
+	w := remind.Writer{W: &out, Now: func() time.Time { return at(5, 8, 0) }}
+	if err := w.Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "2024-03-05 08:00 due      #7 Water plants is due today\n" {
+		t.Fatalf("writer printed %q", out.String())
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Skip("exec hook test uses sh")
+	}
+	file := filepath.Join(t.TempDir(), "hook.txt")
+	hook := remind.Exec{Command: `printf '%s|%s|%s' "$TODO_ID" "$TODO_KIND" "$TODO_MESSAGE" > ` + file}
+	if err := hook.Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "7|due|#7 Water plants is due today" {
+		t.Fatalf("hook wrote %q", got)
+	}
+
+	desktop := remind.Desktop{Command: []string{"sh", "-c", `printf '%s / %s' "$1" "$2" > ` + file, "notify"}}
+	if err := desktop.Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = os.ReadFile(file)
+	if string(got) != "Due: #7 / #7 Water plants is due today" {
+		t.Fatalf("desktop command wrote %q", got)
+	}
+
+	if err := (remind.Exec{Command: "exit 3"}).Notify(context.Background(), n); err == nil {
+		t.Fatal("expected a failing hook to report an error")
+	}
+}
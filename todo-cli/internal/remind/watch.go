@@ -0,0 +1,104 @@
+package remind
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// Watcher fires notices for a task file as their moments come, re-reading the
+// file whenever it changes on disk.
+type Watcher struct {
+	// Load reads the current tasks.
+	Load func() ([]tasks.Task, error)
+	// Paths are the files whose changes trigger a reload.
+	Paths     []string
+	Notifiers []Notifier
+	Now       func() time.Time
+	// Poll is how often the files are checked; it also bounds how late a
+	// notice can fire.
+	Poll time.Duration
+	// Errors receives failed reloads and notifier errors; the watcher keeps
+	// going after both.
+	Errors io.Writer
+
+	schedule Schedule
+	list     []tasks.Task
+	stamps   []stamp
+	loaded   bool
+}
+
+// stamp is what a file looked like at the last reload.
+type stamp struct {
+	mod  time.Time
+	size int64
+	ok   bool
+}
+
+func stat(path string) stamp {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return stamp{}
+	}
+	return stamp{mod: fi.ModTime(), size: fi.Size(), ok: true}
+}
+
+// Check reloads the tasks if a watched file changed since the last call, then
+// delivers the pending notices. It reports whether it reloaded.
+func (w *Watcher) Check(ctx context.Context) bool {
+	reloaded := w.refresh()
+	for _, n := range w.schedule.Pending(w.list, w.Now()) {
+		for _, notifier := range w.Notifiers {
+			if err := notifier.Notify(ctx, n); err != nil {
+				fmt.Fprintln(w.Errors, "notify:", err)
+			}
+		}
+	}
+	return reloaded
+}
+
+func (w *Watcher) refresh() bool {
+	stamps := make([]stamp, len(w.Paths))
+	changed := !w.loaded
+	for i, p := range w.Paths {
+		stamps[i] = stat(p)
+		if i >= len(w.stamps) || stamps[i] != w.stamps[i] {
+			changed = true
+		}
+	}
+	if !changed {
+		return false
+	}
+	list, err := w.Load()
+	if err != nil {
+		// Probably caught mid-write; keep the old list and retry next poll.
+		fmt.Fprintln(w.Errors, "reload:", err)
+		return false
+	}
+	w.list, w.stamps, w.loaded = list, stamps, true
+	return true
+}
+
+// Run checks until ctx is done, waking every Poll or at the next scheduled
+// notice, whichever comes first.
+func (w *Watcher) Run(ctx context.Context) error {
+	for {
+		w.Check(ctx)
+		wait := w.Poll
+		now := w.Now()
+		if next, ok := Next(w.list, now); ok && next.Sub(now) < wait {
+			wait = next.Sub(now)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
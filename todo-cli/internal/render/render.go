@@ -0,0 +1,233 @@
+package render
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// Format names accepted by --format.
+const (
+	FormatText     = "text"
+	FormatJSON     = "json"
+	FormatJSONL    = "jsonl"
+	FormatCSV      = "csv"
+	FormatTSV      = "tsv"
+	FormatTemplate = "template"
+)
+
+// ListFormats are the formats supported for task lists.
+var ListFormats = []string{FormatText, FormatJSON, FormatJSONL, FormatCSV, FormatTSV, FormatTemplate}
+
+// columns is the header row of csv and tsv output.
+var columns = []string{"id", "done", "text", "due", "priority", "tags"}
+
+// Options configures list output.
+type Options struct {
+	Format   string
+	Template string    // text/template source, used by FormatTemplate
+	Now      time.Time // reference time for overdue markers
+	// All is the whole list the tasks were picked from. Text output uses it
+	// to tell which blockers are still open; nil means the listed tasks.
+	All []tasks.Task
+}
+
+// CheckFormat validates a format name against the allowed set.
+func CheckFormat(format string, allowed []string) error {
+	for _, f := range allowed {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid format %q (want %s)", format, strings.Join(allowed, ", "))
+}
+
+// WriteList writes list to w in the format selected by opts.
+func WriteList(w io.Writer, list []tasks.Task, opts Options) error {
+	switch opts.Format {
+	case "", FormatText:
+		all := opts.All
+		if all == nil {
+			all = list
+		}
+		for _, n := range tasks.Tree(list) {
+			if _, err := fmt.Fprintln(w, TreeLine(n, all, opts.Now)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatJSON:
+		if list == nil {
+			list = []tasks.Task{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	case FormatJSONL:
+		enc := json.NewEncoder(w)
+		for i := range list {
+			if err := enc.Encode(&list[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV, FormatTSV:
+		cw := csv.NewWriter(w)
+		if opts.Format == FormatTSV {
+			cw.Comma = '\t'
+		}
+		if err := cw.Write(columns); err != nil {
+			return err
+		}
+		for _, t := range list {
+			if err := cw.Write(record(t)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatTemplate:
+		tmpl, err := ParseTemplate(opts.Template, opts.Now)
+		if err != nil {
+			return err
+		}
+		for _, t := range list {
+			if err := tmpl.Execute(w, t); err != nil {
+				return err
+			}
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return CheckFormat(opts.Format, ListFormats)
+}
+
+// ParseTemplate compiles a per-task template. Besides the Task fields it
+// offers `due` (formatted due date or ""), `join` and `overdue`.
+func ParseTemplate(src string, now time.Time) (*template.Template, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, fmt.Errorf("template format requires --template")
+	}
+	return template.New("task").Funcs(template.FuncMap{
+		"due": func(t tasks.Task) string {
+			if t.Due == nil {
+				return ""
+			}
+			return tasks.FormatDue(*t.Due)
+		},
+		"join":    strings.Join,
+		"overdue": func(t tasks.Task) bool { return t.Overdue(now) },
+	}).Parse(src)
+}
+
+// TreeLine renders a task placed by tasks.Tree: subtasks are indented under
+// their parent with box-drawing connectors, and only blockers that are still
+// open in all are listed.
+func TreeLine(n tasks.Node, all []tasks.Task, now time.Time) string {
+	t := n.Task
+	t.BlockedBy = tasks.OpenBlockers(all, t)
+	if n.Depth == 0 {
+		return Line(t, now)
+	}
+	t.Parent = 0 // shown by the indentation
+	var b strings.Builder
+	for _, more := range n.Lines[1:] {
+		if more {
+			b.WriteString("│  ")
+		} else {
+			b.WriteString("   ")
+		}
+	}
+	if n.Last {
+		b.WriteString("└─ ")
+	} else {
+		b.WriteString("├─ ")
+	}
+	return b.String() + Line(t, now)
+}
+
+// Line renders one task for the human-readable list: the done marker, ID and
+// text, followed by any due date, reminder, recurrence, priority, tags, parent and
+// blockers.
+func Line(t tasks.Task, now time.Time) string {
+	state := " "
+	if t.Done {
+		state = "x"
+	}
+	line := fmt.Sprintf("[%s] #%d %s", state, t.ID, t.Text)
+	var details []string
+	if t.Due != nil {
+		due := "due " + tasks.FormatDue(*t.Due)
+		if t.Overdue(now) {
+			due += ", overdue"
+		}
+		details = append(details, due)
+	}
+	if t.RemindAt != nil && !t.Done {
+		details = append(details, "remind "+tasks.FormatDue(*t.RemindAt))
+	}
+	if t.Recur != nil {
+		details = append(details, "repeats "+t.Recur.String())
+	}
+	if t.Priority != tasks.PriorityNone {
+		details = append(details, "priority "+t.Priority.String())
+	}
+	if len(t.Tags) > 0 {
+		details = append(details, "tags "+strings.Join(t.Tags, ","))
+	}
+	if t.Parent != 0 {
+		details = append(details, fmt.Sprintf("subtask of #%d", t.Parent))
+	}
+	if len(t.BlockedBy) > 0 {
+		details = append(details, "blocked by "+tasks.FormatIDs(t.BlockedBy))
+	}
+	if len(details) > 0 {
+		line += " (" + strings.Join(details, ", ") + ")"
+	}
+	return line
+}
+
+func record(t tasks.Task) []string {
+	due := ""
+	if t.Due != nil {
+		due = t.Due.Format(time.RFC3339)
+	}
+	priority := ""
+	if t.Priority != tasks.PriorityNone {
+		priority = t.Priority.String()
+	}
+	return []string{
+		strconv.Itoa(t.ID),
+		strconv.FormatBool(t.Done),
+		t.Text,
+		due,
+		priority,
+		strings.Join(t.Tags, ","),
+	}
+}
+
+// Result is the JSON document printed by mutating commands with --format json.
+type Result struct {
+	Op        string       `json:"op"`
+	IDs       []int        `json:"ids"`
+	Unchanged []int        `json:"unchanged,omitempty"`
+	Spawned   []int        `json:"spawned,omitempty"`
+	Tasks     []tasks.Task `json:"tasks,omitempty"`
+}
+
+// WriteResult prints r as a single JSON line.
+func WriteResult(w io.Writer, r Result) error {
+	if r.IDs == nil {
+		r.IDs = []int{}
+	}
+	return json.NewEncoder(w).Encode(r)
+}
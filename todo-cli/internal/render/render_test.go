@@ -0,0 +1,96 @@
+package render_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/render"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+func sample() []tasks.Task {
+	due := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	return []tasks.Task{
+		{ID: 1, Text: `say "hi", then leave`, Done: true},
+		{ID: 2, Text: "file\ttaxes", Due: &due, Priority: tasks.PriorityHigh, Tags: []string{"home", "money"}},
+	}
+}
+
+func TestWriteListJSONAndJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	if err := render.WriteList(&buf, sample(), render.Options{Format: render.FormatJSON}); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	var decoded []tasks.Task
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 2 || decoded[1].Priority != tasks.PriorityHigh {
+		t.Fatalf("json output did not round trip: %v\n%s", err, buf.String())
+	}
+
+	buf.Reset()
+	if err := render.WriteList(&buf, nil, render.Options{Format: render.FormatJSON}); err != nil || strings.TrimSpace(buf.String()) != "[]" {
+		t.Fatalf("expected empty JSON array, got %q (%v)", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := render.WriteList(&buf, sample(), render.Options{Format: render.FormatJSONL}); err != nil {
+		t.Fatalf("jsonl: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], `{"id":1,`) {
+		t.Fatalf("unexpected jsonl output:\n%s", buf.String())
+	}
+}
+
+func TestWriteListCSVAndTSVQuoteFields(t *testing.T) {
+	for _, format := range []string{render.FormatCSV, render.FormatTSV} {
+		var buf bytes.Buffer
+		if err := render.WriteList(&buf, sample(), render.Options{Format: format}); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		r := csv.NewReader(&buf)
+		if format == render.FormatTSV {
+			r.Comma = '\t'
+		}
+		rows, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("%s output is not parseable: %v", format, err)
+		}
+		want := [][]string{
+			{"id", "done", "text", "due", "priority", "tags"},
+			{"1", "true", `say "hi", then leave`, "", "", ""},
+			{"2", "false", "file\ttaxes", "2024-03-01T00:00:00Z", "high", "home,money"},
+		}
+		for i := range want {
+			if strings.Join(rows[i], "|") != strings.Join(want[i], "|") {
+				t.Fatalf("%s row %d: got %q, want %q", format, i, rows[i], want[i])
+			}
+		}
+	}
+}
+
+func TestWriteListTemplate(t *testing.T) {
+	var buf bytes.Buffer
+	opts := render.Options{
+		Format:   render.FormatTemplate,
+		Template: `{{.ID}}:{{if .Done}}done{{else}}open{{end}}:{{due .}}:{{join .Tags "+"}}`,
+		Now:      time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC),
+	}
+	if err := render.WriteList(&buf, sample(), opts); err != nil {
+		t.Fatalf("template: %v", err)
+	}
+	want := "1:done::\n2:open:" + tasks.FormatDue(*sample()[1].Due) + ":home+money\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+
+	if _, err := render.ParseTemplate("{{.ID", time.Now()); err == nil {
+		t.Fatalf("expected template syntax error")
+	}
+	if err := render.WriteList(&buf, sample(), render.Options{Format: "xml"}); err == nil {
+		t.Fatalf("expected error for unknown format")
+	}
+}
@@ -0,0 +1,298 @@
+// Package search ranks tasks against a query using case-insensitive
+// substring, regular expression or fuzzy matching, and reports the matched
+// spans so callers can highlight them.
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// Mode selects how a query matches.
+type Mode int
+
+const (
+	Substring Mode = iota
+	Regex
+	Fuzzy
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Regex:
+		return "regex"
+	case Fuzzy:
+		return "fuzzy"
+	}
+	return "substring"
+}
+
+// Span is a matched byte range [Start, End) within a string.
+type Span struct {
+	Start, End int
+}
+
+// Hit is a task that matched, with its score and the spans that matched in
+// the text and in each tag (TagSpans is parallel to Task.Tags).
+type Hit struct {
+	Task     tasks.Task
+	Score    int
+	Spans    []Span
+	TagSpans [][]Span
+}
+
+// Matcher matches a compiled query against strings.
+type Matcher struct {
+	mode  Mode
+	query []rune
+	re    *regexp.Regexp
+}
+
+// Compile prepares query for mode. Substring and fuzzy queries ignore case;
+// regular expressions are compiled case-insensitively.
+func Compile(query string, mode Mode) (*Matcher, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("empty search query")
+	}
+	m := &Matcher{mode: mode}
+	switch mode {
+	case Regex:
+		re, err := regexp.Compile("(?i)" + query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		m.re = re
+	case Fuzzy:
+		for _, r := range query {
+			if !unicode.IsSpace(r) {
+				m.query = append(m.query, unicode.ToLower(r))
+			}
+		}
+	default:
+		m.query, _ = fold(query)
+	}
+	return m, nil
+}
+
+// Score bonuses. Matches in the task text outrank matches that only hit a
+// tag; matches starting at a word boundary outrank ones inside a word.
+const (
+	scoreMatch    = 100
+	scoreTag      = 60
+	scoreExact    = 50
+	scorePrefix   = 20
+	scoreBoundary = 10
+	scoreRepeat   = 5
+)
+
+// Match scores s and returns the matched spans; ok is false when s does not
+// match at all.
+func (m *Matcher) Match(s string) (score int, spans []Span, ok bool) {
+	switch m.mode {
+	case Regex:
+		return m.matchRegex(s)
+	case Fuzzy:
+		return m.matchFuzzy(s)
+	}
+	return m.matchSubstring(s)
+}
+
+func (m *Matcher) matchSubstring(s string) (int, []Span, bool) {
+	runes, offsets := fold(s)
+	n := len(m.query)
+	var spans []Span
+	for i := 0; i+n <= len(runes); i++ {
+		if equalRunes(runes[i:i+n], m.query) {
+			spans = append(spans, Span{offsets[i], offsets[i+n]})
+			i += n - 1
+		}
+	}
+	if len(spans) == 0 {
+		return 0, nil, false
+	}
+	return m.rank(s, spans), spans, true
+}
+
+func (m *Matcher) matchRegex(s string) (int, []Span, bool) {
+	var spans []Span
+	for _, loc := range m.re.FindAllStringIndex(s, -1) {
+		if loc[1] > loc[0] {
+			spans = append(spans, Span{loc[0], loc[1]})
+		}
+	}
+	if len(spans) == 0 {
+		return 0, nil, false
+	}
+	return m.rank(s, spans), spans, true
+}
+
+// rank scores exact substring and regex hits.
+func (m *Matcher) rank(s string, spans []Span) int {
+	score := scoreMatch + scoreRepeat*(len(spans)-1)
+	first := spans[0]
+	switch {
+	case first.Start == 0 && first.End == len(s):
+		score += scoreExact
+	case first.Start == 0:
+		score += scorePrefix
+	case atBoundary(s, first.Start):
+		score += scoreBoundary
+	}
+	return score
+}
+
+// matchFuzzy requires every query rune to appear in order. It finds the
+// first window that contains the query, then walks back from its end to pick
+// the latest start, so "ab" in "a cab" highlights "ab" rather than "a c-b".
+// Consecutive runs and word-boundary hits score higher; gaps inside the
+// window cost a point each.
+func (m *Matcher) matchFuzzy(s string) (int, []Span, bool) {
+	runes, offsets := fold(s)
+	qi, end := 0, -1
+	for i, r := range runes {
+		if r == m.query[qi] {
+			qi++
+			if qi == len(m.query) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+	positions := make([]int, len(m.query))
+	qi = len(m.query) - 1
+	for i := end; i >= 0 && qi >= 0; i-- {
+		if runes[i] == m.query[qi] {
+			positions[qi] = i
+			qi--
+		}
+	}
+
+	score := 0
+	var spans []Span
+	for k, p := range positions {
+		score += 16
+		if k > 0 && positions[k-1] == p-1 {
+			score += 12
+			spans[len(spans)-1].End = offsets[p+1]
+			continue
+		}
+		if k > 0 {
+			score -= p - positions[k-1] - 1
+		}
+		if p == 0 {
+			score += 15
+		} else if atBoundary(s, offsets[p]) {
+			score += scoreBoundary
+		}
+		spans = append(spans, Span{offsets[p], offsets[p+1]})
+	}
+	if score < 1 {
+		score = 1
+	}
+	return score, spans, true
+}
+
+// Search matches every task's text and tags and returns the hits ranked by
+// score. Ties keep open tasks before completed ones, then lower IDs first.
+func Search(list []tasks.Task, m *Matcher) []Hit {
+	var hits []Hit
+	for _, t := range list {
+		hit := Hit{Task: t}
+		matched := false
+		if score, spans, ok := m.Match(t.Text); ok {
+			hit.Score = score
+			hit.Spans = spans
+			matched = true
+		}
+		for i, tag := range t.Tags {
+			score, spans, ok := m.Match(tag)
+			if !ok {
+				continue
+			}
+			if hit.TagSpans == nil {
+				hit.TagSpans = make([][]Span, len(t.Tags))
+			}
+			hit.TagSpans[i] = spans
+			// A tag hit adds a little to a text hit but ranks below one on
+			// its own.
+			tagScore := score * scoreTag / scoreMatch
+			if matched {
+				tagScore /= 4
+			}
+			hit.Score += tagScore
+			matched = true
+		}
+		if matched {
+			hits = append(hits, hit)
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Task.Done != b.Task.Done {
+			return !a.Task.Done
+		}
+		return a.Task.ID < b.Task.ID
+	})
+	return hits
+}
+
+// Highlight wraps each span of s in open and close markers.
+func Highlight(s string, spans []Span, open, close string) string {
+	if len(spans) == 0 {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, sp := range spans {
+		b.WriteString(s[last:sp.Start])
+		b.WriteString(open)
+		b.WriteString(s[sp.Start:sp.End])
+		b.WriteString(close)
+		last = sp.End
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// fold lowercases s rune by rune and returns the byte offset of each rune,
+// plus len(s) as a final sentinel, so spans map back onto the original.
+func fold(s string) ([]rune, []int) {
+	runes := make([]rune, 0, len(s))
+	offsets := make([]int, 0, len(s)+1)
+	for i, r := range s {
+		runes = append(runes, unicode.ToLower(r))
+		offsets = append(offsets, i)
+	}
+	return runes, append(offsets, len(s))
+}
+
+func equalRunes(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// atBoundary reports whether byte offset i starts a word in s.
+func atBoundary(s string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, _ := utf8.DecodeLastRuneInString(s[:i])
+	return !unicode.IsLetter(prev) && !unicode.IsDigit(prev)
+}
@@ -0,0 +1,111 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/search"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+func ids(hits []search.Hit) []int {
+	out := make([]int, len(hits))
+	for i, h := range hits {
+		out[i] = h.Task.ID
+	}
+	return out
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func compile(t *testing.T, q string, mode search.Mode) *search.Matcher {
+	t.Helper()
+	m, err := search.Compile(q, mode)
+	if err != nil {
+		t.Fatalf("compile %q: %v", q, err)
+	}
+	return m
+}
+
+func TestSubstringIsCaseInsensitiveAndRanked(t *testing.T) {
+	list := []tasks.Task{
+		{ID: 1, Text: "Call the plumber"},
+		{ID: 2, Text: "Plumber"},
+		{ID: 3, Text: "Pay the bill", Tags: []string{"plumbing"}},
+		{ID: 4, Text: "Ask about plumbers", Done: true},
+		{ID: 5, Text: "Walk dog"},
+		{ID: 6, Text: "Ask about plumbers"},
+	}
+	hits := search.Search(list, compile(t, "PLUMB", search.Substring))
+	if got, want := ids(hits), []int{2, 1, 6, 4, 3}; !equalInts(got, want) {
+		t.Fatalf("ranking: got %v, want %v", got, want)
+	}
+	if sp := hits[1].Spans; len(sp) != 1 || sp[0] != (search.Span{Start: 9, End: 14}) {
+		t.Fatalf("unexpected spans %v", sp)
+	}
+	if tag := hits[4].TagSpans; len(tag) != 1 || len(tag[0]) != 1 || hits[4].Spans != nil {
+		t.Fatalf("expected tag-only hit, got %+v", hits[4])
+	}
+}
+
+func TestSpansAreByteOffsetsForUnicode(t *testing.T) {
+	m := compile(t, "café", search.Substring)
+	_, spans, ok := m.Match("Ein CAFÉ, bitte")
+	if !ok || len(spans) != 1 || spans[0] != (search.Span{Start: 4, End: 9}) {
+		t.Fatalf("got %v %v", spans, ok)
+	}
+	if got := search.Highlight("Ein CAFÉ, bitte", spans, "[", "]"); got != "Ein [CAFÉ], bitte" {
+		t.Fatalf("highlight: %q", got)
+	}
+}
+
+func TestRegex(t *testing.T) {
+	m := compile(t, `inv(oice)?-\d+`, search.Regex)
+	_, spans, ok := m.Match("Send INVOICE-42 and inv-7")
+	if !ok || len(spans) != 2 || spans[1] != (search.Span{Start: 20, End: 25}) {
+		t.Fatalf("got %v %v", spans, ok)
+	}
+	if _, err := search.Compile("(", search.Regex); err == nil {
+		t.Fatal("expected invalid regex error")
+	}
+	if _, err := search.Compile("  ", search.Substring); err == nil {
+		t.Fatal("expected empty query error")
+	}
+}
+
+func TestFuzzy(t *testing.T) {
+	m := compile(t, "rnw pass", search.Fuzzy)
+	_, spans, ok := m.Match("Renew passport")
+	if !ok {
+		t.Fatal("expected fuzzy match")
+	}
+	if got := search.Highlight("Renew passport", spans, "[", "]"); got != "[R]e[n]e[w] [pass]port" {
+		t.Fatalf("highlight: %q", got)
+	}
+	if _, _, ok := m.Match("passport renewal"); ok {
+		t.Fatal("fuzzy match must keep query order")
+	}
+
+	_, spans, _ = compile(t, "ab", search.Fuzzy).Match("a cab")
+	if len(spans) != 1 || spans[0] != (search.Span{Start: 3, End: 5}) {
+		t.Fatalf("expected tightened window, got %v", spans)
+	}
+
+	list := []tasks.Task{
+		{ID: 1, Text: "buy groceries"},
+		{ID: 2, Text: "bug report"},
+		{ID: 3, Text: "big update"},
+	}
+	if got := ids(search.Search(list, compile(t, "bug", search.Fuzzy))); !equalInts(got, []int{2, 1}) {
+		t.Fatalf("fuzzy ranking: got %v", got)
+	}
+}
@@ -0,0 +1,456 @@
+// Package server exposes a task list as a small JSON REST API:
+//
+//	GET    /tasks        list tasks (?done=true|false, ?tag=name)
+//	POST   /tasks        create a task
+//	GET    /tasks/{id}   fetch one task
+//	PATCH  /tasks/{id}   change some fields of a task
+//	DELETE /tasks/{id}   remove a task
+//
+// Every task and the collection carry an ETag. PATCH and DELETE must send the
+// task's current ETag in If-Match (or "*"), so a client that has not seen the
+// latest version gets 412 Precondition Failed instead of overwriting it. The
+// check runs inside Store.Update, under the same lock as the change.
+//
+// Requests must name the server in their Host header: the address they
+// arrived on, or localhost or 127.0.0.1 with its port. Anything else gets 421
+// Misdirected Request, so a web page cannot reach the API through a DNS name
+// that was rebound to the loopback address.
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// Store is what the server reads and changes. Update must apply fn under an
+// exclusive lock and persist the result, like any other command.
+type Store interface {
+	Load() ([]tasks.Task, error)
+	Update(op string, fn func(list []tasks.Task) ([]tasks.Task, string, error)) error
+}
+
+// Input is the request body of POST and PATCH. Absent fields are left alone;
+// an empty due, remind_at or recur string clears the value.
+type Input struct {
+	Text      *string   `json:"text"`
+	Done      *bool     `json:"done"`
+	Due       *string   `json:"due"`
+	RemindAt  *string   `json:"remind_at"`
+	Priority  *string   `json:"priority"`
+	Tags      *[]string `json:"tags"`
+	Recur     *string   `json:"recur"`
+	Parent    *int      `json:"parent"`
+	BlockedBy *[]int    `json:"blocked_by"`
+}
+
+// maxBody caps request bodies; a task is a few hundred bytes.
+const maxBody = 1 << 20
+
+var (
+	errPrecondition   = errors.New("task has changed since it was read (ETag mismatch)")
+	errNoPrecondition = errors.New("If-Match header required")
+	errMediaType      = errors.New("Content-Type must be application/json")
+	errMisdirected    = errors.New("Host header does not name this server")
+)
+
+// badRequestError marks input the client has to fix.
+type badRequestError struct{ err error }
+
+func (e badRequestError) Error() string { return e.err.Error() }
+
+func badRequest(format string, args ...any) error {
+	return badRequestError{fmt.Errorf(format, args...)}
+}
+
+type server struct {
+	store Store
+	now   func() time.Time
+}
+
+// New returns the API handler. now supplies the clock for created and
+// completed times and relative due dates.
+func New(store Store, now func() time.Time) http.Handler {
+	s := &server{store: store, now: now}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", s.list)
+	mux.HandleFunc("POST /tasks", s.create)
+	mux.HandleFunc("GET /tasks/{id}", s.get)
+	mux.HandleFunc("PATCH /tasks/{id}", s.update)
+	mux.HandleFunc("DELETE /tasks/{id}", s.remove)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !knownHost(r) {
+			writeError(w, errMisdirected)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// knownHost reports whether the Host header names the local address the
+// request arrived on, localhost or 127.0.0.1, each with the local port.
+func knownHost(r *http.Request) bool {
+	local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return false
+	}
+	ip, port, err := net.SplitHostPort(local.String())
+	if err != nil {
+		return false
+	}
+	host, hostPort, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host, hostPort = r.Host, "80"
+	}
+	if hostPort != port {
+		return false
+	}
+	return host == ip || host == "127.0.0.1" || strings.EqualFold(host, "localhost")
+}
+
+func (s *server) list(w http.ResponseWriter, r *http.Request) {
+	list, err := s.store.Load()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var filter tasks.Filter
+	q := r.URL.Query()
+	if v := q.Get("done"); v != "" {
+		done, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, badRequest("done must be true or false"))
+			return
+		}
+		filter.Done = &done
+	}
+	filter.Tags = tasks.NormalizeTags(q["tag"])
+	etag := ETag(list)
+	w.Header().Set("ETag", etag)
+	if matches(r.Header.Get("If-None-Match"), etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	out := filter.Apply(tasks.SortOldestFirst(list), s.now())
+	if out == nil {
+		out = []tasks.Task{}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *server) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	list, err := s.store.Load()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	t, ok := find(list, id)
+	if !ok {
+		writeError(w, tasks.ErrNotFound)
+		return
+	}
+	etag := ETag(t)
+	w.Header().Set("ETag", etag)
+	if matches(r.Header.Get("If-None-Match"), etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// create adds a task. If-Match is optional here; when given it is compared
+// with the collection's ETag.
+func (s *server) create(w http.ResponseWriter, r *http.Request) {
+	in, err := decode(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if in.Text == nil || strings.TrimSpace(*in.Text) == "" {
+		writeError(w, badRequest("text is required"))
+		return
+	}
+	if in.Done != nil && *in.Done {
+		writeError(w, badRequest("new tasks cannot be done"))
+		return
+	}
+	ifMatch := r.Header.Get("If-Match")
+	var added tasks.Task
+	err = s.store.Update("add", func(list []tasks.Task) ([]tasks.Task, string, error) {
+		if ifMatch != "" && !matches(ifMatch, ETag(list), false) {
+			return nil, "", errPrecondition
+		}
+		created := s.now()
+		t := tasks.Task{CreatedAt: &created}
+		if err := apply(&t, in, created); err != nil {
+			return nil, "", err
+		}
+		list = tasks.AddTask(list, t)
+		added = list[len(list)-1]
+		return list, fmt.Sprintf("added #%d %s", added.ID, added.Text), nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/tasks/%d", added.ID))
+	w.Header().Set("ETag", ETag(added))
+	writeJSON(w, http.StatusCreated, added)
+}
+
+func (s *server) update(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	in, err := decode(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		writeError(w, errNoPrecondition)
+		return
+	}
+	var updated tasks.Task
+	err = s.store.Update("edit", func(list []tasks.Task) ([]tasks.Task, string, error) {
+		i := index(list, id)
+		if i < 0 {
+			return nil, "", tasks.ErrNotFound
+		}
+		if !matches(ifMatch, ETag(list[i]), false) {
+			return nil, "", errPrecondition
+		}
+		at := s.now()
+		if err := apply(&list[i], in, at); err != nil {
+			return nil, "", err
+		}
+		if in.Done != nil {
+			list, _ = tasks.SetDone(list, []int{id}, *in.Done, at)
+			if *in.Done {
+				if err := tasks.CheckChildrenDone(list, []int{id}); err != nil {
+					return nil, "", err
+				}
+				list, _ = tasks.Recur(list, []int{id}, at)
+			}
+		}
+		updated = list[index(list, id)]
+		return list, fmt.Sprintf("updated #%d %s", id, updated.Text), nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("ETag", ETag(updated))
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (s *server) remove(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		writeError(w, errNoPrecondition)
+		return
+	}
+	err = s.store.Update("rm", func(list []tasks.Task) ([]tasks.Task, string, error) {
+		i := index(list, id)
+		if i < 0 {
+			return nil, "", tasks.ErrNotFound
+		}
+		if !matches(ifMatch, ETag(list[i]), false) {
+			return nil, "", errPrecondition
+		}
+		return tasks.RemoveAll(list, []int{id}), fmt.Sprintf("removed #%d", id), nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apply copies the fields present in in onto t. Done is left to the caller,
+// which also has to handle subtasks and recurrence.
+func apply(t *tasks.Task, in Input, now time.Time) error {
+	if in.Text != nil {
+		text := strings.TrimSpace(*in.Text)
+		if text == "" {
+			return badRequest("text cannot be empty")
+		}
+		t.Text = text
+	}
+	if in.Due != nil {
+		t.Due = nil
+		if *in.Due != "" {
+			d, err := tasks.ParseDue(*in.Due, now)
+			if err != nil {
+				return badRequestError{err}
+			}
+			t.Due = &d
+		}
+	}
+	if in.RemindAt != nil {
+		t.RemindAt = nil
+		if *in.RemindAt != "" {
+			r, err := tasks.ParseRemind(*in.RemindAt, t.Due, now)
+			if err != nil {
+				return badRequestError{err}
+			}
+			t.RemindAt = &r
+		}
+	}
+	if in.Priority != nil {
+		p, err := tasks.ParsePriority(*in.Priority)
+		if err != nil {
+			return badRequestError{err}
+		}
+		t.Priority = p
+	}
+	if in.Tags != nil {
+		t.Tags = tasks.NormalizeTags(*in.Tags)
+	}
+	if in.Recur != nil {
+		t.Recur = nil
+		if *in.Recur != "" {
+			rule, err := tasks.ParseRecurrence(*in.Recur)
+			if err != nil {
+				return badRequestError{err}
+			}
+			t.Recur = &rule
+			if t.Due == nil {
+				first := rule.First(tasks.StartOfDay(now))
+				t.Due = &first
+			}
+		}
+	}
+	if in.Parent != nil {
+		if *in.Parent < 0 {
+			return badRequest("invalid parent ID")
+		}
+		t.Parent = *in.Parent
+	}
+	if in.BlockedBy != nil {
+		t.BlockedBy = nil
+		if len(*in.BlockedBy) > 0 {
+			t.BlockedBy = append([]int(nil), *in.BlockedBy...)
+		}
+	}
+	return nil
+}
+
+// ETag returns the strong entity tag of v's JSON encoding: a task or a list.
+func ETag(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return `""`
+	}
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// matches reports whether an If-Match or If-None-Match header value names
+// etag. If-None-Match compares weakly, so weak tags match by value; If-Match
+// compares strongly, so they never match.
+func matches(header, etag string, weak bool) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if weak {
+			v = strings.TrimPrefix(v, "W/")
+		}
+		if v == "*" || v == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func decode(r *http.Request) (Input, error) {
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+		return Input{}, errMediaType
+	}
+	var in Input
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		return Input{}, badRequest("invalid JSON body: %v", err)
+	}
+	return in, nil
+}
+
+func pathID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		return 0, tasks.ErrNotFound
+	}
+	return id, nil
+}
+
+func index(list []tasks.Task, id int) int {
+	for i := range list {
+		if list[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func find(list []tasks.Task, id int) (tasks.Task, bool) {
+	if i := index(list, id); i >= 0 {
+		return list[i], true
+	}
+	return tasks.Task{}, false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError maps err to a status code and writes {"error": "..."}.
+func writeError(w http.ResponseWriter, err error) {
+	var bad badRequestError
+	var openChildren *tasks.OpenChildrenError
+	status := http.StatusInternalServerError
+	switch {
+	case errors.As(err, &bad):
+		status = http.StatusBadRequest
+	case errors.Is(err, errPrecondition):
+		status = http.StatusPreconditionFailed
+	case errors.Is(err, errNoPrecondition):
+		status = http.StatusPreconditionRequired
+	case errors.Is(err, errMediaType):
+		status = http.StatusUnsupportedMediaType
+	case errors.Is(err, errMisdirected):
+		status = http.StatusMisdirectedRequest
+	case err == tasks.ErrNotFound:
+		status = http.StatusNotFound
+	case errors.Is(err, tasks.ErrNotFound), errors.Is(err, tasks.ErrCycle), errors.As(err, &openChildren):
+		// A link to a missing task or a refused completion: the request is
+		// well-formed but conflicts with the current list.
+		status = http.StatusConflict
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,229 @@
+package server_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/server"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// memStore is a locked in-memory server.Store.
+type memStore struct {
+	mu   sync.Mutex
+	list []tasks.Task
+}
+
+func (m *memStore) Load() ([]tasks.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return tasks.CloneList(m.list), nil
+}
+
+func (m *memStore) Update(_ string, fn func([]tasks.Task) ([]tasks.Task, string, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	after, _, err := fn(tasks.CloneList(m.list))
+	if err != nil {
+		return err
+	}
+	if err := tasks.CheckGraph(after); err != nil {
+		return err
+	}
+	m.list = after
+	return nil
+}
+
+type client struct {
+	t   *testing.T
+	srv *httptest.Server
+}
+
+func newClient(t *testing.T, store server.Store) *client {
+	now := func() time.Time { return time.Date(2024, time.March, 5, 9, 0, 0, 0, time.UTC) }
+	srv := httptest.NewServer(server.New(store, now))
+	t.Cleanup(srv.Close)
+	return &client{t: t, srv: srv}
+}
+
+func (c *client) do(method, path, body string, header ...string) (*http.Response, string) {
+	c.t.Helper()
+	req, err := http.NewRequest(method, c.srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		if header[i] == "Host" {
+			req.Host = header[i+1]
+			continue
+		}
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return resp, string(b)
+}
+
+func (c *client) expect(status int, method, path, body string, header ...string) (*http.Response, string) {
+	c.t.Helper()
+	resp, out := c.do(method, path, body, header...)
+	if resp.StatusCode != status {
+		c.t.Fatalf("%s %s: expected %d, got %d: %s", method, path, status, resp.StatusCode, out)
+	}
+	return resp, out
+}
+
+func decodeTask(t *testing.T, body string) tasks.Task {
+	t.Helper()
+	var task tasks.Task
+	if err := json.Unmarshal([]byte(body), &task); err != nil {
+		t.Fatalf("decode %q: %v", body, err)
+	}
+	return task
+}
+
+func TestCreateGetUpdateDelete(t *testing.T) {
+	c := newClient(t, &memStore{})
+
+	resp, body := c.expect(http.StatusCreated, "POST", "/tasks", `{"text":"Buy milk","due":"tomorrow","tags":["Home"]}`)
+	if got := resp.Header.Get("Location"); got != "/tasks/1" {
+		t.Fatalf("location %q", got)
+	}
+	created := decodeTask(t, body)
+	if created.ID != 1 || created.Due == nil || created.Due.Format(tasks.DateLayout) != "2024-03-06" || created.Tags[0] != "Home" {
+		t.Fatalf("unexpected task %+v", created)
+	}
+	etag := resp.Header.Get("ETag")
+
+	resp, _ = c.expect(http.StatusOK, "GET", "/tasks/1", "")
+	if resp.Header.Get("ETag") != etag {
+		t.Fatalf("GET etag %q differs from POST etag %q", resp.Header.Get("ETag"), etag)
+	}
+	c.expect(http.StatusNotModified, "GET", "/tasks/1", "", "If-None-Match", etag)
+
+	c.expect(http.StatusPreconditionRequired, "PATCH", "/tasks/1", `{"text":"Buy oat milk"}`)
+	resp, body = c.expect(http.StatusOK, "PATCH", "/tasks/1", `{"text":"Buy oat milk","done":true}`, "If-Match", etag)
+	updated := decodeTask(t, body)
+	if updated.Text != "Buy oat milk" || !updated.Done || updated.CompletedAt == nil {
+		t.Fatalf("unexpected update %+v", updated)
+	}
+	newTag := resp.Header.Get("ETag")
+	if newTag == etag {
+		t.Fatal("expected the ETag to change")
+	}
+
+	// A second client still holding the old ETag cannot clobber the change.
+	_, body = c.expect(http.StatusPreconditionFailed, "PATCH", "/tasks/1", `{"text":"stale"}`, "If-Match", etag)
+	if !strings.Contains(body, "ETag mismatch") {
+		t.Fatalf("unexpected error body %q", body)
+	}
+	c.expect(http.StatusPreconditionFailed, "DELETE", "/tasks/1", "", "If-Match", etag)
+	c.expect(http.StatusNoContent, "DELETE", "/tasks/1", "", "If-Match", newTag)
+	c.expect(http.StatusNotFound, "GET", "/tasks/1", "")
+	c.expect(http.StatusNotFound, "DELETE", "/tasks/1", "", "If-Match", "*")
+}
+
+func TestPreconditionsAndContentType(t *testing.T) {
+	c := newClient(t, &memStore{})
+	resp, _ := c.expect(http.StatusCreated, "POST", "/tasks", `{"text":"Buy milk"}`, "Content-Type", "application/json; charset=utf-8")
+	etag := resp.Header.Get("ETag")
+
+	// If-None-Match compares weakly, If-Match strongly.
+	c.expect(http.StatusNotModified, "GET", "/tasks/1", "", "If-None-Match", "W/"+etag)
+	c.expect(http.StatusPreconditionFailed, "PATCH", "/tasks/1", `{"text":"Buy oat milk"}`, "If-Match", "W/"+etag)
+
+	c.expect(http.StatusUnsupportedMediaType, "POST", "/tasks", `{"text":"Walk dog"}`, "Content-Type", "text/plain")
+	c.expect(http.StatusUnsupportedMediaType, "PATCH", "/tasks/1", `{"text":"Buy oat milk"}`, "Content-Type", "application/x-www-form-urlencoded", "If-Match", etag)
+	c.expect(http.StatusOK, "PATCH", "/tasks/1", `{"text":"Buy oat milk"}`, "If-Match", etag)
+}
+
+func TestRejectsForeignHost(t *testing.T) {
+	c := newClient(t, &memStore{})
+	port := c.srv.URL[strings.LastIndex(c.srv.URL, ":")+1:]
+
+	c.expect(http.StatusOK, "GET", "/tasks", "")
+	c.expect(http.StatusOK, "GET", "/tasks", "", "Host", "localhost:"+port)
+	c.expect(http.StatusOK, "GET", "/tasks", "", "Host", "127.0.0.1:"+port)
+
+	// A rebound name, or the right name on another port, is refused before
+	// anything is read or changed.
+	c.expect(http.StatusMisdirectedRequest, "GET", "/tasks", "", "Host", "attacker.example:"+port)
+	c.expect(http.StatusMisdirectedRequest, "GET", "/tasks", "", "Host", "localhost:1")
+	c.expect(http.StatusMisdirectedRequest, "POST", "/tasks", `{"text":"x"}`, "Host", "attacker.example")
+	if _, body := c.expect(http.StatusOK, "GET", "/tasks", ""); strings.TrimSpace(body) != "[]" {
+		t.Fatalf("refused request changed the list: %s", body)
+	}
+}
+
+func TestListFiltersAndCollectionETag(t *testing.T) {
+	c := newClient(t, &memStore{})
+	c.expect(http.StatusCreated, "POST", "/tasks", `{"text":"Pay rent","tags":["bills"]}`)
+	c.expect(http.StatusCreated, "POST", "/tasks", `{"text":"Walk dog"}`)
+	c.expect(http.StatusOK, "PATCH", "/tasks/2", `{"done":true}`, "If-Match", "*")
+
+	resp, body := c.expect(http.StatusOK, "GET", "/tasks", "")
+	var all []tasks.Task
+	if err := json.Unmarshal([]byte(body), &all); err != nil || len(all) != 2 || all[0].ID != 1 {
+		t.Fatalf("unexpected list %s (%v)", body, err)
+	}
+	etag := resp.Header.Get("ETag")
+	c.expect(http.StatusNotModified, "GET", "/tasks", "", "If-None-Match", etag)
+
+	_, body = c.expect(http.StatusOK, "GET", "/tasks?done=false", "")
+	if !strings.Contains(body, "Pay rent") || strings.Contains(body, "Walk dog") {
+		t.Fatalf("done=false returned %s", body)
+	}
+	_, body = c.expect(http.StatusOK, "GET", "/tasks?tag=nothing", "")
+	if strings.TrimSpace(body) != "[]" {
+		t.Fatalf("expected an empty array, got %s", body)
+	}
+	c.expect(http.StatusBadRequest, "GET", "/tasks?done=maybe", "")
+
+	// Creating with a stale collection ETag fails; the fresh one succeeds.
+	c.expect(http.StatusCreated, "POST", "/tasks", `{"text":"Call mum"}`, "If-Match", etag)
+	c.expect(http.StatusPreconditionFailed, "POST", "/tasks", `{"text":"Call dad"}`, "If-Match", etag)
+}
+
+func TestValidationAndGraphErrors(t *testing.T) {
+	c := newClient(t, &memStore{})
+	c.expect(http.StatusBadRequest, "POST", "/tasks", `{"text":""}`)
+	c.expect(http.StatusBadRequest, "POST", "/tasks", `{"text":"x","priority":"urgent"}`)
+	c.expect(http.StatusBadRequest, "POST", "/tasks", `{"text":"x","colour":"red"}`)
+	c.expect(http.StatusBadRequest, "POST", "/tasks", `not json`)
+	c.expect(http.StatusConflict, "POST", "/tasks", `{"text":"orphan","parent":42}`)
+
+	c.expect(http.StatusCreated, "POST", "/tasks", `{"text":"Plan trip"}`)
+	c.expect(http.StatusCreated, "POST", "/tasks", `{"text":"Book flights","parent":1}`)
+	_, body := c.expect(http.StatusConflict, "PATCH", "/tasks/1", `{"done":true}`, "If-Match", "*")
+	if !strings.Contains(body, "open subtasks") {
+		t.Fatalf("unexpected body %s", body)
+	}
+	c.expect(http.StatusConflict, "PATCH", "/tasks/2", `{"blocked_by":[1]}`, "If-Match", "*")
+}
+
+func TestCompletingRecurringTaskSpawnsNext(t *testing.T) {
+	store := &memStore{}
+	c := newClient(t, store)
+	c.expect(http.StatusCreated, "POST", "/tasks", `{"text":"Water plants","recur":"weekly","due":"2024-03-05"}`)
+	c.expect(http.StatusOK, "PATCH", "/tasks/1", `{"done":true}`, "If-Match", "*")
+	list, _ := store.Load()
+	if len(list) != 2 || list[1].Recur == nil || list[1].Due.Format(tasks.DateLayout) != "2024-03-12" {
+		t.Fatalf("expected the next occurrence, got %+v", list)
+	}
+}
@@ -0,0 +1,196 @@
+// Package stats summarises a task list over time: how many tasks were created
+// and completed per day and per week, how long they took, the open backlog
+// and the run of consecutive days with something completed.
+package stats
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// Bucket counts the tasks created and completed in one day or week.
+type Bucket struct {
+	Start     time.Time `json:"start"`
+	Created   int       `json:"created"`
+	Completed int       `json:"completed"`
+}
+
+// Report is the outcome of Compute.
+type Report struct {
+	Days  []Bucket `json:"days"`
+	Weeks []Bucket `json:"weeks"`
+	// Created and Completed total the daily buckets.
+	Created   int `json:"created"`
+	Completed int `json:"completed"`
+	// AvgToComplete is the mean time from creation to completion of the
+	// tasks completed within the daily window that record both times.
+	AvgToComplete time.Duration `json:"avg_to_complete_ns"`
+	Timed         int           `json:"timed"`
+	Open          int           `json:"open"`
+	Overdue       int           `json:"overdue"`
+	// Streak counts the consecutive days up to today with at least one
+	// completion. A day without completions so far does not break it until
+	// the day is over.
+	Streak        int `json:"streak"`
+	LongestStreak int `json:"longest_streak"`
+	// Untracked counts tasks written before timestamps were recorded, which
+	// the per-day figures cannot place.
+	Untracked int `json:"untracked"`
+}
+
+// Compute builds the report for the days and weeks ending with now's day.
+// Weeks start on Monday.
+func Compute(list []tasks.Task, now time.Time, days, weeks int) Report {
+	loc := now.Location()
+	today := tasks.StartOfDay(now)
+	r := Report{
+		Days:  make([]Bucket, days),
+		Weeks: make([]Bucket, weeks),
+	}
+	for i := range r.Days {
+		r.Days[i].Start = today.AddDate(0, 0, i-days+1)
+	}
+	thisWeek := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	for i := range r.Weeks {
+		r.Weeks[i].Start = thisWeek.AddDate(0, 0, 7*(i-weeks+1))
+	}
+
+	completedOn := make(map[string]bool)
+	var total time.Duration
+	for _, t := range list {
+		if !t.Done {
+			r.Open++
+			if t.Overdue(now) {
+				r.Overdue++
+			}
+		}
+		if t.CreatedAt == nil && (!t.Done || t.CompletedAt == nil) {
+			r.Untracked++
+		}
+		if t.CreatedAt != nil {
+			created := t.CreatedAt.In(loc)
+			if b := find(r.Days, created, 1); b != nil {
+				b.Created++
+				r.Created++
+			}
+			if b := find(r.Weeks, created, 7); b != nil {
+				b.Created++
+			}
+		}
+		if !t.Done || t.CompletedAt == nil {
+			continue
+		}
+		completed := t.CompletedAt.In(loc)
+		completedOn[completed.Format(tasks.DateLayout)] = true
+		if b := find(r.Days, completed, 1); b != nil {
+			b.Completed++
+			r.Completed++
+			if t.CreatedAt != nil && !completed.Before(*t.CreatedAt) {
+				total += completed.Sub(*t.CreatedAt)
+				r.Timed++
+			}
+		}
+		if b := find(r.Weeks, completed, 7); b != nil {
+			b.Completed++
+		}
+	}
+	if r.Timed > 0 {
+		r.AvgToComplete = total / time.Duration(r.Timed)
+	}
+	r.Streak, r.LongestStreak = streaks(completedOn, today)
+	return r
+}
+
+// find returns the bucket of span days that contains t, or nil.
+func find(buckets []Bucket, t time.Time, span int) *Bucket {
+	for i := range buckets {
+		start := buckets[i].Start
+		if !t.Before(start) && t.Before(start.AddDate(0, 0, span)) {
+			return &buckets[i]
+		}
+	}
+	return nil
+}
+
+// streaks returns the current and the longest run of consecutive days in
+// days, which holds dates in tasks.DateLayout.
+func streaks(days map[string]bool, today time.Time) (current, longest int) {
+	on := func(t time.Time) bool { return days[t.Format(tasks.DateLayout)] }
+	day := today
+	if !on(day) {
+		day = day.AddDate(0, 0, -1)
+	}
+	for on(day) {
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+	for key := range days {
+		d, _ := time.ParseInLocation(tasks.DateLayout, key, today.Location())
+		if on(d.AddDate(0, 0, -1)) {
+			continue // not the first day of a run
+		}
+		n := 0
+		for on(d.AddDate(0, 0, n)) {
+			n++
+		}
+		if n > longest {
+			longest = n
+		}
+	}
+	return current, longest
+}
+
+// sparkLevels are the ASCII heights a sparkline is drawn with, lowest first.
+const sparkLevels = "_.:-=+*#%@"
+
+// Sparkline draws one character per value, scaled to the largest value.
+// Zero is always the lowest level, and any non-zero value shows above it.
+func Sparkline(values []int) string {
+	max := 0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	var b strings.Builder
+	top := len(sparkLevels) - 1
+	for _, v := range values {
+		level := 0
+		if v > 0 {
+			level = 1 + v*(top-1)/max
+		}
+		b.WriteByte(sparkLevels[level])
+	}
+	return b.String()
+}
+
+// FormatDuration renders d coarsely: "2d 4h", "3h 12m" or "45m".
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// Counts extracts the created or completed counts of buckets.
+func Counts(buckets []Bucket, completed bool) []int {
+	out := make([]int, len(buckets))
+	for i, b := range buckets {
+		if completed {
+			out[i] = b.Completed
+		} else {
+			out[i] = b.Created
+		}
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package stats_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/stats"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+func at(month time.Month, day, hour int) *time.Time {
+	t := time.Date(2024, month, day, hour, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestCompute(t *testing.T) {
+	// Thursday 7 March 2024, noon.
+	now := *at(time.March, 7, 12)
+	list := []tasks.Task{
+		{ID: 1, Text: "a", Done: true, CreatedAt: at(time.March, 1, 9), CompletedAt: at(time.March, 5, 9)},
+		{ID: 2, Text: "b", Done: true, CreatedAt: at(time.March, 5, 9), CompletedAt: at(time.March, 6, 9)},
+		{ID: 3, Text: "c", Done: true, CreatedAt: at(time.March, 6, 9), CompletedAt: at(time.March, 6, 11)},
+		{ID: 4, Text: "d", CreatedAt: at(time.March, 7, 9), Due: at(time.March, 6, 0)},
+		{ID: 5, Text: "e", CreatedAt: at(time.February, 20, 9)},
+		{ID: 6, Text: "old"},
+		// An earlier, longer streak: Feb 10-13.
+		{ID: 7, Text: "f", Done: true, CompletedAt: at(time.February, 10, 8)},
+		{ID: 8, Text: "g", Done: true, CompletedAt: at(time.February, 11, 8)},
+		{ID: 9, Text: "h", Done: true, CompletedAt: at(time.February, 12, 8)},
+		{ID: 10, Text: "i", Done: true, CompletedAt: at(time.February, 13, 8)},
+	}
+	r := stats.Compute(list, now, 7, 3)
+
+	if r.Days[0].Start.Format(tasks.DateLayout) != "2024-03-01" || r.Days[6].Start.Format(tasks.DateLayout) != "2024-03-07" {
+		t.Fatalf("unexpected day window %v .. %v", r.Days[0].Start, r.Days[6].Start)
+	}
+	if got := stats.Counts(r.Days, false); !equal(got, []int{1, 0, 0, 0, 1, 1, 1}) {
+		t.Fatalf("created per day %v", got)
+	}
+	if got := stats.Counts(r.Days, true); !equal(got, []int{0, 0, 0, 0, 1, 2, 0}) {
+		t.Fatalf("completed per day %v", got)
+	}
+	if r.Created != 4 || r.Completed != 3 {
+		t.Fatalf("totals created=%d completed=%d", r.Created, r.Completed)
+	}
+	// Weeks start on Monday: Feb 19, Feb 26, Mar 4.
+	if r.Weeks[0].Start.Format(tasks.DateLayout) != "2024-02-19" {
+		t.Fatalf("first week starts %v", r.Weeks[0].Start)
+	}
+	if got := stats.Counts(r.Weeks, false); !equal(got, []int{1, 1, 3}) {
+		t.Fatalf("created per week %v", got)
+	}
+	if got := stats.Counts(r.Weeks, true); !equal(got, []int{0, 0, 3}) {
+		t.Fatalf("completed per week %v", got)
+	}
+	// 4d, 1d and 2h.
+	if r.Timed != 3 || r.AvgToComplete != (96+24+2)*time.Hour/3 {
+		t.Fatalf("average %v over %d", r.AvgToComplete, r.Timed)
+	}
+	if r.Open != 3 || r.Overdue != 1 || r.Untracked != 1 {
+		t.Fatalf("open=%d overdue=%d untracked=%d", r.Open, r.Overdue, r.Untracked)
+	}
+	// Nothing completed today yet, so the streak still counts Mar 5-6.
+	if r.Streak != 2 || r.LongestStreak != 4 {
+		t.Fatalf("streak=%d longest=%d", r.Streak, r.LongestStreak)
+	}
+
+	r = stats.Compute(list, *at(time.March, 8, 12), 7, 3)
+	if r.Streak != 0 {
+		t.Fatalf("a full day without completions should end the streak, got %d", r.Streak)
+	}
+}
+
+func TestSparklineAndDuration(t *testing.T) {
+	if got := stats.Sparkline([]int{0, 1, 2, 4, 8}); got != "_:-+@" {
+		t.Fatalf("sparkline %q", got)
+	}
+	if got := stats.Sparkline([]int{0, 0}); got != "__" {
+		t.Fatalf("empty sparkline %q", got)
+	}
+	for d, want := range map[time.Duration]string{
+		45 * time.Minute:             "45m",
+		3*time.Hour + 12*time.Minute: "3h 12m",
+		52 * time.Hour:               "2d 4h",
+	} {
+		if got := stats.FormatDuration(d); got != want {
+			t.Fatalf("FormatDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
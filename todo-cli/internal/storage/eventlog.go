@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// Event operations recorded in the log.
+const (
+	EventPut    = "put"    // task created or replaced
+	EventDelete = "delete" // task removed
+)
+
+// Event is one line of the append-only JSONL log.
+type Event struct {
+	Op   string      `json:"op"`
+	At   time.Time   `json:"at"`
+	ID   int         `json:"id,omitempty"`
+	Task *tasks.Task `json:"task,omitempty"`
+}
+
+// EventLogStore records every change as an event appended to a JSONL file.
+// Loading replays the log; saving appends only the difference between the
+// replayed state and the new list, so existing lines are never rewritten.
+type EventLogStore struct {
+	path string
+}
+
+func NewEventLogStore(path string) *EventLogStore { return &EventLogStore{path: path} }
+
+// Events returns every event in the log in the order it was written.
+func (s *EventLogStore) Events() ([]Event, error) {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var events []Event
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		raw := bytes.TrimSpace(sc.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal(raw, &ev); err != nil {
+			// A crash mid-append can leave a torn final line without a
+			// newline; everything before it is still valid.
+			if !bytes.HasSuffix(b, []byte("\n")) && bytes.HasSuffix(bytes.TrimSpace(b), raw) {
+				break
+			}
+			return nil, fmt.Errorf("%s:%d: %w", s.path, line, err)
+		}
+		events = append(events, ev)
+	}
+	return events, sc.Err()
+}
+
+func (s *EventLogStore) Load() ([]tasks.Task, error) {
+	events, err := s.Events()
+	if err != nil {
+		return nil, err
+	}
+	return replay(events)
+}
+
+func (s *EventLogStore) Save(list []tasks.Task) error {
+	current, err := s.Load()
+	if err != nil {
+		return err
+	}
+	events := diffEvents(current, list, time.Now().UTC())
+	if len(events) == 0 {
+		return nil
+	}
+	if err := ensureDir(s.path); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i := range events {
+		if err := enc.Encode(&events[i]); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := trimTornTail(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// trimTornTail positions f for appending. A torn final line left by an
+// interrupted append is cut off, since Events ignores it anyway; a complete
+// final line that only lacks its newline gets one.
+func trimTornTail(f *os.File) error {
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	if len(b) == 0 || b[len(b)-1] == '\n' {
+		return nil
+	}
+	cut := bytes.LastIndexByte(b, '\n') + 1
+	if json.Valid(b[cut:]) {
+		_, err := f.Write([]byte("\n"))
+		return err
+	}
+	if err := f.Truncate(int64(cut)); err != nil {
+		return err
+	}
+	_, err = f.Seek(int64(cut), io.SeekStart)
+	return err
+}
+
+// replay folds events into the task list they describe, keeping tasks in the
+// order they were first created.
+func replay(events []Event) ([]tasks.Task, error) {
+	var list []tasks.Task
+	index := make(map[int]int) // id -> position in list
+	for _, ev := range events {
+		switch ev.Op {
+		case EventPut:
+			if ev.Task == nil {
+				return nil, fmt.Errorf("put event without task")
+			}
+			if i, ok := index[ev.Task.ID]; ok {
+				list[i] = *ev.Task
+				continue
+			}
+			index[ev.Task.ID] = len(list)
+			list = append(list, *ev.Task)
+		case EventDelete:
+			i, ok := index[ev.ID]
+			if !ok {
+				continue
+			}
+			list = append(list[:i], list[i+1:]...)
+			delete(index, ev.ID)
+			for id, pos := range index {
+				if pos > i {
+					index[id] = pos - 1
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unknown event op %q", ev.Op)
+		}
+	}
+	return list, nil
+}
+
+// diffEvents returns the events that turn before into after.
+func diffEvents(before, after []tasks.Task, at time.Time) []Event {
+	old := make(map[int]tasks.Task, len(before))
+	for _, t := range before {
+		old[t.ID] = t
+	}
+	keep := make(map[int]bool, len(after))
+	var events []Event
+	for _, t := range after {
+		keep[t.ID] = true
+		if prev, ok := old[t.ID]; ok && reflect.DeepEqual(prev, t) {
+			continue
+		}
+		t := t.Clone()
+		events = append(events, Event{Op: EventPut, At: at, Task: &t})
+	}
+	for _, t := range before {
+		if !keep[t.ID] {
+			events = append(events, Event{Op: EventDelete, At: at, ID: t.ID})
+		}
+	}
+	return events
+}
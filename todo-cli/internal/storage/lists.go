@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// EnvList names the environment variable that selects the default list.
+const EnvList = "TODO_CLI_LIST"
+
+// DefaultListName is the list stored at DefaultPath itself.
+const DefaultListName = "default"
+
+// listsDir holds named lists next to the default tasks.json.
+const listsDir = "lists"
+
+var listNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// DefaultList returns $TODO_CLI_LIST, or the default list when unset.
+func DefaultList() string {
+	if l := os.Getenv(EnvList); l != "" {
+		return l
+	}
+	return DefaultListName
+}
+
+// CheckListName rejects names that cannot be used as a file name.
+func CheckListName(name string) error {
+	if !listNamePattern.MatchString(name) || len(name) > 64 {
+		return fmt.Errorf("invalid list name %q (use letters, digits, '.', '_' or '-')", name)
+	}
+	return nil
+}
+
+// ListPath returns the tasks file for the named list. The default list keeps
+// using base; any other list lives at <dir of base>/lists/<name>.json.
+func ListPath(base, name string) (string, error) {
+	if name == "" || name == DefaultListName {
+		return base, nil
+	}
+	if err := CheckListName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(base), listsDir, name+".json"), nil
+}
+
+// ListNames returns the default list followed by every named list found next
+// to base, sorted. Lists kept by the event-log backend (.jsonl) count too.
+func ListNames(base string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(filepath.Dir(base), listsDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		for _, ext := range []string{".jsonl", ".json"} {
+			if strings.HasSuffix(name, ext) {
+				name = strings.TrimSuffix(name, ext)
+				if CheckListName(name) == nil && name != DefaultListName && !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DefaultListName}, names...), nil
+}
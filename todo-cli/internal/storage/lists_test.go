@@ -0,0 +1,49 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+)
+
+func TestListPathAndNames(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "tasks.json")
+
+	for _, name := range []string{"", storage.DefaultListName} {
+		if p, err := storage.ListPath(base, name); err != nil || p != base {
+			t.Fatalf("ListPath(%q) = %q, %v; want base", name, p, err)
+		}
+	}
+	work, err := storage.ListPath(base, "work")
+	if err != nil || work != filepath.Join(dir, "lists", "work.json") {
+		t.Fatalf("ListPath(work) = %q, %v", work, err)
+	}
+	for _, bad := range []string{"../x", ".hidden", "a/b", "with space"} {
+		if _, err := storage.ListPath(base, bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+	if storage.LockPath(work) == storage.LockPath(base) {
+		t.Fatal("named lists should not share the default list's lock")
+	}
+
+	if names, err := storage.ListNames(base); err != nil || !reflect.DeepEqual(names, []string{"default"}) {
+		t.Fatalf("ListNames on a fresh dir = %v, %v", names, err)
+	}
+	if err := storage.SaveTasks(work, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"home.jsonl", "home.json", "notes.txt", "tasks.lock", "work.json.journal"} {
+		if err := os.WriteFile(filepath.Join(dir, "lists", f), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names, err := storage.ListNames(base)
+	if err != nil || !reflect.DeepEqual(names, []string{"default", "home", "work"}) {
+		t.Fatalf("ListNames = %v, %v", names, err)
+	}
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	lockName           = "tasks.lock"
+	envLockTimeout     = "TODO_CLI_LOCK_TIMEOUT"
+	defaultLockTimeout = 5 * time.Second
+	lockPollInterval   = 25 * time.Millisecond
+)
+
+// ErrLockTimeout is returned when the lock could not be acquired before the
+// wait timeout expired.
+var ErrLockTimeout = errors.New("timed out waiting for lock")
+
+// LockOptions controls how a lock is acquired.
+type LockOptions struct {
+	// Shared requests a read lock that other readers may hold at the same
+	// time. Writers always take an exclusive lock.
+	Shared bool
+	// Timeout bounds how long to wait for a conflicting holder to go away.
+	// Zero means $TODO_CLI_LOCK_TIMEOUT, or 5s when that is unset.
+	Timeout time.Duration
+}
+
+// LockOwner is recorded in the lock file by exclusive holders so waiters can
+// report who is in the way and detect owners that died without cleaning up.
+type LockOwner struct {
+	PID        int       `json:"pid"`
+	Host       string    `json:"host"`
+	AcquiredAt time.Time `json:"acquired_at"`
+}
+
+func (o LockOwner) String() string {
+	return fmt.Sprintf("pid %d on %s since %s", o.PID, o.Host, o.AcquiredAt.Format(time.RFC3339))
+}
+
+// Lock is a held advisory lock on the tasks.lock file next to the JSON file.
+type Lock struct {
+	path   string
+	shared bool
+	held   bool
+	file   *os.File
+}
+
+// AcquireLock takes an exclusive lock for the storage at jsonPath, waiting up
+// to the default timeout for other holders.
+func AcquireLock(jsonPath string) (*Lock, error) {
+	return AcquireLockWithOptions(jsonPath, LockOptions{})
+}
+
+// AcquireSharedLock takes a read lock for the storage at jsonPath.
+func AcquireSharedLock(jsonPath string) (*Lock, error) {
+	return AcquireLockWithOptions(jsonPath, LockOptions{Shared: true})
+}
+
+// AcquireLockWithOptions locks the storage at jsonPath as described by opts.
+func AcquireLockWithOptions(jsonPath string, opts LockOptions) (*Lock, error) {
+	dir := filepath.Dir(jsonPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = lockTimeoutFromEnv()
+	}
+	l := &Lock{path: LockPath(jsonPath), shared: opts.Shared}
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := l.try()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return l, nil
+		}
+		if time.Now().After(deadline) {
+			l.release()
+			if owner, err := readLockOwner(l.path); err == nil {
+				return nil, fmt.Errorf("%w after %s: held by %s", ErrLockTimeout, timeout, owner)
+			}
+			return nil, fmt.Errorf("%w after %s: held by another process", ErrLockTimeout, timeout)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// LockPath returns the lock file guarding jsonPath. Every tasks file in one
+// directory shares it, so all named lists under lists/ share a lock.
+func LockPath(jsonPath string) string {
+	return filepath.Join(filepath.Dir(jsonPath), lockName)
+}
+
+// Release drops the lock. It is safe to call on a nil or released lock.
+func (l *Lock) Release() {
+	if l == nil || l.path == "" {
+		return
+	}
+	l.release()
+	l.path = ""
+}
+
+func lockTimeoutFromEnv() time.Duration {
+	if v := os.Getenv(envLockTimeout); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultLockTimeout
+}
+
+func currentOwner() LockOwner {
+	host, _ := os.Hostname()
+	return LockOwner{PID: os.Getpid(), Host: host, AcquiredAt: time.Now().UTC()}
+}
+
+func readLockOwner(path string) (LockOwner, error) {
+	var o LockOwner
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return o, err
+	}
+	if err := json.Unmarshal(b, &o); err != nil {
+		return o, err
+	}
+	if o.PID == 0 {
+		return o, errors.New("lock file has no owner")
+	}
+	return o, nil
+}
+
+// stale reports whether the recorded owner can no longer be holding the lock:
+// it ran on this host and its process is gone.
+func (o LockOwner) stale() bool {
+	host, err := os.Hostname()
+	if err != nil || host != o.Host {
+		return false
+	}
+	return !processAlive(o.PID)
+}
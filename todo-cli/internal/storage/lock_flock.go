@@ -0,0 +1,74 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"syscall"
+)
+
+// try attempts a non-blocking flock on the lock file. The kernel drops flock
+// locks when the holder exits, so a crashed process can never leave a lock
+// behind; the owner record is informational on these platforms.
+func (l *Lock) try() (bool, error) {
+	if l.file == nil {
+		f, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0o644)
+		if err != nil {
+			return false, err
+		}
+		l.file = f
+	}
+	how := syscall.LOCK_EX
+	if l.shared {
+		how = syscall.LOCK_SH
+	}
+	for {
+		err := syscall.Flock(int(l.file.Fd()), how|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return false, nil
+		}
+		l.release()
+		return false, err
+	}
+	l.held = true
+	if !l.shared {
+		// Only exclusive holders record themselves; readers would race each
+		// other for the same file contents.
+		b, _ := json.Marshal(currentOwner())
+		if err := l.file.Truncate(0); err == nil {
+			_, _ = l.file.WriteAt(b, 0)
+		}
+	}
+	return true, nil
+}
+
+// release clears the owner record, unlocks and closes the file. The file
+// itself stays in place: removing it would let a waiter lock an unlinked
+// inode while a newcomer locks a fresh one.
+func (l *Lock) release() {
+	if l.file == nil {
+		return
+	}
+	if l.held {
+		if !l.shared {
+			_ = l.file.Truncate(0)
+		}
+		_ = syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	}
+	_ = l.file.Close()
+	l.file = nil
+	l.held = false
+}
+
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,81 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// legacyStaleAfter is how old a lock file without an owner record (as written
+// by earlier versions) must be before it is considered abandoned.
+const legacyStaleAfter = time.Minute
+
+// reclaimName is held while removing a stale lock file. Its .lock suffix keeps
+// it out of synced repositories like the lock itself.
+const reclaimName = "tasks.reclaim.lock"
+
+// try creates the lock file exclusively. Without flock there is no kernel
+// cleanup, so a lock whose recorded owner is no longer running on this host is
+// reclaimed. Shared locks are treated as exclusive.
+func (l *Lock) try() (bool, error) {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err == nil {
+		b, _ := json.Marshal(currentOwner())
+		_, _ = f.Write(b)
+		_ = f.Close()
+		l.held = true
+		return true, nil
+	}
+	if !errors.Is(err, os.ErrExist) {
+		return false, err
+	}
+	owner, oerr := readLockOwner(l.path)
+	switch {
+	case oerr == nil && owner.stale():
+		l.reclaim(func() bool {
+			again, err := readLockOwner(l.path)
+			return err == nil && again.PID == owner.PID && again.Host == owner.Host && again.AcquiredAt.Equal(owner.AcquiredAt)
+		})
+	case oerr != nil:
+		if info, serr := os.Stat(l.path); serr == nil && time.Since(info.ModTime()) > legacyStaleAfter {
+			l.reclaim(func() bool {
+				again, err := os.Stat(l.path)
+				return err == nil && again.ModTime().Equal(info.ModTime())
+			})
+		}
+	}
+	return false, nil
+}
+
+// reclaim removes the stale lock file if unchanged still reports that it holds
+// what the caller saw. Waiters that saw the same stale lock take turns through
+// an exclusively created reclaim file, so a lock taken afresh by the first one
+// is never removed by the next.
+func (l *Lock) reclaim(unchanged func() bool) {
+	token := filepath.Join(filepath.Dir(l.path), reclaimName)
+	f, err := os.OpenFile(token, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		// A waiter that died while reclaiming leaves its token behind.
+		if info, serr := os.Stat(token); serr == nil && time.Since(info.ModTime()) > legacyStaleAfter {
+			_ = os.Remove(token)
+		}
+		return
+	}
+	_ = f.Close()
+	defer os.Remove(token)
+	if unchanged() {
+		_ = os.Remove(l.path)
+	}
+}
+
+func (l *Lock) release() {
+	if !l.held {
+		return
+	}
+	_ = os.Remove(l.path)
+	l.held = false
+}
@@ -0,0 +1,104 @@
+package storage_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+func TestExclusiveLockTimesOutAndReportsOwner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+
+	held, err := storage.AcquireLock(path)
+	if err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+
+	_, err = storage.AcquireLockWithOptions(path, storage.LockOptions{Timeout: 50 * time.Millisecond})
+	if !errors.Is(err, storage.ErrLockTimeout) {
+		t.Fatalf("expected ErrLockTimeout, got %v", err)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("pid %d", os.Getpid())) {
+		t.Fatalf("expected owner pid in error, got %v", err)
+	}
+
+	held.Release()
+	again, err := storage.AcquireLockWithOptions(path, storage.LockOptions{Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("expected lock to be free after release: %v", err)
+	}
+	again.Release()
+}
+
+func TestSharedLocksCoexistButBlockWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	short := 50 * time.Millisecond
+
+	r1, err := storage.AcquireSharedLock(path)
+	if err != nil {
+		t.Fatalf("first shared lock: %v", err)
+	}
+	r2, err := storage.AcquireLockWithOptions(path, storage.LockOptions{Shared: true, Timeout: short})
+	if err != nil {
+		t.Fatalf("second shared lock should not block: %v", err)
+	}
+	if _, err := storage.AcquireLockWithOptions(path, storage.LockOptions{Timeout: short}); !errors.Is(err, storage.ErrLockTimeout) {
+		t.Fatalf("expected writer to wait for readers, got %v", err)
+	}
+	r1.Release()
+	r2.Release()
+	w, err := storage.AcquireLockWithOptions(path, storage.LockOptions{Timeout: short})
+	if err != nil {
+		t.Fatalf("writer should get the lock once readers leave: %v", err)
+	}
+	w.Release()
+}
+
+func TestLockSerializesConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	const writers = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l, err := storage.AcquireLock(path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer l.Release()
+			list, err := storage.LoadTasks(path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			errs <- storage.SaveTasks(path, tasks.Add(list, fmt.Sprintf("task %d", i)))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("writer failed: %v", err)
+		}
+	}
+
+	list, err := storage.LoadTasks(path)
+	if err != nil {
+		t.Fatalf("LoadTasks: %v", err)
+	}
+	if len(list) != writers {
+		t.Fatalf("expected %d tasks, got %d: lost updates", writers, len(list))
+	}
+}
@@ -0,0 +1,114 @@
+package storage_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+)
+
+func seedLockOwner(t *testing.T, dir string, pid int) {
+	t.Helper()
+	host, _ := os.Hostname()
+	b, _ := json.Marshal(storage.LockOwner{PID: pid, Host: host, AcquiredAt: time.Now().Add(-time.Hour)})
+	if err := os.WriteFile(filepath.Join(dir, "tasks.lock"), b, 0o644); err != nil {
+		t.Fatalf("seed lock: %v", err)
+	}
+}
+
+func TestLockReclaimsStaleOwner(t *testing.T) {
+	// A process that has exited left its owner record behind.
+	child := exec.Command(os.Args[0], "-test.run=^$")
+	if err := child.Run(); err != nil {
+		t.Fatalf("run child: %v", err)
+	}
+	dir := t.TempDir()
+	seedLockOwner(t, dir, child.Process.Pid)
+
+	l, err := storage.AcquireLockWithOptions(filepath.Join(dir, "tasks.json"), storage.LockOptions{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("expected stale lock to be reclaimed: %v", err)
+	}
+	l.Release()
+}
+
+func TestLockConcurrentReclaimersTakeTurns(t *testing.T) {
+	child := exec.Command(os.Args[0], "-test.run=^$")
+	if err := child.Run(); err != nil {
+		t.Fatalf("run child: %v", err)
+	}
+	dir := t.TempDir()
+	seedLockOwner(t, dir, child.Process.Pid)
+
+	// Every waiter sees the same stale owner. Once one has reclaimed and
+	// taken the lock, the others must wait for it instead of removing it.
+	var holders, overlaps atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, err := storage.AcquireLockWithOptions(filepath.Join(dir, "tasks.json"), storage.LockOptions{Timeout: 10 * time.Second})
+			if err != nil {
+				t.Errorf("acquire: %v", err)
+				return
+			}
+			if holders.Add(1) > 1 {
+				overlaps.Add(1)
+			}
+			time.Sleep(20 * time.Millisecond)
+			holders.Add(-1)
+			l.Release()
+		}()
+	}
+	wg.Wait()
+	if n := overlaps.Load(); n > 0 {
+		t.Fatalf("lock was held by two waiters at once %d times", n)
+	}
+}
+
+func TestLockLeavesReclaimToTheWaiterAlreadyDoingIt(t *testing.T) {
+	child := exec.Command(os.Args[0], "-test.run=^$")
+	if err := child.Run(); err != nil {
+		t.Fatalf("run child: %v", err)
+	}
+	dir := t.TempDir()
+	seedLockOwner(t, dir, child.Process.Pid)
+	token := filepath.Join(dir, "tasks.reclaim.lock")
+	if err := os.WriteFile(token, nil, 0o644); err != nil {
+		t.Fatalf("seed reclaim token: %v", err)
+	}
+
+	_, err := storage.AcquireLockWithOptions(filepath.Join(dir, "tasks.json"), storage.LockOptions{Timeout: 100 * time.Millisecond})
+	if !errors.Is(err, storage.ErrLockTimeout) {
+		t.Fatalf("expected to wait for the other reclaimer, got %v", err)
+	}
+
+	// A token left by a waiter that died midway is cleared after a while.
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(token, old, old); err != nil {
+		t.Fatalf("age reclaim token: %v", err)
+	}
+	l, err := storage.AcquireLockWithOptions(filepath.Join(dir, "tasks.json"), storage.LockOptions{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("expected stale lock to be reclaimed: %v", err)
+	}
+	l.Release()
+}
+
+func TestLockKeepsLiveOwner(t *testing.T) {
+	dir := t.TempDir()
+	seedLockOwner(t, dir, os.Getpid())
+
+	_, err := storage.AcquireLockWithOptions(filepath.Join(dir, "tasks.json"), storage.LockOptions{Timeout: 100 * time.Millisecond})
+	if !errors.Is(err, storage.ErrLockTimeout) {
+		t.Fatalf("expected a live owner to keep the lock, got %v", err)
+	}
+}
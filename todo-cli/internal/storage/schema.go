@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+// SchemaVersion is the version of the tasks.json envelope written by this
+// build. Version 1 is the legacy bare array of tasks.
+const SchemaVersion = 2
+
+// ErrNewerSchema is returned when a file was written by a newer todo-cli that
+// this build does not know how to read.
+var ErrNewerSchema = errors.New("tasks file uses a newer schema version")
+
+// Meta carries bookkeeping about the file itself rather than its tasks.
+type Meta struct {
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// Document is the versioned envelope stored in tasks.json.
+type Document struct {
+	Version int          `json:"version"`
+	Meta    Meta         `json:"meta"`
+	Tasks   []tasks.Task `json:"tasks"`
+}
+
+// migration upgrades a raw document from version from to from+1.
+type migration struct {
+	from int
+	up   func(raw []byte) ([]byte, error)
+}
+
+// migrations is the ordered upgrade chain; each step must produce valid input
+// for the next one. Add a step here whenever SchemaVersion is bumped.
+var migrations = []migration{
+	{from: 1, up: wrapLegacyArray},
+}
+
+// wrapLegacyArray turns a version 1 bare array into a version 2 envelope.
+// Tasks are carried over as raw JSON so no field is lost on the way.
+func wrapLegacyArray(raw []byte) ([]byte, error) {
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []json.RawMessage{}
+	}
+	return json.Marshal(struct {
+		Version int               `json:"version"`
+		Tasks   []json.RawMessage `json:"tasks"`
+	}{Version: 2, Tasks: list})
+}
+
+// detectVersion reports the schema version of raw tasks.json content.
+func detectVersion(raw []byte) (int, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return 1, nil
+	}
+	var probe struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(trimmed, &probe); err != nil {
+		return 0, err
+	}
+	if probe.Version < 1 {
+		return 0, fmt.Errorf("tasks file has no schema version")
+	}
+	return probe.Version, nil
+}
+
+// migrate runs raw through the migration chain up to SchemaVersion and
+// returns the upgraded content together with the version it started at.
+func migrate(raw []byte) ([]byte, int, error) {
+	from, err := detectVersion(raw)
+	if err != nil {
+		return nil, 0, err
+	}
+	if from > SchemaVersion {
+		return nil, from, fmt.Errorf("%w: %d (this build supports %d)", ErrNewerSchema, from, SchemaVersion)
+	}
+	version := from
+	for _, m := range migrations {
+		if m.from != version {
+			continue
+		}
+		if raw, err = m.up(raw); err != nil {
+			return nil, from, fmt.Errorf("migrate schema v%d: %w", m.from, err)
+		}
+		version++
+	}
+	if version != SchemaVersion {
+		return nil, from, fmt.Errorf("no migration path from schema v%d", version)
+	}
+	return raw, from, nil
+}
+
+// backupPath returns a file name for keeping a copy of a pre-migration file
+// that does not clobber an earlier backup.
+func backupPath(jsonPath string, version int) string {
+	p := fmt.Sprintf("%s.v%d.bak", jsonPath, version)
+	if _, err := os.Stat(p); errors.Is(err, os.ErrNotExist) {
+		return p
+	}
+	return fmt.Sprintf("%s.%d", p, time.Now().UnixNano())
+}
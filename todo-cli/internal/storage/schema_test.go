@@ -0,0 +1,122 @@
+package storage_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+)
+
+func TestSaveMigratesLegacyArrayAndKeepsBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.json")
+
+	legacy := `[{"id": 1, "text": "old task", "done": true}]`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatalf("failed to write legacy file: %v", err)
+	}
+
+	doc, err := storage.LoadDocument(path)
+	if err != nil {
+		t.Fatalf("LoadDocument returned error: %v", err)
+	}
+	if doc.Version != storage.SchemaVersion {
+		t.Fatalf("expected version %d, got %d", storage.SchemaVersion, doc.Version)
+	}
+	if len(doc.Tasks) != 1 || doc.Tasks[0].Text != "old task" || !doc.Tasks[0].Done {
+		t.Fatalf("unexpected tasks after migration: %+v", doc.Tasks)
+	}
+
+	// Loading only migrates in memory; readers never write.
+	if raw, err := os.ReadFile(path); err != nil || string(raw) != legacy {
+		t.Fatalf("load rewrote the legacy file: %q, %v", raw, err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.bak*")); len(matches) != 0 {
+		t.Fatalf("load made a backup: %v", matches)
+	}
+
+	// The first save upgrades the file and keeps the original.
+	if err := storage.SaveDocument(path, doc); err != nil {
+		t.Fatalf("SaveDocument returned error: %v", err)
+	}
+	backup, err := os.ReadFile(path + ".v1.bak")
+	if err != nil {
+		t.Fatalf("expected pre-migration backup: %v", err)
+	}
+	if string(backup) != legacy {
+		t.Fatalf("backup content mismatch: %q", backup)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read migrated file: %v", err)
+	}
+	var envelope struct {
+		Version int               `json:"version"`
+		Tasks   []json.RawMessage `json:"tasks"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		t.Fatalf("migrated file is not an envelope: %v\n%s", err, raw)
+	}
+	if envelope.Version != storage.SchemaVersion || len(envelope.Tasks) != 1 {
+		t.Fatalf("unexpected migrated file: %s", raw)
+	}
+
+	// Later saves find the current schema: no further backups appear.
+	if err := storage.SaveTasks(path, doc.Tasks); err != nil {
+		t.Fatalf("second save: %v", err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.bak*"))
+	if len(matches) != 1 {
+		t.Fatalf("expected exactly one backup, got %v", matches)
+	}
+}
+
+func TestLoadRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "tasks": []}`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := storage.LoadTasks(path); !errors.Is(err, storage.ErrNewerSchema) {
+		t.Fatalf("expected ErrNewerSchema, got %v", err)
+	}
+}
+
+func TestSaveRefusesNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	newer := []byte(`{"version": 99, "tasks": []}`)
+	if err := os.WriteFile(path, newer, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := storage.SaveTasks(path, nil); !errors.Is(err, storage.ErrNewerSchema) {
+		t.Fatalf("expected ErrNewerSchema, got %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(raw) != string(newer) {
+		t.Fatalf("newer file was overwritten: %s", raw)
+	}
+}
+
+func TestSaveWritesVersionedEnvelope(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	if err := storage.SaveTasks(path, nil); err != nil {
+		t.Fatalf("SaveTasks: %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	var doc storage.Document
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if doc.Version != storage.SchemaVersion || doc.Meta.UpdatedAt.IsZero() || doc.Tasks == nil {
+		t.Fatalf("unexpected envelope: %s", raw)
+	}
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,19 +12,14 @@ import (
 	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
 )
 
-const (
-	envPath    = "TODO_CLI_PATH"
-	defaultRel = ".todo-cli/tasks.json"
-	lockName   = "tasks.lock"
-)
+// EnvPath names the environment variable that overrides DefaultPath.
+const EnvPath = "TODO_CLI_PATH"
 
-type Lock struct {
-	path string
-}
+const defaultRel = ".todo-cli/tasks.json"
 
 // DefaultPath returns the file path for tasks.json: $TODO_CLI_PATH or $HOME/.todo-cli/tasks.json.
 func DefaultPath() (string, error) {
-	if p := os.Getenv(envPath); p != "" {
+	if p := os.Getenv(EnvPath); p != "" {
 		return p, nil
 	}
 	home, err := os.UserHomeDir()
@@ -39,57 +35,82 @@ func ensureDir(file string) error {
 	return os.MkdirAll(dir, 0o755)
 }
 
-// AcquireLock tries to create a lockfile next to the JSON file.
-// Best-effort: if it already exists, return an error.
-func AcquireLock(jsonPath string) (*Lock, error) {
-	dir := filepath.Dir(jsonPath)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return nil, err
-	}
-	lp := filepath.Join(dir, lockName)
-	f, err := os.OpenFile(lp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+// LoadTasks loads tasks from jsonPath. If the file doesn't exist, returns empty list.
+func LoadTasks(jsonPath string) ([]tasks.Task, error) {
+	doc, err := LoadDocument(jsonPath)
 	if err != nil {
-		return nil, fmt.Errorf("another process may be running (lock exists): %w", err)
+		return nil, err
 	}
-	// tiny bit of metadata (timestamp) to help debugging, ignore errors
-	_, _ = f.WriteString(time.Now().Format(time.RFC3339Nano))
-	_ = f.Close()
-	return &Lock{path: lp}, nil
+	return doc.Tasks, nil
 }
 
-// Release removes the lock file.
-func (l *Lock) Release() {
-	if l == nil || l.path == "" {
-		return
+// SaveTasks writes tasks to jsonPath (pretty JSON), keeping the document's
+// existing metadata.
+func SaveTasks(jsonPath string, list []tasks.Task) error {
+	doc, err := LoadDocument(jsonPath)
+	if err != nil {
+		return err
 	}
-	_ = os.Remove(l.path)
+	doc.Tasks = list
+	return SaveDocument(jsonPath, doc)
 }
 
-// LoadTasks loads tasks from jsonPath. If the file doesn't exist, returns empty list.
-func LoadTasks(jsonPath string) ([]tasks.Task, error) {
+// LoadDocument reads the versioned document at jsonPath. Files written with an
+// older schema are upgraded through the migration chain in memory only; the
+// file itself is upgraded by the next SaveDocument, which runs under the
+// exclusive lock. A missing or empty file yields an empty document.
+func LoadDocument(jsonPath string) (Document, error) {
 	b, err := os.ReadFile(jsonPath)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return nil, nil
+			return Document{Version: SchemaVersion}, nil
 		}
-		return nil, err
+		return Document{}, err
 	}
+	doc, _, err := decodeDocument(b)
+	return doc, err
+}
+
+// ParseTasks decodes the content of a tasks file of any schema version
+// without touching the disk, e.g. a version read from git.
+func ParseTasks(b []byte) ([]tasks.Task, error) {
+	doc, _, err := decodeDocument(b)
+	return doc.Tasks, err
+}
+
+// decodeDocument upgrades b to the current schema and decodes it. It also
+// returns the version b was written with. Empty content is an empty document.
+func decodeDocument(b []byte) (Document, int, error) {
 	if len(b) == 0 {
-		return nil, nil
+		return Document{Version: SchemaVersion}, SchemaVersion, nil
 	}
-	var list []tasks.Task
-	if err := json.Unmarshal(b, &list); err != nil {
-		return nil, err
+	upgraded, from, err := migrate(b)
+	if err != nil {
+		return Document{}, 0, err
+	}
+	var doc Document
+	if err := json.Unmarshal(upgraded, &doc); err != nil {
+		return Document{}, 0, err
 	}
-	return list, nil
+	return doc, from, nil
 }
 
-// SaveTasks writes tasks to jsonPath (pretty JSON).
-func SaveTasks(jsonPath string, list []tasks.Task) error {
+// SaveDocument writes doc to jsonPath at the current schema version, stamping
+// the update time. A file of an older schema is first kept next to it as
+// tasks.json.v<N>.bak. The write goes through a temp file and rename.
+func SaveDocument(jsonPath string, doc Document) error {
 	if err := ensureDir(jsonPath); err != nil {
 		return err
 	}
-	b, err := json.MarshalIndent(list, "", "  ")
+	if err := backupOlderSchema(jsonPath); err != nil {
+		return err
+	}
+	doc.Version = SchemaVersion
+	doc.Meta.UpdatedAt = time.Now().UTC()
+	if doc.Tasks == nil {
+		doc.Tasks = []tasks.Task{}
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -99,3 +120,31 @@ func SaveTasks(jsonPath string, list []tasks.Task) error {
 	}
 	return os.Rename(tmp, jsonPath)
 }
+
+// backupOlderSchema copies jsonPath to a backup when it holds an older schema
+// that the next write is about to replace, and refuses to replace a file from
+// a newer schema. Unreadable content is left to the caller, as before
+// migrations existed.
+func backupOlderSchema(jsonPath string) error {
+	b, err := os.ReadFile(jsonPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	from, err := detectVersion(b)
+	if err != nil || from == SchemaVersion {
+		return nil
+	}
+	if from > SchemaVersion {
+		return fmt.Errorf("%w: %d (this build supports %d)", ErrNewerSchema, from, SchemaVersion)
+	}
+	if err := os.WriteFile(backupPath(jsonPath, from), b, 0o644); err != nil {
+		return fmt.Errorf("backup before migration: %w", err)
+	}
+	return nil
+}
@@ -63,3 +63,28 @@ func TestLoadTasksFromEmptyFile(t *testing.T) {
 		t.Fatalf("expected no tasks, got %d", len(tasks))
 	}
 }
+
+func TestLoadTasksFromLegacyFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.json")
+
+	legacy := `[
+  { "id": 1, "text": "old task", "done": false },
+  { "id": 2, "text": "finished", "done": true }
+]`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatalf("failed to write legacy file: %v", err)
+	}
+
+	got, err := storage.LoadTasks(path)
+	if err != nil {
+		t.Fatalf("LoadTasks returned error: %v", err)
+	}
+	want := []tasks.Task{
+		{ID: 1, Text: "old task"},
+		{ID: 2, Text: "finished", Done: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("loaded tasks mismatch\nwant: %#v\ngot:  %#v", want, got)
+	}
+}
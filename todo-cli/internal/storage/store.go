@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+const envBackend = "TODO_CLI_BACKEND"
+
+// Backend names accepted by Open and $TODO_CLI_BACKEND.
+const (
+	BackendJSON   = "json"
+	BackendJSONL  = "jsonl"
+	BackendMemory = "memory"
+)
+
+// Store persists a task list. Callers are expected to hold the lock for the
+// storage path (see AcquireLock) around a Load/Save pair.
+type Store interface {
+	Load() ([]tasks.Task, error)
+	Save(list []tasks.Task) error
+}
+
+// DefaultBackend returns $TODO_CLI_BACKEND, or the JSON backend when unset.
+func DefaultBackend() string {
+	if b := os.Getenv(envBackend); b != "" {
+		return b
+	}
+	return BackendJSON
+}
+
+// Open returns the store for backend rooted at path. The JSON backend uses
+// path as is; the event log swaps a .json extension for .jsonl so both can
+// share a directory; memory stores are shared per path for the life of the
+// process.
+func Open(backend, path string) (Store, error) {
+	switch strings.ToLower(backend) {
+	case "", BackendJSON:
+		return NewJSONStore(path), nil
+	case BackendJSONL:
+		return NewEventLogStore(EventLogPath(path)), nil
+	case BackendMemory:
+		v, _ := memoryStores.LoadOrStore(path, NewMemoryStore())
+		return v.(*MemoryStore), nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q (want %s, %s or %s)", backend, BackendJSON, BackendJSONL, BackendMemory)
+}
+
+// EventLogPath maps a tasks.json path to its event log sibling, tasks.jsonl.
+func EventLogPath(path string) string {
+	if filepath.Ext(path) == ".json" {
+		return path + "l"
+	}
+	return path
+}
+
+var memoryStores sync.Map // path -> *MemoryStore
+
+// JSONStore keeps the whole list in a single versioned JSON document.
+type JSONStore struct {
+	path string
+}
+
+func NewJSONStore(path string) *JSONStore { return &JSONStore{path: path} }
+
+func (s *JSONStore) Load() ([]tasks.Task, error) { return LoadTasks(s.path) }
+
+func (s *JSONStore) Save(list []tasks.Task) error { return SaveTasks(s.path, list) }
+
+// MemoryStore keeps tasks in process memory. It is meant for tests.
+type MemoryStore struct {
+	mu   sync.Mutex
+	list []tasks.Task
+}
+
+// NewMemoryStore returns a store preloaded with a copy of initial.
+func NewMemoryStore(initial ...tasks.Task) *MemoryStore {
+	return &MemoryStore{list: tasks.CloneList(initial)}
+}
+
+func (s *MemoryStore) Load() ([]tasks.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return tasks.CloneList(s.list), nil
+}
+
+func (s *MemoryStore) Save(list []tasks.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = tasks.CloneList(list)
+	return nil
+}
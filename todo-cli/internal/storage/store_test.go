@@ -0,0 +1,129 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/storage"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+func TestStoreBackendsRoundTrip(t *testing.T) {
+	backends := []string{storage.BackendJSON, storage.BackendJSONL, storage.BackendMemory}
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tasks.json")
+			store, err := storage.Open(backend, path)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+
+			empty, err := store.Load()
+			if err != nil || len(empty) != 0 {
+				t.Fatalf("expected empty store, got %v (err %v)", empty, err)
+			}
+
+			list := tasks.Add(nil, "write tests")
+			list = tasks.Add(list, "implement features")
+			list = tasks.Add(list, "ship it")
+			if err := store.Save(list); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			list, _ = tasks.MarkDone(list, 2)
+			list, _ = tasks.Remove(list, 1)
+			if err := store.Save(list); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			reopened, err := storage.Open(backend, path)
+			if err != nil {
+				t.Fatalf("reopen: %v", err)
+			}
+			got, err := reopened.Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			want := []tasks.Task{
+				{ID: 2, Text: "implement features", Done: true},
+				{ID: 3, Text: "ship it"},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("loaded tasks mismatch\nwant: %#v\ngot:  %#v", want, got)
+			}
+		})
+	}
+}
+
+func TestEventLogAppendsOnlyChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	store := storage.NewEventLogStore(path)
+
+	list := tasks.Add(nil, "one")
+	list = tasks.Add(list, "two")
+	if err := store.Save(list); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	list, _ = tasks.MarkDone(list, 1)
+	if err := store.Save(list); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := store.Save(tasks.Clear(list)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	events, err := store.Events()
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+	var ops []string
+	for _, ev := range events {
+		ops = append(ops, ev.Op)
+	}
+	if got := strings.Join(ops, ","); got != "put,put,put,delete,delete" {
+		t.Fatalf("unexpected event sequence %s", got)
+	}
+
+	// A torn trailing line from an interrupted append is ignored.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, _ = f.WriteString(`{"op":"put","task":{"id":9`)
+	_ = f.Close()
+	got, err := store.Load()
+	if err != nil {
+		t.Fatalf("expected torn line to be ignored, got %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("expected empty list after clear, got %+v", got)
+	}
+
+	// Saving after the tear drops the fragment instead of appending to it.
+	if err := store.Save(tasks.Add(got, "three")); err != nil {
+		t.Fatalf("Save after torn line: %v", err)
+	}
+	got, err = store.Load()
+	if err != nil {
+		t.Fatalf("Load after torn line: %v", err)
+	}
+	if len(got) != 1 || got[0].Text != "three" {
+		t.Fatalf("expected only the new task, got %+v", got)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if strings.Contains(string(b), `"id":9`) {
+		t.Fatalf("torn fragment kept in log:\n%s", b)
+	}
+}
+
+func TestOpenRejectsUnknownBackend(t *testing.T) {
+	if _, err := storage.Open("sqlite", "tasks.json"); err == nil {
+		t.Fatalf("expected error for unknown backend")
+	}
+}
@@ -0,0 +1,190 @@
+package tasks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout is the layout used to print and parse calendar dates.
+const DateLayout = "2006-01-02"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// ParseDue resolves a due date relative to now. It understands absolute dates
+// (2006-01-02, "2006-01-02 15:04", RFC 3339), the words today, tomorrow and
+// yesterday, weekday names (the next such day), and offsets such as +3d, +2w,
+// +1mo, +4h or +30min. Date-only values, including offsets in whole days,
+// resolve to midnight in now's location.
+func ParseDue(s string, now time.Time) (time.Time, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	today := StartOfDay(now)
+	switch v {
+	case "":
+		return time.Time{}, fmt.Errorf("empty due date")
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+	if wd, ok := weekdays[v]; ok {
+		days := (int(wd) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return today.AddDate(0, 0, days), nil
+	}
+	if strings.HasPrefix(v, "+") {
+		o, err := parseOffset(v[1:])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid due offset %q: %v", s, err)
+		}
+		if o.clock == 0 {
+			return today.AddDate(0, o.months, o.days), nil
+		}
+		return now.AddDate(0, o.months, o.days).Add(o.clock), nil
+	}
+	for _, layout := range []string{DateLayout, "2006-01-02 15:04", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(s), now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, strings.TrimSpace(s)); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid due date %q", s)
+}
+
+// StartOfDay truncates t to midnight in its own location.
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// IsDateOnly reports whether t sits exactly at midnight, which is how
+// ParseDue stores due dates that carry no time of day.
+func IsDateOnly(t time.Time) bool {
+	return t.Equal(StartOfDay(t))
+}
+
+// FormatDue renders a due date as a bare date, or with the time of day when
+// one was given.
+func FormatDue(t time.Time) string {
+	t = t.Local()
+	if IsDateOnly(t) {
+		return t.Format(DateLayout)
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// Overdue reports whether an open task has passed its due date. A date-only
+// due date becomes overdue once that whole day has passed.
+func (t Task) Overdue(now time.Time) bool {
+	if t.Done || t.Due == nil {
+		return false
+	}
+	deadline := *t.Due
+	if IsDateOnly(deadline.In(now.Location())) {
+		deadline = deadline.AddDate(0, 0, 1)
+	}
+	return !now.Before(deadline)
+}
+
+// ParseRemind resolves a reminder time. Besides everything ParseDue accepts,
+// it understands offsets before the due date such as -30min, -1h30min, -2d or
+// -1w, which need a due date.
+func ParseRemind(s string, due *time.Time, now time.Time) (time.Time, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	if !strings.HasPrefix(v, "-") {
+		return ParseDue(s, now)
+	}
+	if due == nil {
+		return time.Time{}, fmt.Errorf("reminder %q is relative to the due date, but the task has none", s)
+	}
+	o, err := parseOffset(v[1:])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid reminder offset %q: %v", s, err)
+	}
+	return due.AddDate(0, -o.months, -o.days).Add(-o.clock), nil
+}
+
+// offset is a relative time as written after the sign of a due date or
+// reminder. Months and days are kept apart from clock time so they follow the
+// calendar.
+type offset struct {
+	months, days int
+	clock        time.Duration
+}
+
+// parseOffset reads one or more number-unit pairs such as 3d or 1h30min, with
+// the units min, h, d, w and mo. A bare m is refused because it reads as
+// minutes in one place and months in another.
+func parseOffset(s string) (offset, error) {
+	var o offset
+	if s == "" {
+		return o, fmt.Errorf("want e.g. 30min, 4h, 3d, 2w or 1mo")
+	}
+	for s != "" {
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		j := i
+		for j < len(s) && (s[j] < '0' || s[j] > '9') {
+			j++
+		}
+		n, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return o, fmt.Errorf("want a number before %q", s[i:j])
+		}
+		switch unit := s[i:j]; unit {
+		case "min":
+			o.clock += time.Duration(n) * time.Minute
+		case "h":
+			o.clock += time.Duration(n) * time.Hour
+		case "d":
+			o.days += n
+		case "w":
+			o.days += 7 * n
+		case "mo":
+			o.months += n
+		case "m":
+			return o, fmt.Errorf("use min for minutes or mo for months")
+		default:
+			return o, fmt.Errorf("unknown unit %q (want min, h, d, w or mo)", unit)
+		}
+		s = s[j:]
+	}
+	return o, nil
+}
+
+// SetDue sets the due date of the task with the given id; nil clears it.
+func SetDue(list []Task, id int, due *time.Time) ([]Task, error) {
+	i := indexOf(list, id)
+	if i < 0 {
+		return list, ErrNotFound
+	}
+	list[i].Due = cloneTime(due)
+	return list, nil
+}
+
+// SetRemind sets the reminder of the task with the given id; nil clears it.
+func SetRemind(list []Task, id int, at *time.Time) ([]Task, error) {
+	i := indexOf(list, id)
+	if i < 0 {
+		return list, ErrNotFound
+	}
+	list[i].RemindAt = cloneTime(at)
+	return list, nil
+}
@@ -0,0 +1,108 @@
+package tasks_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+func TestParseDue(t *testing.T) {
+	// Wednesday afternoon.
+	now := time.Date(2024, time.March, 13, 15, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		in   string
+		want time.Time
+	}{
+		{"today", time.Date(2024, time.March, 13, 0, 0, 0, 0, time.UTC)},
+		{"Tomorrow", time.Date(2024, time.March, 14, 0, 0, 0, 0, time.UTC)},
+		{"+3d", time.Date(2024, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"+2w", time.Date(2024, time.March, 27, 0, 0, 0, 0, time.UTC)},
+		{"+1mo", time.Date(2024, time.April, 13, 0, 0, 0, 0, time.UTC)},
+		{"+45min", time.Date(2024, time.March, 13, 16, 15, 0, 0, time.UTC)},
+		{"+2h", time.Date(2024, time.March, 13, 17, 30, 0, 0, time.UTC)},
+		{"fri", time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC)},
+		{"wednesday", time.Date(2024, time.March, 20, 0, 0, 0, 0, time.UTC)},
+		{"2024-05-01", time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)},
+		{"2024-05-01 09:15", time.Date(2024, time.May, 1, 9, 15, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		got, err := tasks.ParseDue(tc.in, now)
+		if err != nil {
+			t.Fatalf("ParseDue(%q) returned error: %v", tc.in, err)
+		}
+		if !got.Equal(tc.want) {
+			t.Fatalf("ParseDue(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"", "soon", "+3x", "+d", "+1m", "2024-13-01"} {
+		if _, err := tasks.ParseDue(bad, now); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestOverdueTreatsDateOnlyAsWholeDay(t *testing.T) {
+	now := time.Date(2024, time.March, 13, 15, 30, 0, 0, time.UTC)
+	today := time.Date(2024, time.March, 13, 0, 0, 0, 0, time.UTC)
+	yesterday := today.AddDate(0, 0, -1)
+	earlier := time.Date(2024, time.March, 13, 9, 0, 0, 0, time.UTC)
+
+	if (tasks.Task{Due: &today}).Overdue(now) {
+		t.Fatalf("task due today should not be overdue yet")
+	}
+	if !(tasks.Task{Due: &yesterday}).Overdue(now) {
+		t.Fatalf("task due yesterday should be overdue")
+	}
+	if !(tasks.Task{Due: &earlier}).Overdue(now) {
+		t.Fatalf("task due earlier today at a set time should be overdue")
+	}
+	if (tasks.Task{Due: &yesterday, Done: true}).Overdue(now) {
+		t.Fatalf("completed tasks are never overdue")
+	}
+}
+
+func TestParseRemind(t *testing.T) {
+	now := time.Date(2024, time.March, 13, 15, 30, 0, 0, time.UTC)
+	due := time.Date(2024, time.March, 15, 9, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		in   string
+		want time.Time
+	}{
+		{"-30min", time.Date(2024, time.March, 15, 8, 30, 0, 0, time.UTC)},
+		{"-1h30min", time.Date(2024, time.March, 15, 7, 30, 0, 0, time.UTC)},
+		{"-2d", time.Date(2024, time.March, 13, 9, 0, 0, 0, time.UTC)},
+		{"-1w", time.Date(2024, time.March, 8, 9, 0, 0, 0, time.UTC)},
+		{"-1mo", time.Date(2024, time.February, 15, 9, 0, 0, 0, time.UTC)},
+		{"+2h", time.Date(2024, time.March, 13, 17, 30, 0, 0, time.UTC)},
+		{"2024-03-14 20:00", time.Date(2024, time.March, 14, 20, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		got, err := tasks.ParseRemind(tc.in, &due, now)
+		if err != nil {
+			t.Fatalf("ParseRemind(%q) returned error: %v", tc.in, err)
+		}
+		if !got.Equal(tc.want) {
+			t.Fatalf("ParseRemind(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"-", "-3x", "-d", "later"} {
+		if _, err := tasks.ParseRemind(bad, &due, now); err == nil {
+			t.Fatalf("ParseRemind(%q) should fail", bad)
+		}
+	}
+	// m would mean minutes before the due date but months after now.
+	for _, ambiguous := range []string{"-30m", "+30m"} {
+		if _, err := tasks.ParseRemind(ambiguous, &due, now); err == nil || !strings.Contains(err.Error(), "min for minutes or mo for months") {
+			t.Fatalf("ParseRemind(%q) should name both units, got %v", ambiguous, err)
+		}
+	}
+	if _, err := tasks.ParseRemind("-1h", nil, now); err == nil {
+		t.Fatal("relative reminder without a due date should fail")
+	}
+}
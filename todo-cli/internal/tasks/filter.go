@@ -0,0 +1,78 @@
+package tasks
+
+import (
+	"strings"
+	"time"
+)
+
+// Filter selects tasks for listing. Zero-valued fields do not restrict the
+// result, so the zero Filter matches every task.
+type Filter struct {
+	Tags      []string   // task must carry every tag (case-insensitive)
+	DueBefore *time.Time // task must have a due date strictly before this moment
+	Priority  *Priority  // task must have exactly this priority
+	Overdue   bool       // task must be open and past its due date
+	Done      *bool      // task must be completed (true) or open (false)
+}
+
+// Match reports whether t passes every criterion of f at time now.
+func (f Filter) Match(t Task, now time.Time) bool {
+	for _, tag := range f.Tags {
+		if !t.HasTag(tag) {
+			return false
+		}
+	}
+	if f.DueBefore != nil && (t.Due == nil || !t.Due.Before(*f.DueBefore)) {
+		return false
+	}
+	if f.Priority != nil && t.Priority != *f.Priority {
+		return false
+	}
+	if f.Overdue && !t.Overdue(now) {
+		return false
+	}
+	if f.Done != nil && t.Done != *f.Done {
+		return false
+	}
+	return true
+}
+
+// Apply returns the tasks in list matching f, preserving order.
+func (f Filter) Apply(list []Task, now time.Time) []Task {
+	var out []Task
+	for _, t := range list {
+		if f.Match(t, now) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// HasTag reports whether t carries tag, ignoring case.
+func (t Task) HasTag(tag string) bool {
+	for _, have := range t.Tags {
+		if strings.EqualFold(have, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeTags trims tags, splits comma-separated entries and drops empty or
+// duplicate (case-insensitive) values while keeping first-seen order.
+func NormalizeTags(in []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, raw := range in {
+		for _, tag := range strings.Split(raw, ",") {
+			tag = strings.TrimSpace(tag)
+			key := strings.ToLower(tag)
+			if tag == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			out = append(out, tag)
+		}
+	}
+	return out
+}
@@ -0,0 +1,279 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrCycle is matched by every CycleError.
+var ErrCycle = errors.New("dependency cycle")
+
+// CycleError reports a loop in the graph formed by blocked-by links and
+// parent/child links. The path starts and ends with the same task.
+type CycleError struct {
+	Path []int
+}
+
+func (e *CycleError) Error() string {
+	parts := make([]string, len(e.Path))
+	for i, id := range e.Path {
+		parts[i] = fmt.Sprintf("#%d", id)
+	}
+	return fmt.Sprintf("%s: %s", ErrCycle, strings.Join(parts, " -> "))
+}
+
+func (e *CycleError) Is(target error) bool { return target == ErrCycle }
+
+// OpenChildrenError is returned when completing a parent whose subtasks are
+// still open.
+type OpenChildrenError struct {
+	Parent   int
+	Children []int
+}
+
+func (e *OpenChildrenError) Error() string {
+	return fmt.Sprintf("#%d has open subtasks: %s", e.Parent, FormatIDs(e.Children))
+}
+
+// waitsFor lists what each task waits on: its blockers and its children. A
+// parent cannot finish before its subtasks, so "child blocked by its parent"
+// is a cycle just like "a blocked by b blocked by a".
+func waitsFor(list []Task) map[int][]int {
+	edges := make(map[int][]int, len(list))
+	for _, t := range list {
+		edges[t.ID] = append(edges[t.ID], t.BlockedBy...)
+		if t.Parent != 0 {
+			edges[t.Parent] = append(edges[t.Parent], t.ID)
+		}
+	}
+	return edges
+}
+
+// CheckGraph validates the parent and blocked-by links in list: every link
+// must name another existing task and the links must not form a cycle.
+func CheckGraph(list []Task) error {
+	exists := make(map[int]bool, len(list))
+	for _, t := range list {
+		exists[t.ID] = true
+	}
+	for _, t := range list {
+		if t.Parent == t.ID {
+			return &CycleError{Path: []int{t.ID, t.ID}}
+		}
+		if t.Parent != 0 && !exists[t.Parent] {
+			return fmt.Errorf("%w: parent #%d of #%d", ErrNotFound, t.Parent, t.ID)
+		}
+		for _, b := range t.BlockedBy {
+			if b == t.ID {
+				return &CycleError{Path: []int{t.ID, t.ID}}
+			}
+			if !exists[b] {
+				return fmt.Errorf("%w: blocker #%d of #%d", ErrNotFound, b, t.ID)
+			}
+		}
+	}
+
+	edges := waitsFor(list)
+	const (
+		unvisited = iota
+		visiting
+		finished
+	)
+	state := make(map[int]int, len(list))
+	var stack []int
+	var visit func(id int) error
+	visit = func(id int) error {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, next := range edges[id] {
+			switch state[next] {
+			case visiting:
+				start := 0
+				for stack[start] != next {
+					start++
+				}
+				path := append(append([]int(nil), stack[start:]...), next)
+				return &CycleError{Path: path}
+			case unvisited:
+				if err := visit(next); err != nil {
+					return err
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = finished
+		return nil
+	}
+	for _, t := range list {
+		if state[t.ID] == unvisited {
+			if err := visit(t.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// OpenBlockers returns the IDs in t.BlockedBy whose tasks are still open.
+func OpenBlockers(list []Task, t Task) []int {
+	open := make(map[int]bool, len(list))
+	for _, o := range list {
+		open[o.ID] = !o.Done
+	}
+	var out []int
+	for _, b := range t.BlockedBy {
+		if open[b] {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// OpenChildren returns the IDs of the open subtasks of id.
+func OpenChildren(list []Task, id int) []int {
+	var out []int
+	for _, t := range list {
+		if t.Parent == id && !t.Done {
+			out = append(out, t.ID)
+		}
+	}
+	return out
+}
+
+// CheckChildrenDone reports the first task in ids that is done while one of
+// its subtasks is still open.
+func CheckChildrenDone(list []Task, ids []int) error {
+	for _, id := range ids {
+		i := indexOf(list, id)
+		if i < 0 || !list[i].Done {
+			continue
+		}
+		if open := OpenChildren(list, id); len(open) > 0 {
+			return &OpenChildrenError{Parent: id, Children: open}
+		}
+	}
+	return nil
+}
+
+// Ready returns the open tasks in list that nothing holds up: every blocker is
+// done and every subtask is done.
+func Ready(list []Task) []Task {
+	var out []Task
+	for _, t := range list {
+		if !t.Done && len(OpenBlockers(list, t)) == 0 && len(OpenChildren(list, t.ID)) == 0 {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Block adds blockers to the task with the given id.
+func Block(list []Task, id int, blockers []int) ([]Task, error) {
+	i := indexOf(list, id)
+	if i < 0 {
+		return list, ErrNotFound
+	}
+	have := idSet(list[i].BlockedBy)
+	for _, b := range blockers {
+		if !have[b] {
+			have[b] = true
+			list[i].BlockedBy = append(list[i].BlockedBy, b)
+		}
+	}
+	sort.Ints(list[i].BlockedBy)
+	return list, nil
+}
+
+// Unblock removes blockers from the task with the given id.
+func Unblock(list []Task, id int, blockers []int) ([]Task, error) {
+	i := indexOf(list, id)
+	if i < 0 {
+		return list, ErrNotFound
+	}
+	drop := idSet(blockers)
+	var keep []int
+	for _, b := range list[i].BlockedBy {
+		if !drop[b] {
+			keep = append(keep, b)
+		}
+	}
+	list[i].BlockedBy = keep
+	return list, nil
+}
+
+// SetParent makes id a subtask of parent, or a top-level task when parent is
+// zero.
+func SetParent(list []Task, id, parent int) ([]Task, error) {
+	i := indexOf(list, id)
+	if i < 0 {
+		return list, ErrNotFound
+	}
+	list[i].Parent = parent
+	return list, nil
+}
+
+// detach rewrites the links that point at removed tasks: subtasks move up to
+// the removed task's parent and blocked-by entries are dropped.
+func detach(list []Task, removed map[int]Task) {
+	for i := range list {
+		for list[i].Parent != 0 {
+			gone, ok := removed[list[i].Parent]
+			if !ok {
+				break
+			}
+			list[i].Parent = gone.Parent
+		}
+		if len(list[i].BlockedBy) == 0 {
+			continue
+		}
+		var keep []int
+		for _, b := range list[i].BlockedBy {
+			if _, ok := removed[b]; !ok {
+				keep = append(keep, b)
+			}
+		}
+		list[i].BlockedBy = keep
+	}
+}
+
+// Node is a task placed in a tree: Depth counts its ancestors within the
+// listed tasks and Last marks the final child of its parent.
+type Node struct {
+	Task  Task
+	Depth int
+	Last  bool
+	// Lines holds, for each ancestor level, whether a sibling follows further
+	// down, so renderers can draw connecting lines.
+	Lines []bool
+}
+
+// Tree orders list depth-first under parents, keeping the relative order of
+// siblings. Tasks whose parent is not in list are shown at the top level.
+func Tree(list []Task) []Node {
+	present := make(map[int]bool, len(list))
+	for _, t := range list {
+		present[t.ID] = true
+	}
+	children := make(map[int][]Task)
+	var roots []Task
+	for _, t := range list {
+		if t.Parent != 0 && present[t.Parent] {
+			children[t.Parent] = append(children[t.Parent], t)
+		} else {
+			roots = append(roots, t)
+		}
+	}
+	var out []Node
+	var walk func(level []Task, depth int, lines []bool)
+	walk = func(level []Task, depth int, lines []bool) {
+		for i, t := range level {
+			last := i == len(level)-1
+			out = append(out, Node{Task: t, Depth: depth, Last: last, Lines: append([]bool(nil), lines...)})
+			walk(children[t.ID], depth+1, append(lines, !last))
+		}
+	}
+	walk(roots, 0, nil)
+	return out
+}
@@ -0,0 +1,122 @@
+package tasks_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+func TestCheckGraphDetectsCycles(t *testing.T) {
+	ok := []tasks.Task{
+		{ID: 1, Text: "trip"},
+		{ID: 2, Text: "flights", Parent: 1},
+		{ID: 3, Text: "hotel", Parent: 1, BlockedBy: []int{2}},
+	}
+	if err := tasks.CheckGraph(ok); err != nil {
+		t.Fatalf("valid graph rejected: %v", err)
+	}
+
+	cases := map[string][]tasks.Task{
+		"blocked-by loop": {
+			{ID: 1, BlockedBy: []int{3}},
+			{ID: 2, BlockedBy: []int{1}},
+			{ID: 3, BlockedBy: []int{2}},
+		},
+		"parent loop": {
+			{ID: 1, Parent: 2},
+			{ID: 2, Parent: 1},
+		},
+		"child blocked by parent": {
+			{ID: 1},
+			{ID: 2, Parent: 1, BlockedBy: []int{1}},
+		},
+		"self block": {
+			{ID: 1, BlockedBy: []int{1}},
+		},
+	}
+	for name, list := range cases {
+		err := tasks.CheckGraph(list)
+		var cycle *tasks.CycleError
+		if !errors.Is(err, tasks.ErrCycle) || !errors.As(err, &cycle) || cycle.Path[0] != cycle.Path[len(cycle.Path)-1] {
+			t.Fatalf("%s: expected a cycle, got %v", name, err)
+		}
+	}
+
+	err := tasks.CheckGraph([]tasks.Task{{ID: 1, BlockedBy: []int{9}}})
+	if !errors.Is(err, tasks.ErrNotFound) {
+		t.Fatalf("expected missing blocker error, got %v", err)
+	}
+}
+
+func TestReadyAndChildren(t *testing.T) {
+	list := []tasks.Task{
+		{ID: 1, Text: "trip"},
+		{ID: 2, Text: "flights", Parent: 1, Done: true},
+		{ID: 3, Text: "hotel", Parent: 1, BlockedBy: []int{2}},
+		{ID: 4, Text: "pack", BlockedBy: []int{3}},
+		{ID: 5, Text: "done already", Done: true},
+	}
+	var ready []int
+	for _, r := range tasks.Ready(list) {
+		ready = append(ready, r.ID)
+	}
+	if !reflect.DeepEqual(ready, []int{3}) {
+		t.Fatalf("ready: got %v, want [3]", ready)
+	}
+
+	list[0].Done = true
+	var open *tasks.OpenChildrenError
+	if err := tasks.CheckChildrenDone(list, []int{1}); !errors.As(err, &open) || !reflect.DeepEqual(open.Children, []int{3}) {
+		t.Fatalf("expected open children error, got %v", err)
+	}
+}
+
+func TestRemoveAllDetachesLinks(t *testing.T) {
+	list := []tasks.Task{
+		{ID: 1, Text: "project"},
+		{ID: 2, Text: "phase", Parent: 1},
+		{ID: 3, Text: "step", Parent: 2, BlockedBy: []int{2, 4}},
+		{ID: 4, Text: "other"},
+	}
+	list = tasks.RemoveAll(list, []int{2})
+	if len(list) != 3 || list[1].Parent != 1 || !reflect.DeepEqual(list[1].BlockedBy, []int{4}) {
+		t.Fatalf("unexpected list after remove: %+v", list)
+	}
+}
+
+func TestAdoptRemapsLinks(t *testing.T) {
+	existing := []tasks.Task{{ID: 1}, {ID: 2}}
+	moved := []tasks.Task{
+		{ID: 7, Text: "parent"},
+		{ID: 8, Text: "child", Parent: 7, BlockedBy: []int{3, 7}},
+	}
+	list, ids := tasks.Adopt(existing, moved)
+	if !reflect.DeepEqual(ids, []int{3, 4}) {
+		t.Fatalf("ids: %v", ids)
+	}
+	child := list[3]
+	if child.Parent != 3 || !reflect.DeepEqual(child.BlockedBy, []int{3}) {
+		t.Fatalf("links not remapped: %+v", child)
+	}
+}
+
+func TestTreeOrdersChildrenUnderParents(t *testing.T) {
+	list := []tasks.Task{
+		{ID: 4, Parent: 1},
+		{ID: 3},
+		{ID: 2, Parent: 1},
+		{ID: 5, Parent: 2},
+		{ID: 1},
+	}
+	var got []int
+	var depths []int
+	for _, n := range tasks.Tree(list) {
+		got = append(got, n.Task.ID)
+		depths = append(depths, n.Depth)
+	}
+	if !reflect.DeepEqual(got, []int{3, 1, 4, 2, 5}) || !reflect.DeepEqual(depths, []int{0, 0, 1, 1, 2}) {
+		t.Fatalf("tree order %v depths %v", got, depths)
+	}
+}
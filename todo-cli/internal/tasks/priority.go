@@ -0,0 +1,63 @@
+package tasks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Priority ranks how urgent a task is. The zero value means no priority was
+// set, which keeps tasks written by older versions valid.
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+)
+
+// ParsePriority accepts the names used on the command line ("low", "medium",
+// "high", "none") along with their first letters and numeric levels 0-3.
+func ParsePriority(s string) (Priority, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none", "0":
+		return PriorityNone, nil
+	case "l", "low", "1":
+		return PriorityLow, nil
+	case "m", "med", "medium", "2":
+		return PriorityMedium, nil
+	case "h", "high", "3":
+		return PriorityHigh, nil
+	}
+	return PriorityNone, fmt.Errorf("invalid priority %q (want low, medium or high)", s)
+}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityMedium:
+		return "medium"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "none"
+	}
+}
+
+// MarshalText stores priorities by name so tasks.json stays readable.
+func (p Priority) MarshalText() ([]byte, error) {
+	if p < PriorityNone || p > PriorityHigh {
+		return nil, fmt.Errorf("invalid priority %d", int(p))
+	}
+	return []byte(p.String()), nil
+}
+
+func (p *Priority) UnmarshalText(b []byte) error {
+	v, err := ParsePriority(string(b))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
@@ -0,0 +1,286 @@
+package tasks
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecurUnit is the step a recurrence advances by.
+type RecurUnit int
+
+const (
+	RecurDay RecurUnit = iota + 1
+	RecurWeek
+	RecurMonth
+)
+
+// Recurrence repeats a task every Interval units. Weekly rules may be pinned
+// to weekdays, in which case the task recurs on each of those days in every
+// Interval-th week. Monthly rules may be pinned to a day of the month; zero
+// means the day the occurrence they advance from falls on.
+type Recurrence struct {
+	Interval int
+	Unit     RecurUnit
+	Weekdays []time.Weekday
+	Day      int
+}
+
+var unitNames = map[string]RecurUnit{
+	"d": RecurDay, "day": RecurDay, "days": RecurDay,
+	"w": RecurWeek, "week": RecurWeek, "weeks": RecurWeek,
+	"m": RecurMonth, "month": RecurMonth, "months": RecurMonth,
+}
+
+// ParseRecurrence understands daily, weekly, monthly, weekdays, "every N
+// days|weeks|months" (also "every 3d"), a weekday list after a colon on
+// weekly rules, "weekly:mon,thu" or "every 2 weeks:fri", and a day of the
+// month after a colon on monthly ones, "monthly:31".
+func ParseRecurrence(s string) (Recurrence, error) {
+	v := strings.ToLower(strings.Join(strings.Fields(s), " "))
+	rule, days, hasDays := strings.Cut(v, ":")
+	var r Recurrence
+	switch rule {
+	case "daily":
+		r = Recurrence{Interval: 1, Unit: RecurDay}
+	case "weekly":
+		r = Recurrence{Interval: 1, Unit: RecurWeek}
+	case "monthly":
+		r = Recurrence{Interval: 1, Unit: RecurMonth}
+	case "weekdays":
+		if hasDays {
+			return Recurrence{}, fmt.Errorf("invalid recurrence %q", s)
+		}
+		return ParseRecurrence("weekly:mon,tue,wed,thu,fri")
+	default:
+		n, unit, err := parseEvery(rule)
+		if err != nil {
+			return Recurrence{}, fmt.Errorf("invalid recurrence %q (want daily, weekly[:mon,...], monthly or every N days|weeks|months)", s)
+		}
+		r = Recurrence{Interval: n, Unit: unit}
+	}
+	if hasDays && r.Unit == RecurMonth {
+		day, err := strconv.Atoi(strings.TrimSpace(days))
+		if err != nil || day < 1 || day > 31 {
+			return Recurrence{}, fmt.Errorf("invalid day of month %q in recurrence", days)
+		}
+		r.Day = day
+		return r, nil
+	}
+	if hasDays {
+		if r.Unit != RecurWeek {
+			return Recurrence{}, fmt.Errorf("invalid recurrence %q: weekdays only apply to weekly rules", s)
+		}
+		seen := map[time.Weekday]bool{}
+		for _, name := range strings.Split(days, ",") {
+			wd, ok := weekdays[strings.TrimSpace(name)]
+			if !ok {
+				return Recurrence{}, fmt.Errorf("invalid weekday %q in recurrence", name)
+			}
+			if !seen[wd] {
+				seen[wd] = true
+				r.Weekdays = append(r.Weekdays, wd)
+			}
+		}
+		sort.Slice(r.Weekdays, func(i, j int) bool { return mondayIndex(r.Weekdays[i]) < mondayIndex(r.Weekdays[j]) })
+	}
+	return r, nil
+}
+
+// parseEvery reads "every 3 days", "every 3days" or "every 3d".
+func parseEvery(rule string) (int, RecurUnit, error) {
+	rest, ok := strings.CutPrefix(rule, "every ")
+	if !ok {
+		return 0, 0, fmt.Errorf("not an every rule")
+	}
+	rest = strings.ReplaceAll(rest, " ", "")
+	i := 0
+	for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+		i++
+	}
+	n, err := strconv.Atoi(rest[:i])
+	unit, ok := unitNames[rest[i:]]
+	if err != nil || !ok || n < 1 {
+		return 0, 0, fmt.Errorf("invalid every rule")
+	}
+	return n, unit, nil
+}
+
+// String renders the rule in the form ParseRecurrence reads back.
+func (r Recurrence) String() string {
+	var s string
+	switch {
+	case r.Interval == 1 && r.Unit == RecurDay:
+		s = "daily"
+	case r.Interval == 1 && r.Unit == RecurWeek:
+		s = "weekly"
+	case r.Interval == 1 && r.Unit == RecurMonth:
+		s = "monthly"
+	default:
+		unit := map[RecurUnit]string{RecurDay: "days", RecurWeek: "weeks", RecurMonth: "months"}[r.Unit]
+		s = fmt.Sprintf("every %d %s", r.Interval, unit)
+	}
+	if len(r.Weekdays) > 0 {
+		names := make([]string, len(r.Weekdays))
+		for i, wd := range r.Weekdays {
+			names[i] = strings.ToLower(wd.String()[:3])
+		}
+		s += ":" + strings.Join(names, ",")
+	}
+	if r.Day > 0 {
+		s += ":" + strconv.Itoa(r.Day)
+	}
+	return s
+}
+
+// MarshalText stores rules in their readable form, like priorities.
+func (r Recurrence) MarshalText() ([]byte, error) {
+	if r.Interval < 1 || r.Unit < RecurDay || r.Unit > RecurMonth || r.Day < 0 || r.Day > 31 || (r.Day > 0 && r.Unit != RecurMonth) {
+		return nil, fmt.Errorf("invalid recurrence %+v", r)
+	}
+	return []byte(r.String()), nil
+}
+
+func (r *Recurrence) UnmarshalText(b []byte) error {
+	v, err := ParseRecurrence(string(b))
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// Next returns the first occurrence after from, keeping its clock time.
+// Monthly rules that land on a day the month lacks (the 31st in April) use the
+// month's last day instead.
+func (r Recurrence) Next(from time.Time) time.Time {
+	switch r.Unit {
+	case RecurDay:
+		return from.AddDate(0, 0, r.Interval)
+	case RecurMonth:
+		y, m, d := from.Date()
+		if r.Day > 0 {
+			d = r.Day
+		}
+		target := time.Date(y, m+time.Month(r.Interval), 1, from.Hour(), from.Minute(), from.Second(), from.Nanosecond(), from.Location())
+		if last := daysIn(target); d > last {
+			d = last
+		}
+		return target.AddDate(0, 0, d-1)
+	}
+	if len(r.Weekdays) == 0 {
+		return from.AddDate(0, 0, 7*r.Interval)
+	}
+	for d := 1; d <= 7; d++ {
+		next := from.AddDate(0, 0, d)
+		if !r.onWeekday(next) {
+			continue
+		}
+		if mondayIndex(next.Weekday()) <= mondayIndex(from.Weekday()) {
+			// Wrapped into the following week: skip the idle weeks.
+			next = next.AddDate(0, 0, 7*(r.Interval-1))
+		}
+		return next
+	}
+	return from.AddDate(0, 0, 7*r.Interval)
+}
+
+// First returns the first occurrence on or after day, for tasks that get a
+// rule but no due date.
+func (r Recurrence) First(day time.Time) time.Time {
+	for len(r.Weekdays) > 0 && !r.onWeekday(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// After returns the first occurrence following due that is not already in
+// the past relative to now, so completing a chore late does not spawn a
+// backlog of overdue copies. Date-only due dates may land on today.
+func (r Recurrence) After(due, now time.Time) time.Time {
+	r = r.anchor(due)
+	next := r.Next(due)
+	if IsDateOnly(due) {
+		today := StartOfDay(now)
+		for next.Before(today) {
+			next = r.Next(next)
+		}
+		return next
+	}
+	for !next.After(now) {
+		next = r.Next(next)
+	}
+	return next
+}
+
+// anchor pins a monthly rule to the day of the month of due, so that an
+// occurrence moved to a short month's last day does not move the ones after
+// it: Jan 31 is followed by Feb 28 and then Mar 31.
+func (r Recurrence) anchor(due time.Time) Recurrence {
+	if r.Unit == RecurMonth && r.Day == 0 {
+		r.Day = due.Day()
+	}
+	return r
+}
+
+func (r Recurrence) onWeekday(t time.Time) bool {
+	for _, wd := range r.Weekdays {
+		if t.Weekday() == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// mondayIndex orders weekdays Monday first.
+func mondayIndex(wd time.Weekday) int {
+	return (int(wd) + 6) % 7
+}
+
+func daysIn(month time.Time) int {
+	return month.AddDate(0, 1, -month.Day()).Day()
+}
+
+// Recur completes the cycle of every recurring task in ids: the rule moves to
+// a new open copy due at the next occurrence, so reopening and completing the
+// old task again does not spawn a second copy. It returns the list and the
+// IDs of the new tasks, in the order of ids.
+func Recur(list []Task, ids []int, at time.Time) ([]Task, []int) {
+	var spawned []int
+	for _, id := range ids {
+		i := indexOf(list, id)
+		if i < 0 || list[i].Recur == nil {
+			continue
+		}
+		due := StartOfDay(at)
+		if list[i].Due != nil {
+			due = *list[i].Due
+		}
+		rule := list[i].Recur.anchor(due)
+		next := list[i].Clone()
+		nextDue := rule.After(due, at)
+		created := at
+		next.Due, next.CreatedAt, next.CompletedAt = &nextDue, &created, nil
+		next.Recur = &rule
+		if next.RemindAt != nil {
+			// Keep the reminder the same distance ahead of the due date.
+			remind := nextDue.Add(next.RemindAt.Sub(due))
+			next.RemindAt = &remind
+		}
+		list[i].Recur = nil
+		list = AddTask(list, next)
+		spawned = append(spawned, list[len(list)-1].ID)
+	}
+	return list, spawned
+}
+
+func indexOf(list []Task, id int) int {
+	for i := range list {
+		if list[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,151 @@
+package tasks_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseRecurrenceRoundTrips(t *testing.T) {
+	cases := map[string]string{
+		"daily":                "daily",
+		"Weekly":               "weekly",
+		"weekly:thu,mon,thu":   "weekly:mon,thu",
+		"weekdays":             "weekly:mon,tue,wed,thu,fri",
+		"monthly":              "monthly",
+		"every 3 days":         "every 3 days",
+		"every 2w":             "every 2 weeks",
+		"every 2 weeks:sunday": "every 2 weeks:sun",
+		"every 1 month":        "monthly",
+		"monthly: 31":          "monthly:31",
+		"every 3 months:15":    "every 3 months:15",
+	}
+	for in, want := range cases {
+		r, err := tasks.ParseRecurrence(in)
+		if err != nil {
+			t.Fatalf("%q: %v", in, err)
+		}
+		if r.String() != want {
+			t.Fatalf("%q: got %q, want %q", in, r.String(), want)
+		}
+	}
+	for _, bad := range []string{"", "hourly", "every 0 days", "every x days", "daily:mon", "weekly:funday", "monthly:32", "monthly:mon", "weekly:3"} {
+		if _, err := tasks.ParseRecurrence(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	parse := func(s string) tasks.Recurrence {
+		r, err := tasks.ParseRecurrence(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	cases := []struct {
+		rule string
+		from time.Time
+		want time.Time
+	}{
+		{"daily", date(2024, time.March, 31), date(2024, time.April, 1)},
+		{"every 3 days", date(2024, time.March, 1), date(2024, time.March, 4)},
+		{"weekly", date(2024, time.March, 1), date(2024, time.March, 8)},
+		// 2024-03-04 is a Monday.
+		{"weekly:mon,thu", date(2024, time.March, 4), date(2024, time.March, 7)},
+		{"weekly:mon,thu", date(2024, time.March, 7), date(2024, time.March, 11)},
+		{"every 2 weeks:mon,thu", date(2024, time.March, 4), date(2024, time.March, 7)},
+		{"every 2 weeks:mon,thu", date(2024, time.March, 7), date(2024, time.March, 18)},
+		{"monthly", date(2024, time.January, 31), date(2024, time.February, 29)},
+		{"every 2 months", date(2024, time.November, 15), date(2025, time.January, 15)},
+		{"monthly:31", date(2025, time.April, 30), date(2025, time.May, 31)},
+	}
+	for _, c := range cases {
+		if got := parse(c.rule).Next(c.from); !got.Equal(c.want) {
+			t.Fatalf("%s from %s: got %s, want %s", c.rule, c.from.Format(tasks.DateLayout), got.Format(tasks.DateLayout), c.want.Format(tasks.DateLayout))
+		}
+	}
+
+	// Completing a daily chore three days late lands on today, not on a
+	// backlog of missed days.
+	late := parse("daily").After(date(2024, time.March, 1), time.Date(2024, time.March, 4, 18, 0, 0, 0, time.UTC))
+	if !late.Equal(date(2024, time.March, 4)) {
+		t.Fatalf("late completion: got %s", late)
+	}
+}
+
+func TestRecurSpawnsNextOccurrence(t *testing.T) {
+	rule, _ := tasks.ParseRecurrence("weekly:mon,thu")
+	due := date(2024, time.March, 4)
+	list := []tasks.Task{
+		{ID: 1, Text: "Gym", Due: &due, Recur: &rule, Tags: []string{"health"}},
+		{ID: 2, Text: "One-off"},
+	}
+	at := time.Date(2024, time.March, 4, 9, 0, 0, 0, time.UTC)
+	list, _ = tasks.SetDone(list, []int{1, 2}, true, at)
+	list, spawned := tasks.Recur(list, []int{1, 2}, at)
+	if len(spawned) != 1 || spawned[0] != 3 || len(list) != 3 {
+		t.Fatalf("expected one spawned task #3, got %v (%d tasks)", spawned, len(list))
+	}
+	next := list[2]
+	if next.Done || next.Recur == nil || next.CompletedAt != nil || next.Text != "Gym" || next.Tags[0] != "health" {
+		t.Fatalf("unexpected spawned task %+v", next)
+	}
+	if !next.Due.Equal(date(2024, time.March, 7)) {
+		t.Fatalf("next due %s, want 2024-03-07", next.Due)
+	}
+	if list[0].Recur != nil {
+		t.Fatal("completed task should hand its rule to the new occurrence")
+	}
+
+	b, err := json.Marshal(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded tasks.Task
+	if err := json.Unmarshal(b, &decoded); err != nil || decoded.Recur == nil || decoded.Recur.String() != "weekly:mon,thu" {
+		t.Fatalf("recurrence did not survive JSON: %s (%v)", b, err)
+	}
+}
+
+func TestRecurKeepsMonthlyDayOfMonth(t *testing.T) {
+	rule, _ := tasks.ParseRecurrence("monthly")
+	due := date(2025, time.January, 31)
+	list := []tasks.Task{{ID: 1, Text: "Rent", Due: &due, Recur: &rule}}
+
+	// Each occurrence is completed on its due date; a month cut short must
+	// not pull the following ones back.
+	want := []time.Time{date(2025, time.February, 28), date(2025, time.March, 31), date(2025, time.April, 30), date(2025, time.May, 31)}
+	for i, w := range want {
+		id := list[len(list)-1].ID
+		at := list[len(list)-1].Due.Add(9 * time.Hour)
+		list, _ = tasks.SetDone(list, []int{id}, true, at)
+		list, _ = tasks.Recur(list, []int{id}, at)
+		next := list[len(list)-1]
+		if !next.Due.Equal(w) {
+			t.Fatalf("occurrence %d: due %s, want %s", i+1, next.Due.Format(tasks.DateLayout), w.Format(tasks.DateLayout))
+		}
+		if next.Recur.String() != "monthly:31" {
+			t.Fatalf("expected the rule to keep the 31st, got %q", next.Recur)
+		}
+	}
+}
+
+func TestRecurKeepsReminderOffset(t *testing.T) {
+	rule, _ := tasks.ParseRecurrence("daily")
+	due := time.Date(2024, time.March, 4, 18, 0, 0, 0, time.UTC)
+	remind := due.Add(-2 * time.Hour)
+	list := []tasks.Task{{ID: 1, Text: "Take pills", Due: &due, RemindAt: &remind, Recur: &rule}}
+	list, _ = tasks.Recur(list, []int{1}, due)
+	want := time.Date(2024, time.March, 5, 16, 0, 0, 0, time.UTC)
+	if list[1].RemindAt == nil || !list[1].RemindAt.Equal(want) {
+		t.Fatalf("next reminder %v, want %v", list[1].RemindAt, want)
+	}
+}
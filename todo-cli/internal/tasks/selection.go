@@ -0,0 +1,190 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrEmptySelection is returned when a selection matches no task at all.
+var ErrEmptySelection = errors.New("no tasks match")
+
+// MissingError lists explicitly requested IDs that do not exist. It matches
+// ErrNotFound with errors.Is.
+type MissingError struct {
+	IDs []int
+}
+
+func (e *MissingError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrNotFound, FormatIDs(e.IDs))
+}
+
+func (e *MissingError) Is(target error) bool { return target == ErrNotFound }
+
+// IDRange is an inclusive range of task IDs, as in "3-7".
+type IDRange struct {
+	From, To int
+}
+
+// Selection picks tasks by explicit IDs, ID ranges and an optional filter.
+// When both IDs (or ranges) and a filter are given, a task must satisfy both.
+type Selection struct {
+	IDs    []int
+	Ranges []IDRange
+	Filter *Filter
+}
+
+// ParseSelection parses ID arguments such as "3", "3-7" or "1,4,9-12".
+func ParseSelection(args []string) (Selection, error) {
+	var sel Selection
+	for _, arg := range args {
+		for _, part := range strings.Split(arg, ",") {
+			part = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(part), "#"))
+			if part == "" {
+				continue
+			}
+			if from, to, ok := strings.Cut(part, "-"); ok {
+				a, errA := strconv.Atoi(from)
+				b, errB := strconv.Atoi(to)
+				if errA != nil || errB != nil || a < 1 || b < a {
+					return Selection{}, fmt.Errorf("invalid ID range %q", part)
+				}
+				sel.Ranges = append(sel.Ranges, IDRange{From: a, To: b})
+				continue
+			}
+			id, err := strconv.Atoi(part)
+			if err != nil || id < 1 {
+				return Selection{}, fmt.Errorf("invalid ID %q", part)
+			}
+			sel.IDs = append(sel.IDs, id)
+		}
+	}
+	return sel, nil
+}
+
+// Empty reports whether the selection has no criteria at all.
+func (s Selection) Empty() bool {
+	return len(s.IDs) == 0 && len(s.Ranges) == 0 && s.Filter == nil
+}
+
+// Single returns the ID when the selection names exactly one task by ID.
+func (s Selection) Single() (int, bool) {
+	if len(s.IDs) == 1 && len(s.Ranges) == 0 && s.Filter == nil {
+		return s.IDs[0], true
+	}
+	return 0, false
+}
+
+// Resolve returns the IDs of the tasks in list picked by s, in list order.
+// Explicit IDs must exist; ranges only pick the IDs that do.
+func (s Selection) Resolve(list []Task, now time.Time) ([]int, error) {
+	byID := make(map[int]bool, len(list))
+	for _, t := range list {
+		byID[t.ID] = true
+	}
+	var missing []int
+	for _, id := range s.IDs {
+		if !byID[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &MissingError{IDs: missing}
+	}
+
+	explicit := make(map[int]bool, len(s.IDs))
+	for _, id := range s.IDs {
+		explicit[id] = true
+	}
+	byIDOrRange := len(s.IDs) > 0 || len(s.Ranges) > 0
+	var ids []int
+	for _, t := range list {
+		if byIDOrRange && !explicit[t.ID] && !s.inRange(t.ID) {
+			continue
+		}
+		if s.Filter != nil && !s.Filter.Match(t, now) {
+			continue
+		}
+		ids = append(ids, t.ID)
+	}
+	if len(ids) == 0 {
+		return nil, ErrEmptySelection
+	}
+	return ids, nil
+}
+
+func (s Selection) inRange(id int) bool {
+	for _, r := range s.Ranges {
+		if id >= r.From && id <= r.To {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDone sets Done on every task in ids and returns the IDs whose state
+// actually changed. Completing a task stamps CompletedAt with at; reopening
+// clears it.
+func SetDone(list []Task, ids []int, done bool, at time.Time) ([]Task, []int) {
+	want := idSet(ids)
+	var changed []int
+	for i := range list {
+		if want[list[i].ID] && list[i].Done != done {
+			list[i].Done = done
+			list[i].CompletedAt = nil
+			if done {
+				stamp := at
+				list[i].CompletedAt = &stamp
+			}
+			changed = append(changed, list[i].ID)
+		}
+	}
+	return list, changed
+}
+
+// RemoveAll deletes every task in ids. Subtasks of a removed task move up to
+// its parent, and blocked-by links to removed tasks are dropped.
+func RemoveAll(list []Task, ids []int) []Task {
+	drop := idSet(ids)
+	removed := make(map[int]Task, len(ids))
+	out := list[:0]
+	for _, t := range list {
+		if drop[t.ID] {
+			removed[t.ID] = t
+		} else {
+			out = append(out, t)
+		}
+	}
+	detach(out, removed)
+	return out
+}
+
+// Edit replaces the text of the task with the given id.
+func Edit(list []Task, id int, text string) ([]Task, error) {
+	for i := range list {
+		if list[i].ID == id {
+			list[i].Text = text
+			return list, nil
+		}
+	}
+	return list, ErrNotFound
+}
+
+// FormatIDs renders IDs as "#1, #2, #5".
+func FormatIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = "#" + strconv.Itoa(id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func idSet(ids []int) map[int]bool {
+	set := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
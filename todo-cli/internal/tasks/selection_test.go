@@ -0,0 +1,78 @@
+package tasks_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+)
+
+func TestParseSelection(t *testing.T) {
+	sel, err := tasks.ParseSelection([]string{"1", "3-5,#8", " 10 "})
+	if err != nil {
+		t.Fatalf("ParseSelection: %v", err)
+	}
+	if fmt.Sprint(sel.IDs) != "[1 8 10]" || len(sel.Ranges) != 1 || sel.Ranges[0] != (tasks.IDRange{From: 3, To: 5}) {
+		t.Fatalf("unexpected selection %+v", sel)
+	}
+	for _, bad := range []string{"x", "0", "5-3", "2-", "-4"} {
+		if _, err := tasks.ParseSelection([]string{bad}); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestSelectionResolve(t *testing.T) {
+	now := time.Now()
+	list := []tasks.Task{
+		{ID: 1, Text: "a", Done: true},
+		{ID: 2, Text: "b"},
+		{ID: 4, Text: "d", Done: true},
+		{ID: 7, Text: "g"},
+	}
+	done := true
+
+	cases := []struct {
+		name string
+		sel  tasks.Selection
+		want string
+	}{
+		{"ids", tasks.Selection{IDs: []int{7, 1}}, "[1 7]"},
+		{"range skips gaps", tasks.Selection{Ranges: []tasks.IDRange{{From: 2, To: 6}}}, "[2 4]"},
+		{"filter only", tasks.Selection{Filter: &tasks.Filter{Done: &done}}, "[1 4]"},
+		{"range and filter", tasks.Selection{Ranges: []tasks.IDRange{{From: 2, To: 7}}, Filter: &tasks.Filter{Done: &done}}, "[4]"},
+	}
+	for _, tc := range cases {
+		got, err := tc.sel.Resolve(list, now)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if fmt.Sprint(got) != tc.want {
+			t.Fatalf("%s: got %v, want %s", tc.name, got, tc.want)
+		}
+	}
+
+	_, err := tasks.Selection{IDs: []int{2, 3, 9}}.Resolve(list, now)
+	var missing *tasks.MissingError
+	if !errors.As(err, &missing) || fmt.Sprint(missing.IDs) != "[3 9]" || !errors.Is(err, tasks.ErrNotFound) {
+		t.Fatalf("expected missing IDs 3 and 9, got %v", err)
+	}
+	if _, err := (tasks.Selection{Ranges: []tasks.IDRange{{From: 20, To: 30}}}).Resolve(list, now); !errors.Is(err, tasks.ErrEmptySelection) {
+		t.Fatalf("expected ErrEmptySelection, got %v", err)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	list := tasks.Add(nil, "tpyo")
+	list, _ = tasks.MarkDone(list, 1)
+
+	list, err := tasks.Edit(list, 1, "typo")
+	if err != nil || list[0].Text != "typo" {
+		t.Fatalf("Edit: %v %+v", err, list)
+	}
+	if _, err := tasks.Edit(list, 5, "x"); !errors.Is(err, tasks.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
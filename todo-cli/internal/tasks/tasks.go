@@ -3,18 +3,65 @@ package tasks
 import (
 	"errors"
 	"sort"
+	"time"
 )
 
 type Task struct {
-	ID   int    `json:"id"`
-	Text string `json:"text"`
-	Done bool   `json:"done"`
-	// CreatedAt intentionally omitted from JSON schema for now to keep tests simple;
-	// newest-first is implemented via ID ordering.
+	ID          int         `json:"id"`
+	Text        string      `json:"text"`
+	Done        bool        `json:"done"`
+	Due         *time.Time  `json:"due,omitempty"`
+	RemindAt    *time.Time  `json:"remind_at,omitempty"`
+	Priority    Priority    `json:"priority,omitempty"`
+	Tags        []string    `json:"tags,omitempty"`
+	CreatedAt   *time.Time  `json:"created_at,omitempty"`
+	CompletedAt *time.Time  `json:"completed_at,omitempty"`
+	Recur       *Recurrence `json:"recur,omitempty"`
+	Parent      int         `json:"parent,omitempty"`
+	BlockedBy   []int       `json:"blocked_by,omitempty"`
+	// Newest-first is implemented via ID ordering; the timestamps feed
+	// stats and are optional because tasks written by older versions do not
+	// have them.
 }
 
 var ErrNotFound = errors.New("task not found")
 
+// Clone returns a copy of t that shares no pointers or slices with it.
+func (t Task) Clone() Task {
+	t.Due = cloneTime(t.Due)
+	t.RemindAt = cloneTime(t.RemindAt)
+	t.CreatedAt = cloneTime(t.CreatedAt)
+	t.CompletedAt = cloneTime(t.CompletedAt)
+	t.Tags = append([]string(nil), t.Tags...)
+	t.BlockedBy = append([]int(nil), t.BlockedBy...)
+	if t.Recur != nil {
+		r := *t.Recur
+		r.Weekdays = append([]time.Weekday(nil), r.Weekdays...)
+		t.Recur = &r
+	}
+	return t
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := *t
+	return &v
+}
+
+// CloneList returns a deep copy of list; nil stays nil.
+func CloneList(list []Task) []Task {
+	if list == nil {
+		return nil
+	}
+	out := make([]Task, len(list))
+	for i, t := range list {
+		out[i] = t.Clone()
+	}
+	return out
+}
+
 // ErrTaskNotFound is kept for backward compatibility with earlier versions of
 // the package. It aliases ErrNotFound so older code (and tests) continue to
 // compile while newer code can use the shorter name.
@@ -33,8 +80,46 @@ func NextID(list []Task) int {
 
 // Add appends a new task with a new ID.
 func Add(list []Task, text string) []Task {
-	id := NextID(list)
-	return append(list, Task{ID: id, Text: text, Done: false})
+	return AddTask(list, Task{Text: text})
+}
+
+// AddTask appends t with a new ID, keeping any due date, priority and tags set
+// by the caller.
+func AddTask(list []Task, t Task) []Task {
+	t.ID = NextID(list)
+	t.Done = false
+	return append(list, t)
+}
+
+// Adopt appends copies of moved with fresh IDs, keeping every other field
+// (including Done), and returns the IDs they were given. Parent and
+// blocked-by links between moved tasks follow the new IDs; links to tasks
+// that were not moved are dropped.
+func Adopt(list []Task, moved []Task) ([]Task, []int) {
+	start := len(list)
+	renamed := make(map[int]int, len(moved))
+	ids := make([]int, 0, len(moved))
+	for _, t := range moved {
+		t = t.Clone()
+		id := NextID(list)
+		if t.ID != 0 {
+			renamed[t.ID] = id
+		}
+		t.ID = id
+		list = append(list, t)
+		ids = append(ids, id)
+	}
+	for i := start; i < len(list); i++ {
+		list[i].Parent = renamed[list[i].Parent]
+		var deps []int
+		for _, b := range list[i].BlockedBy {
+			if id, ok := renamed[b]; ok {
+				deps = append(deps, id)
+			}
+		}
+		list[i].BlockedBy = deps
+	}
+	return list, ids
 }
 
 // MarkDone sets Done=true for the given id.
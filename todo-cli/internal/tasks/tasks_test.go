@@ -1,8 +1,12 @@
 package tasks_test
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
 )
@@ -91,3 +95,69 @@ func TestSortOrdersByNewestAndReverse(t *testing.T) {
 		t.Fatalf("expected ascending order when reverse flag set, got %+v", reversed)
 	}
 }
+
+func TestFilterMatchesTagsPriorityAndDue(t *testing.T) {
+	now := time.Date(2024, time.March, 13, 12, 0, 0, 0, time.UTC)
+	past := now.AddDate(0, 0, -2)
+	soon := now.AddDate(0, 0, 1)
+	later := now.AddDate(0, 0, 10)
+
+	list := []tasks.Task{
+		{ID: 1, Text: "file taxes", Due: &past, Priority: tasks.PriorityHigh, Tags: []string{"home"}},
+		{ID: 2, Text: "review PR", Due: &soon, Priority: tasks.PriorityMedium, Tags: []string{"Work", "code"}},
+		{ID: 3, Text: "plan offsite", Due: &later, Tags: []string{"work"}},
+		{ID: 4, Text: "read book"},
+	}
+
+	ids := func(list []tasks.Task) []int {
+		var out []int
+		for _, t := range list {
+			out = append(out, t.ID)
+		}
+		return out
+	}
+
+	high := tasks.PriorityHigh
+	cutoff := now.AddDate(0, 0, 5)
+	cases := []struct {
+		name   string
+		filter tasks.Filter
+		want   []int
+	}{
+		{"zero filter", tasks.Filter{}, []int{1, 2, 3, 4}},
+		{"tag is case-insensitive", tasks.Filter{Tags: []string{"work"}}, []int{2, 3}},
+		{"all tags required", tasks.Filter{Tags: []string{"work", "code"}}, []int{2}},
+		{"priority", tasks.Filter{Priority: &high}, []int{1}},
+		{"due before", tasks.Filter{DueBefore: &cutoff}, []int{1, 2}},
+		{"overdue", tasks.Filter{Overdue: true}, []int{1}},
+	}
+	for _, tc := range cases {
+		got := ids(tc.filter.Apply(list, now))
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Fatalf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPriorityJSONRoundTrip(t *testing.T) {
+	in := tasks.Task{ID: 1, Text: "ship", Priority: tasks.PriorityHigh, Tags: []string{"release"}}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(b), `"priority":"high"`) {
+		t.Fatalf("expected priority stored by name, got %s", b)
+	}
+	var out tasks.Task
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.Priority != tasks.PriorityHigh || len(out.Tags) != 1 {
+		t.Fatalf("round trip mismatch: %+v", out)
+	}
+
+	plain, _ := json.Marshal(tasks.Task{ID: 2, Text: "plain"})
+	if string(plain) != `{"id":2,"text":"plain","done":false}` {
+		t.Fatalf("expected optional fields omitted, got %s", plain)
+	}
+}
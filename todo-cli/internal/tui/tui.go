@@ -0,0 +1,427 @@
+// Package tui implements the full-screen task list: keyboard navigation,
+// inline add and edit, toggling done, deleting and live filtering. It draws
+// through ui.Screen, so tests drive it with ui.FakeScreen.
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/render"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/search"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/ui"
+)
+
+// Store is what the TUI reads and changes. Each call is one complete
+// operation, so the CLI can lock and journal it like any other command.
+type Store interface {
+	Load() ([]tasks.Task, error)
+	Add(text string) (tasks.Task, error)
+	Edit(id int, text string) error
+	SetDone(id int, done bool) error
+	Remove(id int) error
+}
+
+type mode int
+
+const (
+	browsing mode = iota
+	adding
+	editing
+	filtering
+	confirmingDelete
+)
+
+// App is one TUI session.
+type App struct {
+	store  Store
+	screen ui.Screen
+	now    func() time.Time
+
+	all    []tasks.Task
+	rows   []tasks.Node
+	cursor int
+	offset int
+
+	mode   mode
+	input  []rune
+	filter string
+	status string
+}
+
+// New returns an App drawing on screen. now supplies the clock used for
+// overdue markers.
+func New(store Store, screen ui.Screen, now func() time.Time) *App {
+	return &App{store: store, screen: screen, now: now}
+}
+
+// Run loops until the user quits or the screen runs out of input. It does not
+// close the screen.
+func (a *App) Run() error {
+	if err := a.reload(); err != nil {
+		return err
+	}
+	for {
+		if err := a.screen.Draw(a.View()); err != nil {
+			return err
+		}
+		key, err := a.screen.ReadKey()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if a.Handle(key) {
+			return nil
+		}
+	}
+}
+
+// Handle applies one key press and reports whether the user asked to quit.
+func (a *App) Handle(k ui.Key) bool {
+	if k.Code == ui.KeyCtrlC {
+		return true
+	}
+	switch a.mode {
+	case adding, editing:
+		a.handleInput(k)
+	case filtering:
+		a.handleFilter(k)
+	case confirmingDelete:
+		a.handleConfirm(k)
+	default:
+		return a.handleBrowse(k)
+	}
+	return false
+}
+
+func (a *App) handleBrowse(k ui.Key) bool {
+	a.status = ""
+	switch {
+	case k.Code == ui.KeyUp || k.Is('k'):
+		a.move(-1)
+	case k.Code == ui.KeyDown || k.Is('j'):
+		a.move(1)
+	case k.Code == ui.KeyPageUp:
+		a.move(-a.pageSize())
+	case k.Code == ui.KeyPageDown:
+		a.move(a.pageSize())
+	case k.Code == ui.KeyHome || k.Is('g'):
+		a.move(-len(a.rows))
+	case k.Code == ui.KeyEnd || k.Is('G'):
+		a.move(len(a.rows))
+	case k.Is(' ') || k.Is('x'):
+		if t, ok := a.selected(); ok {
+			a.apply(a.store.SetDone(t.ID, !t.Done))
+		}
+	case k.Is('a'):
+		a.mode, a.input = adding, nil
+	case k.Code == ui.KeyEnter || k.Is('e'):
+		if t, ok := a.selected(); ok {
+			a.mode, a.input = editing, []rune(t.Text)
+		}
+	case k.Code == ui.KeyDelete || k.Is('d'):
+		if _, ok := a.selected(); ok {
+			a.mode = confirmingDelete
+		}
+	case k.Is('/'):
+		a.mode, a.input = filtering, []rune(a.filter)
+	case k.Code == ui.KeyEsc:
+		a.setFilter("")
+	case k.Is('r'):
+		a.apply(nil)
+	case k.Is('q'):
+		return true
+	}
+	return false
+}
+
+func (a *App) handleInput(k ui.Key) {
+	if !a.edit(k) {
+		return
+	}
+	if k.Code == ui.KeyEsc {
+		a.mode = browsing
+		return
+	}
+	// Enter
+	text := strings.TrimSpace(string(a.input))
+	m := a.mode
+	a.mode = browsing
+	if text == "" {
+		a.status = "nothing entered"
+		return
+	}
+	if m == adding {
+		t, err := a.store.Add(text)
+		a.apply(err)
+		if err == nil {
+			a.status = fmt.Sprintf("added #%d", t.ID)
+			a.selectID(t.ID)
+		}
+		return
+	}
+	if t, ok := a.selected(); ok {
+		a.apply(a.store.Edit(t.ID, text))
+	}
+}
+
+func (a *App) handleFilter(k ui.Key) {
+	if !a.edit(k) {
+		a.setFilter(string(a.input))
+		return
+	}
+	if k.Code == ui.KeyEsc {
+		a.setFilter("")
+	}
+	a.mode = browsing
+}
+
+func (a *App) handleConfirm(k ui.Key) {
+	a.mode = browsing
+	if !k.Is('y') && !k.Is('Y') {
+		a.status = "delete cancelled"
+		return
+	}
+	if t, ok := a.selected(); ok {
+		err := a.store.Remove(t.ID)
+		a.apply(err)
+		if err == nil {
+			a.status = fmt.Sprintf("removed #%d", t.ID)
+		}
+	}
+}
+
+// edit applies a line-editing key to the input and reports whether the key
+// ends input (Enter or Esc).
+func (a *App) edit(k ui.Key) bool {
+	switch k.Code {
+	case ui.KeyEnter, ui.KeyEsc:
+		return true
+	case ui.KeyBackspace:
+		if len(a.input) > 0 {
+			a.input = a.input[:len(a.input)-1]
+		}
+	case ui.KeyCtrlU:
+		a.input = nil
+	case ui.KeyRune:
+		a.input = append(a.input, k.Rune)
+	}
+	return false
+}
+
+// apply reloads after an operation, or shows its error.
+func (a *App) apply(err error) {
+	if err != nil {
+		a.status = "error: " + err.Error()
+		return
+	}
+	if err := a.reload(); err != nil {
+		a.status = "error: " + err.Error()
+	}
+}
+
+func (a *App) reload() error {
+	var id int
+	t, hadSelection := a.selected()
+	if hadSelection {
+		id = t.ID
+	}
+	list, err := a.store.Load()
+	if err != nil {
+		return err
+	}
+	a.all = list
+	a.refilter()
+	if hadSelection {
+		a.selectID(id)
+	}
+	return nil
+}
+
+func (a *App) setFilter(f string) {
+	a.filter = f
+	a.refilter()
+	a.cursor, a.offset = 0, 0
+}
+
+// refilter rebuilds the visible rows: the newest-first tree of the tasks whose
+// text or tags contain the filter.
+func (a *App) refilter() {
+	list := tasks.SortNewestFirst(a.all)
+	if m, err := search.Compile(a.filter, search.Substring); err == nil {
+		list = keep(list, m)
+	}
+	a.rows = tasks.Tree(list)
+	a.move(0)
+}
+
+// keep returns the tasks whose text or one of whose tags matches m.
+func keep(list []tasks.Task, m *search.Matcher) []tasks.Task {
+	var out []tasks.Task
+	for _, t := range list {
+		if _, _, ok := m.Match(t.Text); ok {
+			out = append(out, t)
+			continue
+		}
+		for _, tag := range t.Tags {
+			if _, _, ok := m.Match(tag); ok {
+				out = append(out, t)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func (a *App) selected() (tasks.Task, bool) {
+	if a.cursor < 0 || a.cursor >= len(a.rows) {
+		return tasks.Task{}, false
+	}
+	return a.rows[a.cursor].Task, true
+}
+
+func (a *App) selectID(id int) {
+	for i, n := range a.rows {
+		if n.Task.ID == id {
+			a.cursor = i
+			a.move(0)
+			return
+		}
+	}
+}
+
+// move shifts the cursor by delta, clamped to the rows, and scrolls so the
+// cursor stays visible.
+func (a *App) move(delta int) {
+	a.cursor += delta
+	if a.cursor >= len(a.rows) {
+		a.cursor = len(a.rows) - 1
+	}
+	if a.cursor < 0 {
+		a.cursor = 0
+	}
+	page := a.pageSize()
+	if a.cursor < a.offset {
+		a.offset = a.cursor
+	}
+	if a.cursor >= a.offset+page {
+		a.offset = a.cursor - page + 1
+	}
+}
+
+// Screen layout: a header, the list, a status line and a help line.
+const chromeLines = 3
+
+func (a *App) pageSize() int {
+	_, h := a.screen.Size()
+	if h-chromeLines < 1 {
+		return 1
+	}
+	return h - chromeLines
+}
+
+// View renders the current state as a frame.
+func (a *App) View() ui.Frame {
+	width, height := a.screen.Size()
+	now := a.now()
+	open := 0
+	for _, t := range a.all {
+		if !t.Done {
+			open++
+		}
+	}
+	header := fmt.Sprintf("todo-cli: %d open / %d tasks", open, len(a.all))
+	if a.filter != "" || a.mode == filtering {
+		header += fmt.Sprintf("  filter: %s", a.filterText())
+	}
+
+	lines := []string{header}
+	cursor := -1
+	if a.mode == adding {
+		lines = append(lines, "[+] "+string(a.input)+"_")
+	}
+	page := a.pageSize()
+	if a.mode == adding {
+		page--
+	}
+	for i := a.offset; i < len(a.rows) && i < a.offset+page; i++ {
+		line := render.TreeLine(a.rows[i], a.all, now)
+		if i == a.cursor {
+			if a.mode == editing {
+				line = prefix(a.rows[i]) + fmt.Sprintf("#%d %s_", a.rows[i].Task.ID, string(a.input))
+			}
+			if a.mode != adding {
+				cursor = len(lines)
+			}
+		}
+		lines = append(lines, line)
+	}
+	if len(a.rows) == 0 && a.mode != adding {
+		if a.filter != "" {
+			lines = append(lines, "No tasks match the filter.")
+		} else {
+			lines = append(lines, "No tasks yet. Press a to add one.")
+		}
+	}
+	for len(lines) < height-2 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, a.statusLine(), a.help())
+	for i := range lines {
+		lines[i] = truncate(lines[i], width)
+	}
+	return ui.Frame{Lines: lines, Cursor: cursor}
+}
+
+func (a *App) filterText() string {
+	if a.mode == filtering {
+		return string(a.input) + "_"
+	}
+	return a.filter
+}
+
+func (a *App) statusLine() string {
+	if a.mode == confirmingDelete {
+		if t, ok := a.selected(); ok {
+			return fmt.Sprintf("Delete #%d %s? (y/n)", t.ID, t.Text)
+		}
+	}
+	return a.status
+}
+
+func (a *App) help() string {
+	switch a.mode {
+	case adding, editing:
+		return "enter save  esc cancel  ctrl-u clear"
+	case filtering:
+		return "type to filter  enter keep  esc clear"
+	case confirmingDelete:
+		return "y delete  any other key cancels"
+	}
+	return "↑/↓ move  space done  a add  e edit  d delete  / filter  q quit"
+}
+
+// prefix is what TreeLine puts in front of a row's ID: the tree indentation
+// and the done marker.
+func prefix(n tasks.Node) string {
+	line := render.TreeLine(n, nil, time.Time{})
+	return line[:strings.Index(line, "#")]
+}
+
+func truncate(s string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	r := []rune(s)
+	if width == 1 {
+		return "…"
+	}
+	return string(r[:width-1]) + "…"
+}
@@ -0,0 +1,147 @@
+package tui_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tasks"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/tui"
+	"github.com/pekomon/go-sandbox/todo-cli/internal/ui"
+)
+
+// memStore is an in-memory tui.Store.
+type memStore struct {
+	list []tasks.Task
+	fail error
+}
+
+func (m *memStore) Load() ([]tasks.Task, error) { return tasks.CloneList(m.list), nil }
+
+func (m *memStore) Add(text string) (tasks.Task, error) {
+	if m.fail != nil {
+		return tasks.Task{}, m.fail
+	}
+	m.list = tasks.Add(m.list, text)
+	return m.list[len(m.list)-1], nil
+}
+
+func (m *memStore) Edit(id int, text string) error {
+	var err error
+	m.list, err = tasks.Edit(m.list, id, text)
+	return err
+}
+
+func (m *memStore) SetDone(id int, done bool) error {
+	m.list, _ = tasks.SetDone(m.list, []int{id}, done, time.Time{})
+	return nil
+}
+
+func (m *memStore) Remove(id int) error {
+	m.list = tasks.RemoveAll(m.list, []int{id})
+	return nil
+}
+
+func keys(groups ...[]ui.Key) []ui.Key {
+	var out []ui.Key
+	for _, g := range groups {
+		out = append(out, g...)
+	}
+	return out
+}
+
+func k(code ui.KeyCode) []ui.Key { return []ui.Key{{Code: code}} }
+
+func run(t *testing.T, store *memStore, height int, input []ui.Key) *ui.FakeScreen {
+	t.Helper()
+	screen := &ui.FakeScreen{Width: 60, Height: height, Keys: input}
+	now := func() time.Time { return time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC) }
+	if err := tui.New(store, screen, now).Run(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	return screen
+}
+
+func cursorLine(f ui.Frame) string {
+	if f.Cursor < 0 {
+		return ""
+	}
+	return f.Lines[f.Cursor]
+}
+
+func TestAddEditToggleAndDelete(t *testing.T) {
+	store := &memStore{}
+	screen := run(t, store, 10, keys(
+		ui.Type("a"), ui.Type("Buy milk"), k(ui.KeyEnter),
+		ui.Type("a"), ui.Type("Call mum"), k(ui.KeyEnter),
+		ui.Type("j"), ui.Type("e"), k(ui.KeyCtrlU), ui.Type("Buy oat milk"), k(ui.KeyEnter),
+		ui.Type(" "),
+		ui.Type("k"), ui.Type("d"), ui.Type("n"),
+		ui.Type("d"), ui.Type("y"),
+		ui.Type("q"),
+	))
+
+	if len(store.list) != 1 || store.list[0].Text != "Buy oat milk" || !store.list[0].Done {
+		t.Fatalf("unexpected store: %+v", store.list)
+	}
+	last := screen.Last()
+	if last.Lines[0] != "todo-cli: 0 open / 1 tasks" {
+		t.Fatalf("header: %q", last.Lines[0])
+	}
+	if got := cursorLine(last); got != "[x] #1 Buy oat milk" {
+		t.Fatalf("cursor line: %q", got)
+	}
+	if len(last.Lines) != 10 || last.Lines[8] != "removed #2" {
+		t.Fatalf("unexpected frame %q", last.Lines)
+	}
+
+	// The inline editor replaces the row being edited.
+	var sawEditor, sawConfirm bool
+	for _, f := range screen.Frames {
+		if cursorLine(f) == "[ ] #1 Buy oat mil_" {
+			sawEditor = true
+		}
+		if strings.HasPrefix(f.Lines[8], "Delete #2 Call mum? (y/n)") {
+			sawConfirm = true
+		}
+	}
+	if !sawEditor || !sawConfirm {
+		t.Fatalf("expected inline editor and delete prompt frames (editor=%v confirm=%v)", sawEditor, sawConfirm)
+	}
+}
+
+func TestLiveFilterAndScrolling(t *testing.T) {
+	store := &memStore{}
+	for i := 1; i <= 20; i++ {
+		store.list = tasks.Add(store.list, "task "+strings.Repeat("x", i%3))
+	}
+	store.list = tasks.Add(store.list, "Pay rent")
+	store.list[20].Tags = []string{"bills"}
+
+	screen := run(t, store, 8, keys(ui.Type("/bil")))
+	last := screen.Last()
+	if !strings.Contains(last.Lines[0], "filter: bil_") || last.Lines[1] != "[ ] #21 Pay rent (tags bills)" || last.Lines[2] != "" {
+		t.Fatalf("live filter frame: %q", last.Lines)
+	}
+
+	screen = run(t, store, 8, keys(ui.Type("/zzz"), k(ui.KeyEnter)))
+	if screen.Last().Lines[1] != "No tasks match the filter." {
+		t.Fatalf("empty filter frame: %q", screen.Last().Lines)
+	}
+
+	// 5 rows fit; moving to the end scrolls the oldest task into view.
+	screen = run(t, store, 8, keys(k(ui.KeyEnd)))
+	last = screen.Last()
+	if got := cursorLine(last); got != "[ ] #1 task x" || last.Cursor != 5 || last.Lines[1] != "[ ] #5 task xx" {
+		t.Fatalf("scrolled frame: cursor %d %q", last.Cursor, last.Lines)
+	}
+}
+
+func TestStoreErrorsShowInStatusLine(t *testing.T) {
+	store := &memStore{fail: errors.New("disk full")}
+	screen := run(t, store, 6, keys(ui.Type("a"), ui.Type("x"), k(ui.KeyEnter)))
+	if got := screen.Last().Lines[4]; got != "error: disk full" {
+		t.Fatalf("status line: %q", got)
+	}
+}
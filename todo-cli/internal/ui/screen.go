@@ -0,0 +1,102 @@
+package ui
+
+import "io"
+
+// KeyCode identifies a key press. Printable characters use KeyRune with the
+// character in Key.Rune.
+type KeyCode int
+
+const (
+	KeyRune KeyCode = iota
+	KeyEnter
+	KeyEsc
+	KeyBackspace
+	KeyTab
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyDelete
+	KeyCtrlC
+	KeyCtrlU
+)
+
+// Key is one key press read from a Screen.
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// Rune returns the key press for a printable character.
+func Rune(r rune) Key { return Key{Code: KeyRune, Rune: r} }
+
+// Is reports whether k is the printable character r.
+func (k Key) Is(r rune) bool { return k.Code == KeyRune && k.Rune == r }
+
+// Type returns the key presses that type s.
+func Type(s string) []Key {
+	keys := make([]Key, 0, len(s))
+	for _, r := range s {
+		keys = append(keys, Rune(r))
+	}
+	return keys
+}
+
+// Frame is one full screen of output. Cursor is the index of the highlighted
+// line, or -1.
+type Frame struct {
+	Lines  []string
+	Cursor int
+}
+
+// Screen is a full-screen terminal surface, the counterpart of MenuUI for
+// the interactive list view.
+type Screen interface {
+	Size() (width, height int)
+	ReadKey() (Key, error)
+	Draw(f Frame) error
+	Close() error
+}
+
+// FakeScreen replays Keys and records every drawn frame. ReadKey returns
+// io.EOF once the keys run out.
+type FakeScreen struct {
+	Width, Height int
+	Keys          []Key
+	Frames        []Frame
+	Closed        bool
+}
+
+func (f *FakeScreen) Size() (int, int) { return f.Width, f.Height }
+
+func (f *FakeScreen) ReadKey() (Key, error) {
+	if len(f.Keys) == 0 {
+		return Key{}, io.EOF
+	}
+	k := f.Keys[0]
+	f.Keys = f.Keys[1:]
+	return k, nil
+}
+
+func (f *FakeScreen) Draw(frame Frame) error {
+	frame.Lines = append([]string(nil), frame.Lines...)
+	f.Frames = append(f.Frames, frame)
+	return nil
+}
+
+func (f *FakeScreen) Close() error {
+	f.Closed = true
+	return nil
+}
+
+// Last returns the most recent frame.
+func (f *FakeScreen) Last() Frame {
+	if len(f.Frames) == 0 {
+		return Frame{Cursor: -1}
+	}
+	return f.Frames[len(f.Frames)-1]
+}
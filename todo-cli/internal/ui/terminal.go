@@ -0,0 +1,142 @@
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ErrNotTerminal is returned by OpenTerminal when stdin or stdout is not a
+// terminal.
+var ErrNotTerminal = errors.New("not a terminal")
+
+// Terminal is a Screen on the process's terminal. It switches to the
+// alternate screen in raw mode and restores both on Close.
+type Terminal struct {
+	in    *os.File
+	out   *os.File
+	r     *bufio.Reader
+	state *term.State
+}
+
+// OpenTerminal takes over the terminal attached to stdin and stdout.
+func OpenTerminal() (*Terminal, error) {
+	in, out := os.Stdin, os.Stdout
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return nil, ErrNotTerminal
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	// Alternate screen, hidden cursor.
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	return &Terminal{in: in, out: out, r: bufio.NewReader(in), state: state}, nil
+}
+
+func (t *Terminal) Size() (int, int) {
+	w, h, err := term.GetSize(int(t.out.Fd()))
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// ReadKey decodes one key press, including the common VT100 escape
+// sequences for arrows, Home/End, Page Up/Down and Delete.
+func (t *Terminal) ReadKey() (Key, error) {
+	r, _, err := t.r.ReadRune()
+	if err != nil {
+		return Key{}, err
+	}
+	switch r {
+	case '\r', '\n':
+		return Key{Code: KeyEnter}, nil
+	case '\t':
+		return Key{Code: KeyTab}, nil
+	case 0x7f, 0x08:
+		return Key{Code: KeyBackspace}, nil
+	case 0x03:
+		return Key{Code: KeyCtrlC}, nil
+	case 0x15:
+		return Key{Code: KeyCtrlU}, nil
+	case 0x1b:
+		return t.readEscape()
+	}
+	return Rune(r), nil
+}
+
+func (t *Terminal) readEscape() (Key, error) {
+	// A lone Esc arrives on its own; sequences arrive in one read.
+	if t.r.Buffered() == 0 {
+		return Key{Code: KeyEsc}, nil
+	}
+	b, err := t.r.ReadByte()
+	if err != nil {
+		return Key{}, err
+	}
+	if b != '[' && b != 'O' {
+		return Key{Code: KeyEsc}, nil
+	}
+	var seq strings.Builder
+	for t.r.Buffered() > 0 {
+		c, err := t.r.ReadByte()
+		if err != nil {
+			return Key{}, err
+		}
+		seq.WriteByte(c)
+		if c >= 0x40 && c <= 0x7e {
+			break
+		}
+	}
+	switch seq.String() {
+	case "A":
+		return Key{Code: KeyUp}, nil
+	case "B":
+		return Key{Code: KeyDown}, nil
+	case "C":
+		return Key{Code: KeyRight}, nil
+	case "D":
+		return Key{Code: KeyLeft}, nil
+	case "H", "1~", "7~":
+		return Key{Code: KeyHome}, nil
+	case "F", "4~", "8~":
+		return Key{Code: KeyEnd}, nil
+	case "5~":
+		return Key{Code: KeyPageUp}, nil
+	case "6~":
+		return Key{Code: KeyPageDown}, nil
+	case "3~":
+		return Key{Code: KeyDelete}, nil
+	}
+	return Key{Code: KeyEsc}, nil
+}
+
+// Draw repaints the whole screen; the cursor line is shown in reverse video.
+func (t *Terminal) Draw(f Frame) error {
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, line := range f.Lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		if i == f.Cursor {
+			b.WriteString("\x1b[7m" + line + "\x1b[K\x1b[0m")
+		} else {
+			b.WriteString(line + "\x1b[K")
+		}
+	}
+	b.WriteString("\x1b[J")
+	_, err := t.out.WriteString(b.String())
+	return err
+}
+
+// Close leaves the alternate screen and restores the terminal mode.
+func (t *Terminal) Close() error {
+	fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+	return term.Restore(int(t.in.Fd()), t.state)
+}